@@ -2,20 +2,35 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/zisuu/github-actions-digest-pinner/internal/advisory"
+	"github.com/zisuu/github-actions-digest-pinner/internal/audit"
+	"github.com/zisuu/github-actions-digest-pinner/internal/checker"
+	"github.com/zisuu/github-actions-digest-pinner/internal/config"
+	"github.com/zisuu/github-actions-digest-pinner/internal/deps"
 	"github.com/zisuu/github-actions-digest-pinner/internal/finder"
 	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/internal/lockfile"
 	"github.com/zisuu/github-actions-digest-pinner/internal/parser"
+	"github.com/zisuu/github-actions-digest-pinner/internal/policy"
+	"github.com/zisuu/github-actions-digest-pinner/internal/registry"
+	"github.com/zisuu/github-actions-digest-pinner/internal/report"
+	"github.com/zisuu/github-actions-digest-pinner/internal/semver"
 	"github.com/zisuu/github-actions-digest-pinner/internal/updater"
 	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+	"gopkg.in/yaml.v3"
 )
 
 // Build number and versions injected at compile time
@@ -27,10 +42,12 @@ var (
 
 type WorkflowFinder interface {
 	FindWorkflowFiles(fsys fs.FS) ([]string, error)
+	FindActionFiles(fsys fs.FS) ([]string, error)
 }
 
 type WorkflowParser interface {
 	ParseWorkflowActions(content []byte) ([]types.ActionRef, error)
+	ParseCompositeActions(content []byte) ([]types.ActionRef, error)
 }
 
 type WorkflowUpdater interface {
@@ -47,147 +64,1392 @@ type App struct {
 	Updater  WorkflowUpdater
 	FS       func(dir string) fs.FS
 	ReadFile func(fsys fs.FS, name string) ([]byte, error)
+	// CacheDir is the directory of the on-disk resolution cache; empty disables it.
+	CacheDir string
+	// Filter selects the workflow and action files processed by the commands.
+	Filter finder.Filter
+	// AdvisoryDB is the imported advisory database, checked by scan and check if it exists.
+	AdvisoryDB string
 }
 
 // NewApp creates a new instance of App with the provided output and error writers.
+// The client is shared by all commands and caches lookups for the lifetime of the process.
+// Images are resolved against their registries, authenticated from the environment.
 func NewApp(out, err io.Writer) *App {
+	client := ghclient.NewCachingClient(ghclient.NewGitHubClient())
+	cacheDir, cacheErr := ghclient.DefaultCacheDir()
+	if cacheErr != nil {
+		log.Printf("Warning: on-disk cache disabled: %v", cacheErr)
+	}
+	advisoryDB, advisoryErr := advisory.DefaultDatabasePath()
+	if advisoryErr != nil {
+		log.Printf("Warning: advisory database disabled: %v", advisoryErr)
+	}
+	upd := updater.NewUpdater(client)
+	upd.SetImageResolver(registry.NewClient(nil, registry.EnvCredentials))
 	return &App{
 		Out:     out,
 		Err:     err,
-		Client:  ghclient.NewGitHubClient(),
+		Client:  client,
 		Finder:  finder.DefaultFinder{},
 		Parser:  parser.DefaultParser{},
-		Updater: updater.NewUpdater(ghclient.NewGitHubClient()),
+		Updater: upd,
 		FS: func(dir string) fs.FS {
 			return os.DirFS(dir)
 		},
-		ReadFile: fs.ReadFile,
+		ReadFile:   fs.ReadFile,
+		CacheDir:   cacheDir,
+		AdvisoryDB: advisoryDB,
 	}
 }
 
-// scanCommand scans the specified directory for GitHub Actions workflows and prints the actions found.
-func (a *App) scanCommand(dir string, verbose bool) error {
-	if verbose {
+// scanOptions holds the flags of the scan command.
+type scanOptions struct {
+	Dir    string
+	Format string
+	// Advisories is an advisory file or directory relative to Dir; empty uses the imported database, if any.
+	Advisories string
+	Verbose    bool
+}
+
+// scanCommand scans the specified directory for GitHub Actions workflows and prints the actions found,
+// along with the known advisories affecting them.
+func (a *App) scanCommand(opts scanOptions) error {
+	format, err := report.ParseFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+
+	advisories, err := a.loadAdvisories(opts.Dir, opts.Advisories, opts.Verbose)
+	if err != nil {
+		return err
+	}
+
+	verbose := opts.Verbose
+	if verbose {
+		log.SetOutput(a.Err)
+		log.Println("Starting GitHub Actions digest pinner utility")
+		log.Printf("Scanning directory: %s", opts.Dir)
+	}
+
+	fsys := a.FS(opts.Dir)
+
+	if verbose {
+		log.Println("Finding workflow and action files...")
+	}
+
+	files, err := a.findFiles(fsys)
+	if err != nil {
+		return err
+	}
+
+	if verbose {
+		log.Printf("Found %d workflow and action files", len(files))
+		log.Println("Parsing actions in workflow and action files...")
+	}
+
+	var records []report.Record
+	for _, file := range files {
+		if verbose {
+			log.Printf("Processing file: %s", file)
+		}
+
+		fileContent, err := a.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read content of file %s: %w", file, err)
+		}
+
+		actions, err := a.parseFile(file, fileContent)
+		if err != nil {
+			return fmt.Errorf("failed to parse actions in file %s: %w", file, err)
+		}
+
+		if format != report.FormatText {
+			if verbose {
+				log.Printf("Found %d actions in file %s", len(actions), file)
+			}
+			for _, action := range actions {
+				record := report.NewRecord(file, action)
+				record.Advisories = advisoryIDs(advisories.Match(action))
+				records = append(records, record)
+			}
+			continue
+		}
+
+		if verbose {
+			log.Printf("Found %d actions in file %s", len(actions), file)
+			for _, action := range actions {
+				label := "Action"
+				if action.Kind == types.KindReusableWorkflow {
+					label = "Workflow"
+				} else if action.IsImage() {
+					label = "Image"
+				}
+				suffix := ""
+				if action.Ignored {
+					suffix = fmt.Sprintf(" (ignored: %s)", action.IgnoreReason)
+				}
+				_, err := fmt.Fprintf(a.Out, "- %s: %s%s\n", label, action, suffix)
+				if err != nil {
+					return fmt.Errorf("failed to write action output: %w", err)
+				}
+			}
+		} else if len(actions) > 0 {
+			summary := fmt.Sprintf("%s: %d actions found", file, len(actions))
+			if ignored := countIgnored(actions); ignored > 0 {
+				summary += fmt.Sprintf(", %d ignored", ignored)
+			}
+			_, err := fmt.Fprintln(a.Out, summary)
+			if err != nil {
+				return fmt.Errorf("failed to write actions found output: %w", err)
+			}
+		}
+
+		for _, action := range actions {
+			if err := a.writeAdvisories(file, action, advisories.Match(action)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if format != report.FormatText {
+		return a.writeRecords(format, records, "warning")
+	}
+
+	return nil
+}
+
+// checkOptions holds the flags of the check command.
+type checkOptions struct {
+	Dir    string
+	Allow  []string
+	Format string
+	// Advisories is an advisory file or directory relative to Dir; empty uses the imported database, if any.
+	Advisories string
+	Verbose    bool
+}
+
+// advisoryHit is a reference affected by known advisories.
+type advisoryHit struct {
+	File       string
+	Action     types.ActionRef
+	Advisories []advisory.Advisory
+}
+
+// checkCommand reports every action reference that is not pinned to a commit SHA, and every reference
+// affected by a known advisory, and fails if any are found. It works offline, as no references need
+// to be resolved. References ignored by a directive are not required to be pinned; structured output
+// lists them as suppressed.
+func (a *App) checkCommand(opts checkOptions) error {
+	format, err := report.ParseFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+
+	advisories, err := a.loadAdvisories(opts.Dir, opts.Advisories, opts.Verbose)
+	if err != nil {
+		return err
+	}
+
+	verbose := opts.Verbose
+	if verbose {
+		log.SetOutput(a.Err)
+		log.Println("Starting GitHub Actions digest pinner utility")
+		log.Printf("Checking directory: %s", opts.Dir)
+	}
+
+	fsys := a.FS(opts.Dir)
+
+	files, err := a.findFiles(fsys)
+	if err != nil {
+		return err
+	}
+
+	if verbose {
+		log.Printf("Found %d workflow and action files", len(files))
+	}
+
+	results, err := a.parseFiles(fsys, files)
+	if err != nil {
+		return err
+	}
+
+	chk := checker.NewChecker(opts.Allow)
+	total := 0
+	var findings []checker.Finding
+	var hits []advisoryHit
+	for _, result := range results {
+		total += len(result.Actions)
+		findings = append(findings, chk.Check(result.File, result.Actions)...)
+		for _, action := range result.Actions {
+			if matches := advisories.Match(action); len(matches) > 0 {
+				hits = append(hits, advisoryHit{File: result.File, Action: action, Advisories: matches})
+			}
+		}
+	}
+
+	failures := 0
+	for _, finding := range findings {
+		if !finding.Action.Ignored {
+			failures++
+		}
+	}
+	suppressed := len(findings) - failures
+
+	if format != report.FormatText {
+		records := make([]report.Record, 0, len(findings))
+		for _, finding := range findings {
+			record := report.NewRecord(finding.File, finding.Action)
+			record.Advisories = advisoryIDs(advisories.Match(finding.Action))
+			records = append(records, record)
+		}
+		// References that are pinned or allowed are only reported if they are affected by an advisory
+		for _, hit := range hits {
+			if checker.IsPinned(hit.Action) || chk.IsAllowed(hit.Action) {
+				record := report.NewRecord(hit.File, hit.Action)
+				record.Advisories = advisoryIDs(hit.Advisories)
+				record.Allowed = !record.Pinned
+				records = append(records, record)
+			}
+		}
+		if err := a.writeRecords(format, records, "error"); err != nil {
+			return err
+		}
+		return checkError(failures, len(hits))
+	}
+
+	for _, finding := range findings {
+		if finding.Action.Ignored {
+			if verbose {
+				log.Printf("Ignoring %s at %s:%d (%s)", finding.Action, finding.File, finding.Action.Line, finding.Action.IgnoreReason)
+			}
+			continue
+		}
+		_, err := fmt.Fprintf(a.Out, "%s:%d:%d: %s is not pinned to %s\n",
+			finding.File, finding.Action.Line, finding.Action.Column, finding.Action, checker.PinTarget(finding.Action))
+		if err != nil {
+			return fmt.Errorf("failed to write finding output: %w", err)
+		}
+	}
+
+	for _, hit := range hits {
+		if err := a.writeAdvisories(hit.File, hit.Action, hit.Advisories); err != nil {
+			return err
+		}
+	}
+
+	if err := checkError(failures, len(hits)); err != nil {
+		return err
+	}
+
+	if suppressed > 0 {
+		_, err = fmt.Fprintf(a.Out, "All %d action references in %d files are pinned, except %d ignored\n", total, len(files), suppressed)
+	} else {
+		_, err = fmt.Fprintf(a.Out, "All %d action references in %d files are pinned\n", total, len(files))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write check summary output: %w", err)
+	}
+
+	return nil
+}
+
+// checkError returns the error check fails with, or nil if there are neither unpinned nor affected references.
+func checkError(unpinned, affected int) error {
+	switch {
+	case unpinned > 0 && affected > 0:
+		return fmt.Errorf("found %d unpinned action references and %d references affected by advisories", unpinned, affected)
+	case unpinned > 0:
+		return fmt.Errorf("found %d unpinned action references", unpinned)
+	case affected > 0:
+		return fmt.Errorf("found %d references affected by advisories", affected)
+	}
+	return nil
+}
+
+// loadAdvisories loads the advisories of a file or directory, relative to dir, or of the imported
+// database if path is empty. Without an imported database no advisories are checked, which is not an error.
+func (a *App) loadAdvisories(dir, path string, verbose bool) (*advisory.Database, error) {
+	switch {
+	case path != "" && !filepath.IsAbs(path):
+		path = filepath.Join(dir, path)
+	case path == "":
+		if a.AdvisoryDB == "" {
+			return nil, nil
+		}
+		if _, err := os.Stat(a.AdvisoryDB); errors.Is(err, fs.ErrNotExist) {
+			if verbose {
+				log.Printf("No advisory database at %s, skipping advisories", a.AdvisoryDB)
+			}
+			return nil, nil
+		}
+		path = a.AdvisoryDB
+	}
+
+	db, err := advisory.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if verbose {
+		log.Printf("Loaded %d advisories from %s", len(db.Advisories()), path)
+	}
+	return db, nil
+}
+
+// writeAdvisories prints a line per advisory affecting a reference.
+func (a *App) writeAdvisories(file string, action types.ActionRef, matches []advisory.Advisory) error {
+	for _, adv := range matches {
+		_, err := fmt.Fprintf(a.Out, "%s:%d:%d: %s is affected by %s (%s): %s\n",
+			file, action.Line, action.Column, action, adv.ID, adv.Severity(), adv.Summary)
+		if err != nil {
+			return fmt.Errorf("failed to write advisory output: %w", err)
+		}
+	}
+	return nil
+}
+
+// advisoryIDs returns the IDs of advisories.
+func advisoryIDs(advisories []advisory.Advisory) []string {
+	var ids []string
+	for _, adv := range advisories {
+		ids = append(ids, adv.ID)
+	}
+	return ids
+}
+
+// advisoriesImportOptions holds the flags of the advisories import command.
+type advisoriesImportOptions struct {
+	Sources []string
+	Output  string
+}
+
+// advisoriesImportCommand reads OSV advisories from files, ZIP archives or directories and merges
+// those for GitHub Actions into the advisory database, so that scan and check can use them offline.
+func (a *App) advisoriesImportCommand(opts advisoriesImportOptions) error {
+	if opts.Output == "" {
+		return fmt.Errorf("no advisory database location available, use --output")
+	}
+
+	var imported []advisory.Advisory
+	for _, source := range opts.Sources {
+		found, err := advisory.Read(source)
+		if err != nil {
+			return err
+		}
+		imported = append(imported, found...)
+	}
+	imported = advisory.NewDatabase(imported).Advisories()
+
+	var existing []advisory.Advisory
+	if _, err := os.Stat(opts.Output); err == nil {
+		db, err := advisory.Load(opts.Output)
+		if err != nil {
+			return err
+		}
+		existing = db.Advisories()
+	}
+
+	db := advisory.NewDatabase(advisory.Merge(existing, imported))
+	if err := db.Save(opts.Output); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(a.Out, "Imported %d advisories for GitHub Actions into %s (%d in total)\n", len(imported), opts.Output, len(db.Advisories()))
+	if err != nil {
+		return fmt.Errorf("failed to write import output: %w", err)
+	}
+	return nil
+}
+
+// auditOptions holds the flags of the audit command.
+type auditOptions struct {
+	Dir           string
+	Format        string
+	Timeout       int
+	Verbose       bool
+	VerifyCommits bool
+}
+
+// auditCommand re-resolves the version tag recorded next to every pinned SHA and reports tags that
+// were moved to another commit since the reference was pinned, as well as newer releases. With
+// VerifyCommits, it also reports pinned commits that do not belong to their repository. It fails
+// if any tag was moved or cannot be resolved anymore, or any impostor commit was found.
+func (a *App) auditCommand(opts auditOptions) error {
+	if opts.Format != string(report.FormatText) && opts.Format != string(report.FormatJSON) {
+		return fmt.Errorf("unsupported output format %q, expected text or json", opts.Format)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.Timeout)*time.Second)
+	defer cancel()
+
+	if opts.Verbose {
+		log.SetOutput(a.Err)
+		log.Println("Starting GitHub Actions digest pinner utility")
+		log.Printf("Auditing directory: %s", opts.Dir)
+	}
+
+	fsys := a.FS(opts.Dir)
+
+	files, err := a.findFiles(fsys)
+	if err != nil {
+		return err
+	}
+
+	results, err := a.parseFiles(fsys, files)
+	if err != nil {
+		return err
+	}
+
+	auditor := audit.NewAuditor(a.Client)
+	if opts.VerifyCommits {
+		if err := auditor.EnableCommitVerification(); err != nil {
+			return err
+		}
+	}
+	findings := []audit.Finding{}
+	for _, result := range results {
+		fileFindings, err := auditor.Audit(ctx, result.File, result.Actions)
+		if err != nil {
+			return fmt.Errorf("failed to audit %s: %w", result.File, err)
+		}
+		findings = append(findings, fileFindings...)
+	}
+
+	counts := make(map[audit.Status]int)
+	for _, finding := range findings {
+		counts[finding.Status]++
+	}
+	moved, unresolved, impostors := counts[audit.StatusTagMoved], counts[audit.StatusUnresolved], counts[audit.StatusImpostor]
+
+	if opts.Format == string(report.FormatJSON) {
+		encoder := json.NewEncoder(a.Out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(findings); err != nil {
+			return fmt.Errorf("failed to write audit output: %w", err)
+		}
+	} else if err := a.writeAuditText(findings, counts, opts.Verbose); err != nil {
+		return err
+	}
+
+	var problems []string
+	if moved > 0 {
+		problems = append(problems, fmt.Sprintf("%d moved tags", moved))
+	}
+	if unresolved > 0 {
+		problems = append(problems, fmt.Sprintf("%d unresolved tags", unresolved))
+	}
+	if impostors > 0 {
+		problems = append(problems, fmt.Sprintf("%d impostor commits", impostors))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %s", strings.Join(problems, " and "))
+	}
+	return nil
+}
+
+// writeAuditText prints a line per impostor commit, moved or unresolved tag, newer release and
+// unverified commit, and a summary of the number of findings by status. Unchanged references are only listed
+// in verbose mode.
+func (a *App) writeAuditText(findings []audit.Finding, counts map[audit.Status]int, verbose bool) error {
+	for _, finding := range findings {
+		var msg string
+		switch finding.Status {
+		case audit.StatusImpostor:
+			repo, _, _ := strings.Cut(finding.Uses, "@")
+			msg = fmt.Sprintf("impostor commit: not reachable from any branch or tag of %s", repo)
+		case audit.StatusTagMoved:
+			msg = fmt.Sprintf("tag moved: %s now points to %s", finding.Version, finding.CurrentSHA)
+		case audit.StatusNewerRelease:
+			msg = fmt.Sprintf("newer release available: %s", finding.Latest)
+		case audit.StatusUnresolved:
+			msg = fmt.Sprintf("tag unresolved: %s", finding.Error)
+		case audit.StatusUnverified:
+			repo, _, _ := strings.Cut(finding.Uses, "@")
+			msg = fmt.Sprintf("unverified commit: %s has too many branches and tags to check them all", repo)
+		default:
+			if !verbose {
+				continue
+			}
+			if finding.Version == "" {
+				msg = "commit is reachable from the repository"
+			} else {
+				msg = fmt.Sprintf("%s still points to the pinned commit", finding.Version)
+			}
+		}
+
+		uses := finding.Uses
+		if finding.Version != "" {
+			uses += " # " + finding.Version
+		}
+		_, err := fmt.Fprintf(a.Out, "%s:%d:%d: %s: %s\n", finding.File, finding.Line, finding.Column, uses, msg)
+		if err != nil {
+			return fmt.Errorf("failed to write audit output: %w", err)
+		}
+	}
+
+	summary := fmt.Sprintf("Audited %d pinned action references: %d moved tags, %d newer releases, %d impostor commits",
+		len(findings), counts[audit.StatusTagMoved], counts[audit.StatusNewerRelease], counts[audit.StatusImpostor])
+	if n := counts[audit.StatusUnresolved]; n > 0 {
+		summary += fmt.Sprintf(", %d unresolved tags", n)
+	}
+	if n := counts[audit.StatusUnverified]; n > 0 {
+		summary += fmt.Sprintf(", %d unverified commits", n)
+	}
+	if _, err := fmt.Fprintln(a.Out, summary); err != nil {
+		return fmt.Errorf("failed to write audit summary output: %w", err)
+	}
+	return nil
+}
+
+// depsOptions holds the flags of the deps command.
+type depsOptions struct {
+	Dir      string
+	Format   string
+	Timeout  int
+	Verbose  bool
+	NoCache  bool
+	CacheTTL time.Duration
+}
+
+// depsRecord is the dependency tree of a reference found in a file
+type depsRecord struct {
+	File string `json:"file"`
+	*deps.Node
+}
+
+// depsCommand builds the dependency tree of every action and reusable workflow reference from the
+// metadata of the actions at the commits they resolve to, and fails if any transitive dependency is
+// not pinned. Pinning a composite action does not pin the actions it runs in turn.
+func (a *App) depsCommand(opts depsOptions) error {
+	if opts.Format != string(report.FormatText) && opts.Format != string(report.FormatJSON) {
+		return fmt.Errorf("unsupported output format %q, expected text or json", opts.Format)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.Timeout)*time.Second)
+	defer cancel()
+
+	if opts.Verbose {
+		log.SetOutput(a.Err)
+		log.Println("Starting GitHub Actions digest pinner utility")
+		log.Printf("Analyzing dependencies in directory: %s", opts.Dir)
+	}
+
+	fsys := a.FS(opts.Dir)
+
+	files, err := a.findFiles(fsys)
+	if err != nil {
+		return err
+	}
+
+	results, err := a.parseFiles(fsys, files)
+	if err != nil {
+		return err
+	}
+
+	client := a.Client
+	if !opts.NoCache && a.CacheDir != "" {
+		if opts.Verbose {
+			log.Printf("Using cache directory: %s", a.CacheDir)
+		}
+		client = ghclient.NewDiskCachingClient(client, a.CacheDir, opts.CacheTTL)
+	}
+	analyzer, err := deps.NewAnalyzer(client)
+	if err != nil {
+		return err
+	}
+
+	records := []depsRecord{}
+	unpinned := 0
+	for _, result := range results {
+		for _, action := range result.Actions {
+			if action.Ignored || action.IsImage() {
+				continue
+			}
+			node, err := analyzer.Analyze(ctx, action)
+			if err != nil {
+				return fmt.Errorf("failed to analyze %s: %w", action, err)
+			}
+			records = append(records, depsRecord{File: result.File, Node: node})
+			unpinned += len(node.Unpinned())
+		}
+	}
+
+	if opts.Format == string(report.FormatJSON) {
+		encoder := json.NewEncoder(a.Out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(records); err != nil {
+			return fmt.Errorf("failed to write dependency output: %w", err)
+		}
+	} else if err := a.writeDepsText(records, unpinned); err != nil {
+		return err
+	}
+
+	if unpinned > 0 {
+		return fmt.Errorf("found %d unpinned transitive references", unpinned)
+	}
+	return nil
+}
+
+// writeDepsText prints every dependency tree, indenting dependencies below the reference running them,
+// followed by a summary
+func (a *App) writeDepsText(records []depsRecord, unpinned int) error {
+	var write func(node *deps.Node, depth int) error
+	write = func(node *deps.Node, depth int) error {
+		line := strings.Repeat("  ", depth) + node.Uses
+		switch {
+		case node.Cycle:
+			line += " (cycle)"
+		case node.Error != "":
+			line += " (" + node.Error + ")"
+		}
+		if depth > 0 && !node.Pinned {
+			line += " [not pinned]"
+		}
+		if _, err := fmt.Fprintln(a.Out, line); err != nil {
+			return fmt.Errorf("failed to write dependency output: %w", err)
+		}
+
+		for _, dep := range node.Dependencies {
+			if err := write(dep, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, record := range records {
+		if _, err := fmt.Fprintf(a.Out, "%s:%d:%d: ", record.File, record.Line, record.Column); err != nil {
+			return fmt.Errorf("failed to write dependency output: %w", err)
+		}
+		if err := write(record.Node, 0); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(a.Out, "Analyzed the dependencies of %d references: %d unpinned transitive references\n", len(records), unpinned)
+	if err != nil {
+		return fmt.Errorf("failed to write dependency summary output: %w", err)
+	}
+	return nil
+}
+
+// policyOptions holds the flags of the policy check command.
+type policyOptions struct {
+	Dir string
+	// Policy is the path of the policy file, relative to Dir unless absolute.
+	Policy  string
+	Format  string
+	Verbose bool
+}
+
+// policyCheckCommand evaluates every reference against the rules of the policy file and reports the
+// violations. It works offline and fails if any violation has error severity. References ignored by
+// a directive are not evaluated.
+func (a *App) policyCheckCommand(opts policyOptions) error {
+	if opts.Format != string(report.FormatText) && opts.Format != string(report.FormatJSON) {
+		return fmt.Errorf("unsupported output format %q, expected text or json", opts.Format)
+	}
+
+	if opts.Verbose {
+		log.SetOutput(a.Err)
+		log.Println("Starting GitHub Actions digest pinner utility")
+		log.Printf("Checking policy in directory: %s", opts.Dir)
+	}
+
+	policyPath := opts.Policy
+	if !filepath.IsAbs(policyPath) {
+		policyPath = filepath.Join(opts.Dir, policyPath)
+	}
+	pol, err := policy.Load(policyPath)
+	if err != nil {
+		return err
+	}
+
+	fsys := a.FS(opts.Dir)
+
+	files, err := a.findFiles(fsys)
+	if err != nil {
+		return err
+	}
+
+	results, err := a.parseFiles(fsys, files)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	violations := []policy.Violation{}
+	for _, result := range results {
+		for _, action := range result.Actions {
+			if action.Ignored {
+				if opts.Verbose {
+					log.Printf("Ignoring %s at %s:%d (%s)", action, result.File, action.Line, action.IgnoreReason)
+				}
+				continue
+			}
+			total++
+			if v, ok := pol.Evaluate(result.File, action); ok {
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	counts := make(map[policy.Severity]int)
+	for _, v := range violations {
+		counts[v.Severity]++
+	}
+
+	if opts.Format == string(report.FormatJSON) {
+		encoder := json.NewEncoder(a.Out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(violations); err != nil {
+			return fmt.Errorf("failed to write policy output: %w", err)
+		}
+	} else {
+		for _, v := range violations {
+			_, err := fmt.Fprintf(a.Out, "%s:%d:%d: %s: %s [%s]\n", v.File, v.Line, v.Column, v.Severity, v.Message, v.Rule)
+			if err != nil {
+				return fmt.Errorf("failed to write policy output: %w", err)
+			}
+		}
+
+		_, err := fmt.Fprintf(a.Out, "Checked %d action references against %d rules: %d errors, %d warnings, %d notes\n",
+			total, len(pol.Rules), counts[policy.SeverityError], counts[policy.SeverityWarning], counts[policy.SeverityNote])
+		if err != nil {
+			return fmt.Errorf("failed to write policy summary output: %w", err)
+		}
+	}
+
+	if counts[policy.SeverityError] > 0 {
+		return fmt.Errorf("found %d policy errors", counts[policy.SeverityError])
+	}
+	return nil
+}
+
+// updateOptions holds the flags of the update command.
+type updateOptions struct {
+	Dir         string
+	Timeout     int
+	Verbose     bool
+	DryRun      bool
+	DiffFormat  string
+	Concurrency int
+	NoCache     bool
+	CacheTTL    time.Duration
+	// Lockfile is the path of the lockfile relative to Dir; empty disables it.
+	Lockfile string
+	Frozen   bool
+	// NoImages leaves container, service and docker:// images unchanged instead of pinning them to digests.
+	NoImages bool
+}
+
+// updateCommand updates the GitHub Actions workflows in the specified directory to use pinned digests.
+func (a *App) updateCommand(opts updateOptions) error {
+	if opts.DryRun && opts.DiffFormat != "unified" && opts.DiffFormat != "json" {
+		return fmt.Errorf("unsupported diff format %q, expected unified or json", opts.DiffFormat)
+	}
+	if opts.Frozen && opts.Lockfile == "" {
+		return fmt.Errorf("--frozen requires a lockfile")
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.Timeout)*time.Second)
+	defer cancel()
+
+	if opts.Verbose {
+		log.SetOutput(a.Err)
+		log.Println("Starting GitHub Actions digest pinner utility")
+		log.Printf("Scanning directory: %s", opts.Dir)
+	}
+
+	absDir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	fsys := a.FS(absDir)
+
+	if opts.Verbose {
+		log.Println("Finding workflow and action files...")
+	}
+
+	files, err := a.findFiles(fsys)
+	if err != nil {
+		return err
+	}
+
+	if opts.Verbose {
+		log.Printf("Found %d workflow and action files", len(files))
+	}
+
+	var lock *lockfile.Lockfile
+	lockPath := opts.Lockfile
+	if lockPath != "" && !filepath.IsAbs(lockPath) {
+		lockPath = filepath.Join(absDir, lockPath)
+	}
+
+	upd, isUpdater := a.Updater.(*updater.Updater)
+	if isUpdater {
+		upd.SetBaseDir(absDir)
+		upd.SetFileFilter(a.Filter)
+		upd.SetDryRun(opts.DryRun)
+		upd.SetFrozen(opts.Frozen)
+		if opts.NoImages {
+			upd.SetImageResolver(nil)
+		}
+		if opts.Concurrency > 0 {
+			upd.SetConcurrency(opts.Concurrency)
+		}
+		if !opts.NoCache && !opts.Frozen && a.CacheDir != "" {
+			if opts.Verbose {
+				log.Printf("Using cache directory: %s", a.CacheDir)
+			}
+			upd.Client = ghclient.NewDiskCachingClient(upd.Client, a.CacheDir, opts.CacheTTL)
+		}
+		if lockPath != "" {
+			if lock, err = lockfile.Load(lockPath); err != nil {
+				return err
+			}
+			upd.SetLockfile(lock)
+		}
+	} else if opts.DryRun || opts.Frozen {
+		return fmt.Errorf("dry run and frozen mode are not supported by the configured updater")
+	}
+
+	totalUpdates, err := a.Updater.UpdateWorkflows(ctx, fsys)
+	if opts.Verbose {
+		a.logRateLimit()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update workflows: %w", err)
+	}
+
+	if opts.DryRun {
+		return a.writeDryRun(upd.Results(), opts.DiffFormat, totalUpdates, time.Since(start))
+	}
+
+	if lock != nil && lock.Changed() {
+		if err := lock.Save(lockPath); err != nil {
+			return err
+		}
+		if opts.Verbose {
+			log.Printf("Wrote lockfile: %s", lockPath)
+		}
+	}
+
+	if opts.Verbose {
+		log.Printf("Updated %d action references in %v", totalUpdates, time.Since(start).Round(time.Millisecond))
+		for _, file := range files {
+			_, err := fmt.Fprintf(a.Out, "- Processed: %s\n", file)
+			if err != nil {
+				return fmt.Errorf("failed to write processed file output: %w", err)
+			}
+		}
+	} else {
+		_, err := fmt.Fprintf(a.Out, "Updated %d action references in %v\n", totalUpdates, time.Since(start).Round(time.Millisecond))
+		if err != nil {
+			return fmt.Errorf("failed to write update summary output: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// upgradeOptions holds the flags of the upgrade command.
+type upgradeOptions struct {
+	Dir        string
+	Timeout    int
+	Verbose    bool
+	DryRun     bool
+	DiffFormat string
+	// Level is the largest allowed upgrade (patch, minor or major); Range replaces it if set.
+	Level string
+	Range string
+}
+
+// upgradeCommand moves the pinned actions in the specified directory to the newest release allowed by
+// the upgrade level or version range, based on the version comment next to each pinned SHA.
+func (a *App) upgradeCommand(opts upgradeOptions) error {
+	if opts.DryRun && opts.DiffFormat != "unified" && opts.DiffFormat != "json" {
+		return fmt.Errorf("unsupported diff format %q, expected unified or json", opts.DiffFormat)
+	}
+
+	constraint, err := semver.ParseLevel(opts.Level)
+	if opts.Range != "" {
+		constraint, err = semver.ParseRange(opts.Range)
+	}
+	if err != nil {
+		return err
+	}
+
+	upd, ok := a.Updater.(*updater.Updater)
+	if !ok {
+		return fmt.Errorf("upgrade is not supported by the configured updater")
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.Timeout)*time.Second)
+	defer cancel()
+
+	if opts.Verbose {
 		log.SetOutput(a.Err)
 		log.Println("Starting GitHub Actions digest pinner utility")
-		log.Printf("Scanning directory: %s", dir)
+		log.Printf("Upgrading actions in directory: %s", opts.Dir)
+	}
+
+	absDir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
-	fsys := a.FS(dir)
+	upd.SetBaseDir(absDir)
+	upd.SetDryRun(opts.DryRun)
+	upd.SetFileFilter(a.Filter)
 
-	if verbose {
-		log.Println("Finding workflow files...")
+	totalUpgrades, err := upd.UpgradeWorkflows(ctx, a.FS(absDir), constraint)
+	if opts.Verbose {
+		a.logRateLimit()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upgrade workflows: %w", err)
+	}
+
+	if opts.DryRun {
+		return a.writeDryRun(upd.Results(), opts.DiffFormat, totalUpgrades, time.Since(start))
+	}
+
+	_, err = fmt.Fprintf(a.Out, "Upgraded %d action references in %v\n", totalUpgrades, time.Since(start).Round(time.Millisecond))
+	if err != nil {
+		return fmt.Errorf("failed to write upgrade summary output: %w", err)
+	}
+	return nil
+}
+
+// logRateLimit logs the remaining GitHub API quota, if the client reports it.
+func (a *App) logRateLimit() {
+	client := a.Client
+	if upd, ok := a.Updater.(*updater.Updater); ok {
+		client = upd.Client
+	}
+
+	reporter, ok := ghclient.AsRateReporter(client)
+	if !ok {
+		return
+	}
+	if rate, ok := reporter.RateLimit(); ok {
+		log.Printf("GitHub API quota: %d of %d requests remaining, resets at %s", rate.Remaining, rate.Limit, rate.Reset.Format(time.RFC3339))
+	}
+}
+
+// writeDryRun prints the changes an update would make, either as unified diffs per file or as
+// a JSON list of the individual reference changes.
+func (a *App) writeDryRun(results []updater.FileResult, format string, totalUpdates int, elapsed time.Duration) error {
+	if format == "json" {
+		changes := []updater.Change{}
+		for _, result := range results {
+			changes = append(changes, result.Changes...)
+		}
+
+		encoder := json.NewEncoder(a.Out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(changes); err != nil {
+			return fmt.Errorf("failed to write dry run output: %w", err)
+		}
+		return nil
+	}
+
+	for _, result := range results {
+		if _, err := fmt.Fprint(a.Out, result.Diff()); err != nil {
+			return fmt.Errorf("failed to write diff output: %w", err)
+		}
+	}
+
+	_, err := fmt.Fprintf(a.Out, "Would update %d action references in %v\n", totalUpdates, elapsed.Round(time.Millisecond))
+	if err != nil {
+		return fmt.Errorf("failed to write update summary output: %w", err)
 	}
+	return nil
+}
 
+// findFiles returns all workflow files and action metadata files in the filesystem selected by the file filter.
+func (a *App) findFiles(fsys fs.FS) ([]string, error) {
 	files, err := a.Finder.FindWorkflowFiles(fsys)
 	if err != nil {
-		return fmt.Errorf("failed to find workflow files: %w", err)
+		return nil, fmt.Errorf("failed to find workflow files: %w", err)
 	}
 
-	if verbose {
-		log.Printf("Found %d workflow files", len(files))
-		log.Println("Parsing actions in workflow files...")
+	actionFiles, err := a.Finder.FindActionFiles(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find action files: %w", err)
+	}
+
+	return a.Filter.Apply(append(files, actionFiles...)), nil
+}
+
+// countIgnored returns the number of references suppressed by an ignore directive.
+func countIgnored(actions []types.ActionRef) int {
+	ignored := 0
+	for _, action := range actions {
+		if action.Ignored {
+			ignored++
+		}
+	}
+	return ignored
+}
+
+// parseFile extracts action references from a file using the parser matching its type.
+func (a *App) parseFile(file string, content []byte) ([]types.ActionRef, error) {
+	if finder.IsActionFile(file) {
+		return a.Parser.ParseCompositeActions(content)
+	}
+	return a.Parser.ParseWorkflowActions(content)
+}
+
+// writeRecords writes records in a structured output format. SARIF results are reported with the given level.
+func (a *App) writeRecords(format report.Format, records []report.Record, level string) error {
+	switch format {
+	case report.FormatJSON:
+		return report.WriteJSON(a.Out, records)
+	case report.FormatCSV:
+		return report.WriteCSV(a.Out, records)
+	case report.FormatSARIF:
+		return report.WriteSARIF(a.Out, records, version, level)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
 	}
+}
+
+// fileActions holds the action references parsed from a single file.
+type fileActions struct {
+	File    string
+	Actions []types.ActionRef
+}
 
+// parseFiles reads and parses the given files, returning the action references found in each.
+func (a *App) parseFiles(fsys fs.FS, files []string) ([]fileActions, error) {
+	results := make([]fileActions, 0, len(files))
 	for _, file := range files {
-		if verbose {
-			log.Printf("Processing file: %s", file)
+		content, err := a.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read content of file %s: %w", file, err)
 		}
 
-		fileContent, err := a.ReadFile(fsys, file)
+		actions, err := a.parseFile(file, content)
 		if err != nil {
-			return fmt.Errorf("failed to read content of file %s: %w", file, err)
+			return nil, fmt.Errorf("failed to parse actions in file %s: %w", file, err)
+		}
+
+		results = append(results, fileActions{File: file, Actions: actions})
+	}
+	return results, nil
+}
+
+// versionCommand prints the version information of the application.
+func (a *App) versionCommand() {
+	_, err := fmt.Fprintf(a.Out, "Version: %s\nCommit: %s\nDate: %s\n", version, commit, date)
+	if err != nil {
+		log.Printf("Failed to write version output: %v", err)
+	}
+}
+
+// clientOptions holds the persistent flags that select the GitHub API endpoints.
+type clientOptions struct {
+	GitHubURL string
+	Routes    []string
+	// Resolver selects how references are resolved: "api" (REST API), "git" (smart-HTTP protocol)
+	// or "graphql" (batched GraphQL queries).
+	Resolver string
+	// AppID, AppPrivateKeyFile or AppPrivateKey (PEM) and InstallationID authenticate as a GitHub App.
+	AppID             int64
+	AppPrivateKeyFile string
+	AppPrivateKey     string
+	InstallationID    int64
+}
+
+// isDefault reports whether the options select the REST API of github.com authenticated with GITHUB_TOKEN.
+func (o clientOptions) isDefault() bool {
+	return o.GitHubURL == "" && len(o.Routes) == 0 && (o.Resolver == "" || o.Resolver == "api") &&
+		o.AppID == 0 && o.AppPrivateKeyFile == "" && o.AppPrivateKey == "" && o.InstallationID == 0
+}
+
+// clientOptionsFromFlags reads the client options from the persistent flags, falling back to
+// GITHUB_API_URL, GITHUB_APP_ID, GITHUB_APP_PRIVATE_KEY and GITHUB_APP_INSTALLATION_ID.
+func clientOptionsFromFlags(cmd *cobra.Command) (clientOptions, error) {
+	var opts clientOptions
+	opts.GitHubURL, _ = cmd.Flags().GetString("github-url")
+	opts.Routes, _ = cmd.Flags().GetStringSlice("github-route")
+	opts.Resolver, _ = cmd.Flags().GetString("resolver")
+	opts.AppID, _ = cmd.Flags().GetInt64("app-id")
+	opts.AppPrivateKeyFile, _ = cmd.Flags().GetString("app-private-key")
+	opts.InstallationID, _ = cmd.Flags().GetInt64("installation-id")
+
+	if opts.GitHubURL == "" {
+		opts.GitHubURL = os.Getenv("GITHUB_API_URL")
+	}
+	if opts.AppPrivateKeyFile == "" {
+		opts.AppPrivateKey = os.Getenv("GITHUB_APP_PRIVATE_KEY")
+	}
+	if err := int64FromEnv("GITHUB_APP_ID", &opts.AppID); err != nil {
+		return opts, err
+	}
+	if err := int64FromEnv("GITHUB_APP_INSTALLATION_ID", &opts.InstallationID); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// int64FromEnv sets value from an environment variable unless it is already set.
+func int64FromEnv(name string, value *int64) error {
+	if *value != 0 || os.Getenv(name) == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	*value = parsed
+	return nil
+}
+
+// newDefaultClient creates the client for the GitHubURL API, authenticated as a GitHub App if configured.
+func newDefaultClient(opts clientOptions) (ghclient.GitHubClient, error) {
+	if opts.AppID == 0 {
+		if opts.InstallationID != 0 || opts.AppPrivateKeyFile != "" {
+			return nil, fmt.Errorf("--installation-id and --app-private-key require --app-id")
 		}
+		return newClientForURL(opts.Resolver, opts.GitHubURL)
+	}
+	if opts.Resolver == "git" {
+		return nil, fmt.Errorf("GitHub App authentication requires --resolver=api or --resolver=graphql")
+	}
 
-		actions, err := a.Parser.ParseWorkflowActions(fileContent)
+	key := []byte(opts.AppPrivateKey)
+	if opts.AppPrivateKeyFile != "" {
+		var err error
+		key, err = os.ReadFile(opts.AppPrivateKeyFile)
 		if err != nil {
-			return fmt.Errorf("failed to parse actions in file %s: %w", file, err)
+			return nil, fmt.Errorf("failed to read app private key: %w", err)
 		}
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("--app-id requires --app-private-key or GITHUB_APP_PRIVATE_KEY")
+	}
 
-		if verbose {
-			log.Printf("Found %d actions in file %s", len(actions), file)
-			for _, action := range actions {
-				_, err := fmt.Fprintf(a.Out, "- Action: %s/%s@%s\n", action.Owner, action.Repo, action.Ref)
+	client, err := ghclient.NewGitHubAppClient(opts.GitHubURL, ghclient.AppConfig{
+		AppID:          opts.AppID,
+		PrivateKey:     key,
+		InstallationID: opts.InstallationID,
+	})
+	if err != nil || opts.Resolver != "graphql" {
+		return client, err
+	}
+	return ghclient.NewGraphQLClient(client)
+}
+
+// newClientForURL creates a token authenticated client for the GitHub API at apiURL using the given resolver.
+func newClientForURL(resolver, apiURL string) (ghclient.GitHubClient, error) {
+	switch resolver {
+	case "", "api":
+		return ghclient.NewGitHubClientForURL(apiURL)
+	case "git":
+		return ghclient.NewGitClientForURL(apiURL)
+	case "graphql":
+		client, err := ghclient.NewGitHubClientForURL(apiURL)
+		if err != nil {
+			return nil, err
+		}
+		return ghclient.NewGraphQLClient(client)
+	default:
+		return nil, fmt.Errorf("unsupported resolver %q, expected api, git or graphql", resolver)
+	}
+}
+
+// configureClient points the GitHub client of the application and its updater at the configured
+// API endpoints. Routes have the form owner=url and send the references of an owner to another API,
+// e.g. a GitHub Enterprise Server, while all other owners use GitHubURL. GitHub App
+// authentication applies to GitHubURL; routed APIs are authenticated with tokens.
+func (a *App) configureClient(opts clientOptions) error {
+	if opts.isDefault() {
+		return nil
+	}
+
+	client, err := newDefaultClient(opts)
+	if err != nil {
+		return err
+	}
+
+	if len(opts.Routes) > 0 {
+		clients := make(map[string]ghclient.GitHubClient)
+		routes := make(map[string]ghclient.GitHubClient, len(opts.Routes))
+		for _, route := range opts.Routes {
+			owner, apiURL, ok := strings.Cut(route, "=")
+			if !ok || owner == "" || apiURL == "" {
+				return fmt.Errorf("invalid route %q, expected owner=url", route)
+			}
+
+			if _, ok := clients[apiURL]; !ok {
+				clients[apiURL], err = newClientForURL(opts.Resolver, apiURL)
 				if err != nil {
-					return fmt.Errorf("failed to write action output: %w", err)
+					return err
 				}
 			}
-		} else if len(actions) > 0 {
-			_, err := fmt.Fprintf(a.Out, "%s: %d actions found\n", file, len(actions))
-			if err != nil {
-				return fmt.Errorf("failed to write actions found output: %w", err)
-			}
+			routes[owner] = clients[apiURL]
 		}
+		client = ghclient.NewRoutingClient(client, routes)
 	}
 
+	a.Client = ghclient.NewCachingClient(client)
+	if upd, ok := a.Updater.(*updater.Updater); ok {
+		upd.Client = a.Client
+	}
 	return nil
 }
 
-// updateCommand updates the GitHub Actions workflows in the specified directory to use pinned digests.
-func (a *App) updateCommand(dir string, timeout int, verbose bool) error {
-	start := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+// cacheCleanCommand removes the on-disk resolution cache.
+func (a *App) cacheCleanCommand() error {
+	if a.CacheDir == "" {
+		return fmt.Errorf("no cache directory available")
+	}
 
-	if verbose {
-		log.SetOutput(a.Err)
-		log.Println("Starting GitHub Actions digest pinner utility")
-		log.Printf("Scanning directory: %s", dir)
+	if err := ghclient.CleanCache(a.CacheDir); err != nil {
+		return err
 	}
 
-	absDir, err := filepath.Abs(dir)
+	_, err := fmt.Fprintf(a.Out, "Removed cache directory %s\n", a.CacheDir)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
+		return fmt.Errorf("failed to write cache output: %w", err)
 	}
+	return nil
+}
 
-	fsys := a.FS(absDir)
-
-	if verbose {
-		log.Println("Finding workflow files...")
+// loadConfig loads the configuration file at path, or the default file of dir if path is empty, and
+// merges the DIGEST_PINNER_* environment into it. A missing default file is not an error. It returns
+// the path of the loaded file, which is empty if there was none.
+func loadConfig(dir, path string) (config.Config, string, error) {
+	explicit := path != ""
+	if !explicit {
+		path = filepath.Join(dir, config.DefaultPath)
 	}
 
-	files, err := a.Finder.FindWorkflowFiles(fsys)
+	file, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		file, path, err = config.Config{}, "", nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to find workflow files: %w", err)
+		return config.Config{}, "", err
 	}
 
-	if verbose {
-		log.Printf("Found %d workflow files", len(files))
+	env, err := config.FromEnv(os.Getenv)
+	if err != nil {
+		return config.Config{}, "", err
 	}
+	return config.Merge(file, env), path, nil
+}
 
-	if upd, ok := a.Updater.(*updater.Updater); ok {
-		upd.SetBaseDir(absDir)
+// applyConfig applies the settings of the configuration file and environment to the flags of cmd
+// that were not given on the command line, so flags take precedence over the environment, which
+// takes precedence over the file. The output format only applies to scan and check, as the other
+// commands support fewer formats. Finally, the file filter is set from the resulting flags.
+func (a *App) applyConfig(cmd *cobra.Command) error {
+	dir, err := cmd.Flags().GetString("dir")
+	if err != nil {
+		dir = "."
 	}
+	path, _ := cmd.Flags().GetString("config")
 
-	totalUpdates, err := a.Updater.UpdateWorkflows(ctx, fsys)
+	cfg, _, err := loadConfig(dir, path)
 	if err != nil {
-		return fmt.Errorf("failed to update workflows: %w", err)
+		return err
 	}
 
-	if verbose {
-		log.Printf("Updated %d action references in %v", totalUpdates, time.Since(start).Round(time.Millisecond))
-		for _, file := range files {
-			_, err := fmt.Fprintf(a.Out, "- Processed: %s\n", file)
-			if err != nil {
-				return fmt.Errorf("failed to write processed file output: %w", err)
-			}
+	settings := map[string]string{
+		"include":    strings.Join(cfg.Include, ","),
+		"exclude":    strings.Join(cfg.Exclude, ","),
+		"allow":      strings.Join(cfg.Allow, ","),
+		"resolver":   cfg.Resolver,
+		"policy":     cfg.Policy,
+		"advisories": cfg.Advisories,
+	}
+	if (cmd.Name() == "scan" || cmd.Name() == "check") && cmd.Parent() == cmd.Root() {
+		settings["format"] = cfg.Format
+	}
+	if cfg.Concurrency > 0 {
+		settings["concurrency"] = strconv.Itoa(cfg.Concurrency)
+	}
+	if cfg.Timeout > 0 {
+		settings["timeout"] = strconv.Itoa(cfg.Timeout)
+	}
+
+	for name, value := range settings {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed || value == "" {
+			continue
 		}
-	} else {
-		_, err := fmt.Fprintf(a.Out, "Updated %d action references in %v\n", totalUpdates, time.Since(start).Round(time.Millisecond))
-		if err != nil {
-			return fmt.Errorf("failed to write update summary output: %w", err)
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("failed to apply configured %s: %w", name, err)
 		}
 	}
 
+	a.Filter.Include, _ = cmd.Flags().GetStringSlice("include")
+	a.Filter.Exclude, _ = cmd.Flags().GetStringSlice("exclude")
+	return a.Filter.Validate()
+}
+
+// configOptions holds the flags of the config commands.
+type configOptions struct {
+	Dir  string
+	Path string
+	// Flags holds the settings given on the command line.
+	Flags config.Config
+}
+
+// configValidateCommand validates the configuration file and the DIGEST_PINNER_* environment.
+func (a *App) configValidateCommand(opts configOptions) error {
+	_, path, err := loadConfig(opts.Dir, opts.Path)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		_, err = fmt.Fprintf(a.Out, "No configuration file found at %s, using defaults\n", filepath.Join(opts.Dir, config.DefaultPath))
+	} else {
+		_, err = fmt.Fprintf(a.Out, "Configuration %s is valid\n", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write config output: %w", err)
+	}
 	return nil
 }
 
-// versionCommand prints the version information of the application.
-func (a *App) versionCommand() {
-	_, err := fmt.Fprintf(a.Out, "Version: %s\nCommit: %s\nDate: %s\n", version, commit, date)
+// configPrintCommand prints the effective settings as YAML, merged from the defaults, the
+// configuration file, the environment and the command line, in increasing precedence.
+func (a *App) configPrintCommand(opts configOptions) error {
+	cfg, path, err := loadConfig(opts.Dir, opts.Path)
 	if err != nil {
-		log.Printf("Failed to write version output: %v", err)
+		return err
+	}
+
+	effective := config.Merge(config.Merge(config.Default(), cfg), opts.Flags)
+	if err := effective.Validate(); err != nil {
+		return err
+	}
+
+	source := "none"
+	if path != "" {
+		source = path
+	}
+	if _, err := fmt.Fprintf(a.Out, "# Configuration file: %s\n", source); err != nil {
+		return fmt.Errorf("failed to write config output: %w", err)
+	}
+
+	encoder := yaml.NewEncoder(a.Out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(effective); err != nil {
+		return fmt.Errorf("failed to write config output: %w", err)
 	}
+	return encoder.Close()
 }
 
 // newRootCommand creates the root command for the CLI application.
@@ -196,6 +1458,21 @@ func newRootCommand(app *App) *cobra.Command {
 		Use:   "github-actions-digest-pinner",
 		Short: "A tool to pin GitHub Actions to specific digests",
 		Long:  "GitHub Actions Digest Pinner is a tool to help you pin GitHub Actions to specific digests for better security and reliability.",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if err := app.applyConfig(cmd); err != nil {
+				log.Printf("Invalid configuration: %v", err)
+				os.Exit(1)
+			}
+
+			opts, err := clientOptionsFromFlags(cmd)
+			if err == nil {
+				err = app.configureClient(opts)
+			}
+			if err != nil {
+				log.Printf("Invalid GitHub API configuration: %v", err)
+				os.Exit(1)
+			}
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := cmd.Help(); err != nil {
 				log.Printf("Failed to display help: %v", err)
@@ -204,6 +1481,16 @@ func newRootCommand(app *App) *cobra.Command {
 		},
 	}
 
+	cmd.PersistentFlags().String("github-url", "", "GitHub API URL, e.g. https://ghes.example.com/api/v3 (default: $GITHUB_API_URL or https://api.github.com)")
+	cmd.PersistentFlags().String("resolver", "api", "How references are resolved: api (REST API), git (git smart-HTTP protocol, no API quota) or graphql (batched GraphQL queries)")
+	cmd.PersistentFlags().Int64("app-id", 0, "Authenticate as the GitHub App with this ID (default: $GITHUB_APP_ID)")
+	cmd.PersistentFlags().String("app-private-key", "", "Private key file of the GitHub App (default: PEM in $GITHUB_APP_PRIVATE_KEY)")
+	cmd.PersistentFlags().Int64("installation-id", 0, "GitHub App installation to use; discovered per owner if not set (default: $GITHUB_APP_INSTALLATION_ID)")
+	cmd.PersistentFlags().StringSlice("github-route", nil, "Resolve the actions of an owner against another GitHub API, as owner=url (e.g. corp=https://ghes.example.com/api/v3)")
+	cmd.PersistentFlags().String("config", "", "Configuration file (default: "+config.DefaultPath+" in --dir, if present)")
+	cmd.PersistentFlags().StringSlice("include", nil, "Only process workflow and action files matching these globs, relative to --dir (e.g. .github/**)")
+	cmd.PersistentFlags().StringSlice("exclude", nil, "Skip workflow and action files matching these globs, relative to --dir (e.g. .github/workflows/legacy-*.yml)")
+
 	cmd.AddCommand(&cobra.Command{
 		Use:   "version",
 		Short: "Show the version information",
@@ -214,11 +1501,14 @@ func newRootCommand(app *App) *cobra.Command {
 
 	scanCmd := &cobra.Command{
 		Use:   "scan",
-		Short: "Scan the repository for GitHub Actions workflows",
+		Short: "Scan the repository for GitHub Actions workflows and composite actions",
 		Run: func(cmd *cobra.Command, args []string) {
-			dir, _ := cmd.Flags().GetString("dir")
-			verbose, _ := cmd.Flags().GetBool("verbose")
-			if err := app.scanCommand(dir, verbose); err != nil {
+			var opts scanOptions
+			opts.Dir, _ = cmd.Flags().GetString("dir")
+			opts.Format, _ = cmd.Flags().GetString("format")
+			opts.Advisories, _ = cmd.Flags().GetString("advisories")
+			opts.Verbose, _ = cmd.Flags().GetBool("verbose")
+			if err := app.scanCommand(opts); err != nil {
 				log.Printf("Scan failed: %v", err)
 				os.Exit(1)
 			}
@@ -226,17 +1516,104 @@ func newRootCommand(app *App) *cobra.Command {
 	}
 
 	scanCmd.Flags().String("dir", ".", "Directory containing GitHub workflows")
+	scanCmd.Flags().String("format", "text", "Output format: text, json, sarif or csv")
+	scanCmd.Flags().String("advisories", "", "OSV advisory file, ZIP archive or directory, relative to --dir (default: the imported advisory database)")
 	scanCmd.Flags().Bool("verbose", false, "Verbose output")
 	cmd.AddCommand(scanCmd)
 
+	checkCmd := &cobra.Command{
+		Use:     "check",
+		Aliases: []string{"verify"},
+		Short:   "Fail if any GitHub Actions reference is not pinned to a commit SHA",
+		Run: func(cmd *cobra.Command, args []string) {
+			var opts checkOptions
+			opts.Dir, _ = cmd.Flags().GetString("dir")
+			opts.Allow, _ = cmd.Flags().GetStringSlice("allow")
+			opts.Format, _ = cmd.Flags().GetString("format")
+			opts.Advisories, _ = cmd.Flags().GetString("advisories")
+			opts.Verbose, _ = cmd.Flags().GetBool("verbose")
+			if err := app.checkCommand(opts); err != nil {
+				log.Printf("Check failed: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	checkCmd.Flags().String("dir", ".", "Directory containing GitHub workflows")
+	checkCmd.Flags().StringSlice("allow", nil, "Trusted owners or owner/repo patterns allowed to use tags (e.g. myorg,actions/*)")
+	checkCmd.Flags().String("format", "text", "Output format: text, json, sarif or csv")
+	checkCmd.Flags().String("advisories", "", "OSV advisory file, ZIP archive or directory, relative to --dir (default: the imported advisory database)")
+	checkCmd.Flags().Bool("verbose", false, "Verbose output")
+	cmd.AddCommand(checkCmd)
+
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Detect version tags that were moved since the actions were pinned",
+		Run: func(cmd *cobra.Command, args []string) {
+			var opts auditOptions
+			opts.Dir, _ = cmd.Flags().GetString("dir")
+			opts.Format, _ = cmd.Flags().GetString("format")
+			opts.Timeout, _ = cmd.Flags().GetInt("timeout")
+			opts.Verbose, _ = cmd.Flags().GetBool("verbose")
+			opts.VerifyCommits, _ = cmd.Flags().GetBool("verify-commits")
+			if err := app.auditCommand(opts); err != nil {
+				log.Printf("Audit failed: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	auditCmd.Flags().String("dir", ".", "Directory containing GitHub workflows")
+	auditCmd.Flags().String("format", "text", "Output format: text or json")
+	auditCmd.Flags().Int("timeout", 30, "API timeout in seconds")
+	auditCmd.Flags().Bool("verbose", false, "Verbose output")
+	auditCmd.Flags().Bool("verify-commits", false, "Flag pinned commits that are not reachable from any branch or tag of their repository")
+	cmd.AddCommand(auditCmd)
+
+	depsCmd := &cobra.Command{
+		Use:   "deps",
+		Short: "Find unpinned references in the dependencies of composite actions and reusable workflows",
+		Run: func(cmd *cobra.Command, args []string) {
+			var opts depsOptions
+			opts.Dir, _ = cmd.Flags().GetString("dir")
+			opts.Format, _ = cmd.Flags().GetString("format")
+			opts.Timeout, _ = cmd.Flags().GetInt("timeout")
+			opts.Verbose, _ = cmd.Flags().GetBool("verbose")
+			opts.NoCache, _ = cmd.Flags().GetBool("no-cache")
+			opts.CacheTTL, _ = cmd.Flags().GetDuration("cache-ttl")
+			if err := app.depsCommand(opts); err != nil {
+				log.Printf("Dependency analysis failed: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	depsCmd.Flags().String("dir", ".", "Directory containing GitHub workflows")
+	depsCmd.Flags().String("format", "text", "Output format: text or json")
+	depsCmd.Flags().Int("timeout", 30, "API timeout in seconds")
+	depsCmd.Flags().Bool("verbose", false, "Verbose output")
+	depsCmd.Flags().Bool("no-cache", false, "Bypass the on-disk cache of resolved references and action metadata")
+	depsCmd.Flags().Duration("cache-ttl", ghclient.DefaultCacheTTL, "How long resolved references are kept in the on-disk cache")
+	cmd.AddCommand(depsCmd)
+
 	updateCmd := &cobra.Command{
 		Use:   "update",
-		Short: "Update GitHub Actions workflows to use pinned digests",
+		Short: "Update GitHub Actions workflows and composite actions to use pinned digests",
 		Run: func(cmd *cobra.Command, args []string) {
-			dir, _ := cmd.Flags().GetString("dir")
-			timeout, _ := cmd.Flags().GetInt("timeout")
-			verbose, _ := cmd.Flags().GetBool("verbose")
-			if err := app.updateCommand(dir, timeout, verbose); err != nil {
+			var opts updateOptions
+			opts.Dir, _ = cmd.Flags().GetString("dir")
+			opts.Timeout, _ = cmd.Flags().GetInt("timeout")
+			opts.Verbose, _ = cmd.Flags().GetBool("verbose")
+			opts.DryRun, _ = cmd.Flags().GetBool("dry-run")
+			opts.DiffFormat, _ = cmd.Flags().GetString("diff-format")
+			opts.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+			opts.NoCache, _ = cmd.Flags().GetBool("no-cache")
+			opts.CacheTTL, _ = cmd.Flags().GetDuration("cache-ttl")
+			opts.Lockfile, _ = cmd.Flags().GetString("lockfile")
+			opts.Frozen, _ = cmd.Flags().GetBool("frozen")
+			images, _ := cmd.Flags().GetBool("images")
+			opts.NoImages = !images
+			if err := app.updateCommand(opts); err != nil {
 				log.Printf("Update failed: %v", err)
 				os.Exit(1)
 			}
@@ -246,8 +1623,157 @@ func newRootCommand(app *App) *cobra.Command {
 	updateCmd.Flags().String("dir", ".", "Directory containing GitHub workflows")
 	updateCmd.Flags().Int("timeout", 30, "API timeout in seconds")
 	updateCmd.Flags().Bool("verbose", false, "Verbose output")
+	updateCmd.Flags().Bool("dry-run", false, "Resolve references and print the changes without writing files")
+	updateCmd.Flags().String("diff-format", "unified", "Dry run output format: unified or json")
+	updateCmd.Flags().Int("concurrency", 8, "Maximum number of references resolved in parallel")
+	updateCmd.Flags().Bool("no-cache", false, "Bypass the on-disk cache of resolved references")
+	updateCmd.Flags().Duration("cache-ttl", ghclient.DefaultCacheTTL, "How long resolved references are kept in the on-disk cache")
+	updateCmd.Flags().String("lockfile", "", "Lockfile recording resolved references, relative to --dir, e.g. "+lockfile.DefaultPath+"; empty disables it")
+	updateCmd.Flags().Bool("frozen", false, "Resolve references only from the lockfile and fail if an entry is missing")
+	updateCmd.Flags().Bool("images", true, "Pin container, service and docker:// images to digests")
 	cmd.AddCommand(updateCmd)
 
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade pinned actions to newer releases, updating both SHA and version comment",
+		Run: func(cmd *cobra.Command, args []string) {
+			var opts upgradeOptions
+			opts.Dir, _ = cmd.Flags().GetString("dir")
+			opts.Timeout, _ = cmd.Flags().GetInt("timeout")
+			opts.Verbose, _ = cmd.Flags().GetBool("verbose")
+			opts.DryRun, _ = cmd.Flags().GetBool("dry-run")
+			opts.DiffFormat, _ = cmd.Flags().GetString("diff-format")
+			opts.Level, _ = cmd.Flags().GetString("level")
+			opts.Range, _ = cmd.Flags().GetString("range")
+			if err := app.upgradeCommand(opts); err != nil {
+				log.Printf("Upgrade failed: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	upgradeCmd.Flags().String("dir", ".", "Directory containing GitHub workflows")
+	upgradeCmd.Flags().Int("timeout", 30, "API timeout in seconds")
+	upgradeCmd.Flags().Bool("verbose", false, "Verbose output")
+	upgradeCmd.Flags().Bool("dry-run", false, "Report the available upgrades without writing files")
+	upgradeCmd.Flags().String("diff-format", "unified", "Dry run output format: unified or json")
+	upgradeCmd.Flags().String("level", semver.LevelMinor, "Largest allowed upgrade: patch, minor or major")
+	upgradeCmd.Flags().String("range", "", "Version range to upgrade within instead of --level, e.g. \">=4.2 <5\" or ^4.2")
+	upgradeCmd.MarkFlagsMutuallyExclusive("level", "range")
+	cmd.AddCommand(upgradeCmd)
+
+	policyCmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Evaluate the references against the rules of a policy file",
+	}
+	policyCheckCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Report references violating the policy and fail on violations with error severity",
+		Run: func(cmd *cobra.Command, args []string) {
+			var opts policyOptions
+			opts.Dir, _ = cmd.Flags().GetString("dir")
+			opts.Policy, _ = cmd.Flags().GetString("policy")
+			opts.Format, _ = cmd.Flags().GetString("format")
+			opts.Verbose, _ = cmd.Flags().GetBool("verbose")
+			if err := app.policyCheckCommand(opts); err != nil {
+				log.Printf("Policy check failed: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+	policyCheckCmd.Flags().String("dir", ".", "Directory containing GitHub workflows")
+	policyCheckCmd.Flags().String("policy", policy.DefaultPath, "Policy file, relative to --dir")
+	policyCheckCmd.Flags().String("format", "text", "Output format: text or json")
+	policyCheckCmd.Flags().Bool("verbose", false, "Verbose output")
+	policyCmd.AddCommand(policyCheckCmd)
+	cmd.AddCommand(policyCmd)
+
+	advisoriesCmd := &cobra.Command{
+		Use:   "advisories",
+		Short: "Manage the offline database of advisories for GitHub Actions",
+	}
+	advisoriesImportCmd := &cobra.Command{
+		Use:   "import <file|archive|directory>...",
+		Short: "Import OSV advisories, e.g. a downloaded all.zip dump of the GitHub Actions ecosystem",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			opts := advisoriesImportOptions{Sources: args}
+			opts.Output, _ = cmd.Flags().GetString("output")
+			if err := app.advisoriesImportCommand(opts); err != nil {
+				log.Printf("Advisories import failed: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+	advisoriesImportCmd.Flags().String("output", app.AdvisoryDB, "Advisory database to merge the advisories into")
+	advisoriesCmd.AddCommand(advisoriesImportCmd)
+	cmd.AddCommand(advisoriesCmd)
+
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the on-disk cache of resolved references",
+	}
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "clean",
+		Short: "Remove all cached references",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := app.cacheCleanCommand(); err != nil {
+				log.Printf("Cache clean failed: %v", err)
+				os.Exit(1)
+			}
+		},
+	})
+	cmd.AddCommand(cacheCmd)
+
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration file and effective settings",
+		// The config commands load the settings themselves and need no GitHub client.
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	}
+	configFlags := func(cmd *cobra.Command) configOptions {
+		var opts configOptions
+		opts.Dir, _ = cmd.Flags().GetString("dir")
+		opts.Path, _ = cmd.Flags().GetString("config")
+		if cmd.Flags().Changed("resolver") {
+			opts.Flags.Resolver, _ = cmd.Flags().GetString("resolver")
+		}
+		if cmd.Flags().Changed("include") {
+			opts.Flags.Include, _ = cmd.Flags().GetStringSlice("include")
+		}
+		if cmd.Flags().Changed("exclude") {
+			opts.Flags.Exclude, _ = cmd.Flags().GetStringSlice("exclude")
+		}
+		return opts
+	}
+
+	configValidateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration file and DIGEST_PINNER_* environment variables",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := app.configValidateCommand(configFlags(cmd)); err != nil {
+				log.Printf("Config validation failed: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+	configValidateCmd.Flags().String("dir", ".", "Directory containing GitHub workflows")
+	configCmd.AddCommand(configValidateCmd)
+
+	configPrintCmd := &cobra.Command{
+		Use:   "print",
+		Short: "Print the effective settings merged from defaults, file, environment and flags",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := app.configPrintCommand(configFlags(cmd)); err != nil {
+				log.Printf("Config print failed: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+	configPrintCmd.Flags().String("dir", ".", "Directory containing GitHub workflows")
+	configCmd.AddCommand(configPrintCmd)
+	cmd.AddCommand(configCmd)
+
 	return cmd
 }
 
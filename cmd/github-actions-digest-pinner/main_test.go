@@ -3,16 +3,35 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/zisuu/github-actions-digest-pinner/internal/config"
+	"github.com/zisuu/github-actions-digest-pinner/internal/finder"
+	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/internal/lockfile"
+	"github.com/zisuu/github-actions-digest-pinner/internal/parser"
+	"github.com/zisuu/github-actions-digest-pinner/internal/report"
+	"github.com/zisuu/github-actions-digest-pinner/internal/updater"
 	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
 )
 
@@ -34,6 +53,11 @@ func (m *MockFinder) FindWorkflowFiles(fsys fs.FS) ([]string, error) {
 	return args.Get(0).([]string), args.Error(1)
 }
 
+func (m *MockFinder) FindActionFiles(fsys fs.FS) ([]string, error) {
+	args := m.Called(fsys)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 type MockParser struct {
 	mock.Mock
 }
@@ -43,6 +67,11 @@ func (m *MockParser) ParseWorkflowActions(content []byte) ([]types.ActionRef, er
 	return args.Get(0).([]types.ActionRef), args.Error(1)
 }
 
+func (m *MockParser) ParseCompositeActions(content []byte) ([]types.ActionRef, error) {
+	args := m.Called(content)
+	return args.Get(0).([]types.ActionRef), args.Error(1)
+}
+
 type MockUpdater struct {
 	mock.Mock
 }
@@ -111,17 +140,20 @@ func TestVersionCommand(t *testing.T) {
 
 func TestScanCommand(t *testing.T) {
 	tests := []struct {
-		name          string
-		verbose       bool
-		mockFiles     []string
-		mockActions   []types.ActionRef
-		mockError     error
-		expectError   bool
-		expectOutput  string
-		expectVerbose string
+		name            string
+		verbose         bool
+		format          string
+		mockFiles       []string
+		mockActionFiles []string
+		mockActions     []types.ActionRef
+		mockError       error
+		expectError     bool
+		expectOutput    string
+		expectVerbose   string
 	}{
 		{
 			name:         "successful scan",
+			format:       "text",
 			verbose:      false,
 			mockFiles:    []string{"test.yml"},
 			mockActions:  []types.ActionRef{{Owner: "owner", Repo: "repo", Ref: "v1"}},
@@ -129,13 +161,58 @@ func TestScanCommand(t *testing.T) {
 		},
 		{
 			name:          "verbose scan",
+			format:        "text",
 			verbose:       true,
 			mockFiles:     []string{"test.yml"},
 			mockActions:   []types.ActionRef{{Owner: "owner", Repo: "repo", Ref: "v1"}},
 			expectVerbose: "- Action: owner/repo@v1\n",
 		},
+		{
+			name:            "scan composite action",
+			format:          "text",
+			mockActionFiles: []string{".github/actions/setup/action.yml"},
+			mockActions:     []types.ActionRef{{Owner: "owner", Repo: "repo", Ref: "v1"}},
+			expectOutput:    ".github/actions/setup/action.yml: 1 actions found\n",
+		},
+		{
+			name:          "verbose scan of reusable workflow",
+			format:        "text",
+			verbose:       true,
+			mockFiles:     []string{"test.yml"},
+			mockActions:   []types.ActionRef{{Owner: "owner", Repo: "repo", Path: ".github/workflows/build.yml", Ref: "v1", Kind: types.KindReusableWorkflow}},
+			expectVerbose: "- Workflow: owner/repo/.github/workflows/build.yml@v1\n",
+		},
+		{
+			name:      "csv scan",
+			format:    "csv",
+			mockFiles: []string{"test.yml"},
+			mockActions: []types.ActionRef{
+				{Owner: "owner", Repo: "repo", Ref: "v1", Kind: types.KindAction, Job: "build", Step: 2, Line: 9, Column: 15},
+			},
+			expectOutput: "file,kind,job,step,step_name,line,column,owner,repo,path,ref,pinned,image,digest,suppressed,suppression_reason,advisories\n" +
+				"test.yml,action,build,2,,9,15,owner,repo,,v1,false,,,false,,\n",
+		},
+		{
+			name:      "scan with ignored references",
+			format:    "text",
+			mockFiles: []string{"test.yml"},
+			mockActions: []types.ActionRef{
+				{Owner: "owner", Repo: "repo", Ref: "v1"},
+				{Owner: "myorg", Repo: "internal", Ref: "main", Ignored: true, IgnoreReason: "under development"},
+			},
+			expectOutput: "test.yml: 2 actions found, 1 ignored\n",
+		},
+		{
+			name:          "verbose scan of ignored reference",
+			format:        "text",
+			verbose:       true,
+			mockFiles:     []string{"test.yml"},
+			mockActions:   []types.ActionRef{{Owner: "myorg", Repo: "internal", Ref: "main", Ignored: true, IgnoreReason: "under development"}},
+			expectVerbose: "- Action: myorg/internal@main (ignored: under development)\n",
+		},
 		{
 			name:        "file read error",
+			format:      "text",
 			mockFiles:   []string{"error.yml"},
 			mockError:   errors.New("read error"),
 			expectError: true,
@@ -151,7 +228,7 @@ func TestScanCommand(t *testing.T) {
 			mockFS := &MockFS{files: make(map[string]*MockFile)}
 
 			// Create a mock filesystem with the test files
-			for _, file := range tt.mockFiles {
+			for _, file := range append(tt.mockFiles, tt.mockActionFiles...) {
 				mockFS.files[file] = &MockFile{content: []byte("dummy content")}
 			}
 
@@ -176,14 +253,18 @@ func TestScanCommand(t *testing.T) {
 			}
 
 			mockFinder.On("FindWorkflowFiles", mock.Anything).Return(tt.mockFiles, nil).Once()
+			mockFinder.On("FindActionFiles", mock.Anything).Return(tt.mockActionFiles, nil).Once()
 
 			if tt.mockError == nil {
 				for range tt.mockFiles {
 					mockParser.On("ParseWorkflowActions", []byte("dummy content")).Return(tt.mockActions, nil).Once()
 				}
+				for range tt.mockActionFiles {
+					mockParser.On("ParseCompositeActions", []byte("dummy content")).Return(tt.mockActions, nil).Once()
+				}
 			}
 
-			err := app.scanCommand(".", tt.verbose)
+			err := app.scanCommand(scanOptions{Dir: ".", Format: tt.format, Verbose: tt.verbose})
 			if tt.expectError {
 				assert.Error(t, err)
 			} else {
@@ -204,6 +285,474 @@ func TestScanCommand(t *testing.T) {
 	}
 }
 
+func TestUnsupportedOutputFormat(t *testing.T) {
+	var outBuf, errBuf bytes.Buffer
+	app := &App{Out: &outBuf, Err: &errBuf}
+
+	assert.ErrorContains(t, app.scanCommand(scanOptions{Dir: ".", Format: "xml"}), "unsupported output format")
+	assert.ErrorContains(t, app.checkCommand(checkOptions{Dir: ".", Format: "xml"}), "unsupported output format")
+	assert.Empty(t, outBuf.String())
+}
+
+func TestCheckCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		allow        []string
+		format       string
+		mockActions  []types.ActionRef
+		expectError  bool
+		expectOutput string
+	}{
+		{
+			name:   "all pinned",
+			format: "text",
+			mockActions: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675", Line: 7, Column: 15},
+			},
+			expectOutput: "All 1 action references in 1 files are pinned\n",
+		},
+		{
+			name:   "unpinned references",
+			format: "text",
+			mockActions: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Line: 7, Column: 15},
+				{Owner: "myorg", Repo: "shared", Path: ".github/workflows/build.yml", Ref: "main", Line: 3, Column: 11},
+			},
+			expectError: true,
+			expectOutput: "test.yml:7:15: actions/checkout@v4 is not pinned to a commit SHA\n" +
+				"test.yml:3:11: myorg/shared/.github/workflows/build.yml@main is not pinned to a commit SHA\n",
+		},
+		{
+			name:   "unpinned images",
+			format: "text",
+			mockActions: []types.ActionRef{
+				{Image: "alpine", Ref: "3.19", Kind: types.KindDockerImage, Line: 9, Column: 15},
+				{Image: "node", Ref: "20", Kind: types.KindContainerImage, Line: 4, Column: 16},
+				{Image: "redis", Ref: "7", Digest: "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1", Kind: types.KindContainerImage, Line: 7, Column: 16},
+			},
+			expectError: true,
+			expectOutput: "test.yml:9:15: docker://alpine:3.19 is not pinned to a digest\n" +
+				"test.yml:4:16: node:20 is not pinned to a digest\n",
+		},
+		{
+			name:   "json findings",
+			format: "json",
+			mockActions: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 1, Line: 7, Column: 15},
+				{Owner: "actions", Repo: "setup-go", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675", Kind: types.KindAction, Job: "test", Step: 2, Line: 8, Column: 15},
+			},
+			expectError: true,
+			expectOutput: `[
+  {
+    "file": "test.yml",
+    "kind": "action",
+    "job": "test",
+    "step": 1,
+    "line": 7,
+    "column": 15,
+    "owner": "actions",
+    "repo": "checkout",
+    "ref": "v4",
+    "pinned": false
+  }
+]
+`,
+		},
+		{
+			name:   "trusted owners are allowed",
+			format: "text",
+			allow:  []string{"actions", "myorg/*"},
+			mockActions: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Line: 7, Column: 15},
+				{Owner: "myorg", Repo: "shared", Path: ".github/workflows/build.yml", Ref: "main", Line: 3, Column: 11},
+			},
+			expectOutput: "All 2 action references in 1 files are pinned\n",
+		},
+		{
+			name:   "ignored references do not fail",
+			format: "text",
+			mockActions: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675", Line: 7, Column: 15},
+				{Owner: "myorg", Repo: "internal", Ref: "main", Line: 9, Column: 15, Ignored: true, IgnoreReason: "under development"},
+			},
+			expectOutput: "All 2 action references in 1 files are pinned, except 1 ignored\n",
+		},
+		{
+			name:   "json marks ignored references as suppressed",
+			format: "json",
+			mockActions: []types.ActionRef{
+				{Owner: "myorg", Repo: "internal", Ref: "main", Kind: types.KindAction, Job: "test", Step: 1, Line: 9, Column: 15, Ignored: true, IgnoreReason: "under development"},
+			},
+			expectOutput: `[
+  {
+    "file": "test.yml",
+    "kind": "action",
+    "job": "test",
+    "step": 1,
+    "line": 9,
+    "column": 15,
+    "owner": "myorg",
+    "repo": "internal",
+    "ref": "main",
+    "pinned": false,
+    "suppressed": true,
+    "suppression_reason": "under development"
+  }
+]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outBuf, errBuf bytes.Buffer
+
+			mockFinder := new(MockFinder)
+			mockParser := new(MockParser)
+			mockFS := &MockFS{files: map[string]*MockFile{"test.yml": {content: []byte("dummy content")}}}
+
+			app := &App{
+				Out:    &outBuf,
+				Err:    &errBuf,
+				Finder: mockFinder,
+				Parser: mockParser,
+				FS: func(dir string) fs.FS {
+					return mockFS
+				},
+				ReadFile: fs.ReadFile,
+			}
+
+			mockFinder.On("FindWorkflowFiles", mock.Anything).Return([]string{"test.yml"}, nil).Once()
+			mockFinder.On("FindActionFiles", mock.Anything).Return([]string{}, nil).Once()
+			mockParser.On("ParseWorkflowActions", []byte("dummy content")).Return(tt.mockActions, nil).Once()
+
+			err := app.checkCommand(checkOptions{Dir: ".", Allow: tt.allow, Format: tt.format})
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectOutput, outBuf.String())
+
+			mockFinder.AssertExpectations(t)
+			mockParser.AssertExpectations(t)
+		})
+	}
+}
+
+// taggedClient lists a fixed set of tags
+type taggedClient struct {
+	MockGitHubClient
+	tags []ghclient.Tag
+}
+
+func (c *taggedClient) ListTags(ctx context.Context, owner, repo string) ([]ghclient.Tag, error) {
+	return c.tags, nil
+}
+
+func TestAuditCommand(t *testing.T) {
+	const workflow = "jobs:\n  test:\n    steps:\n" +
+		"      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4.1.0\n" +
+		"      - uses: actions/setup-go@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v5.0.0\n" +
+		"      - uses: actions/cache@v4\n"
+
+	tests := []struct {
+		name         string
+		format       string
+		verbose      bool
+		checkoutSHA  string
+		checkoutErr  error
+		expectError  string
+		expectOutput string
+	}{
+		{
+			name:        "newer release",
+			format:      "text",
+			checkoutSHA: "a81bbbf8298c0fa03ea29cdc473d45769f953675",
+			expectOutput: ".github/workflows/ci.yml:4:15: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4.1.0: newer release available: v5.0.0\n" +
+				"Audited 2 pinned action references: 0 moved tags, 1 newer releases, 0 impostor commits\n",
+		},
+		{
+			name:        "verbose lists unchanged references",
+			format:      "text",
+			verbose:     true,
+			checkoutSHA: "a81bbbf8298c0fa03ea29cdc473d45769f953675",
+			expectOutput: ".github/workflows/ci.yml:4:15: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4.1.0: newer release available: v5.0.0\n" +
+				".github/workflows/ci.yml:5:15: actions/setup-go@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v5.0.0: v5.0.0 still points to the pinned commit\n" +
+				"Audited 2 pinned action references: 0 moved tags, 1 newer releases, 0 impostor commits\n",
+		},
+		{
+			name:        "moved tag",
+			format:      "text",
+			checkoutSHA: "c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8",
+			expectError: "found 1 moved tags",
+			expectOutput: ".github/workflows/ci.yml:4:15: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4.1.0: tag moved: v4.1.0 now points to c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8\n" +
+				"Audited 2 pinned action references: 1 moved tags, 0 newer releases, 0 impostor commits\n",
+		},
+		{
+			name:        "deleted tag",
+			format:      "text",
+			checkoutErr: errors.New("failed to resolve ref v4.1.0: 404 Not Found"),
+			expectError: "found 1 unresolved tags",
+			expectOutput: ".github/workflows/ci.yml:4:15: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4.1.0: tag unresolved: failed to resolve ref v4.1.0: 404 Not Found\n" +
+				"Audited 2 pinned action references: 0 moved tags, 0 newer releases, 0 impostor commits, 1 unresolved tags\n",
+		},
+		{
+			name:        "json",
+			format:      "json",
+			checkoutSHA: "c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8",
+			expectError: "found 1 moved tags",
+			expectOutput: `"status": "tag-moved",
+    "current_sha": "c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8",
+    "latest": "v5.0.0"`,
+		},
+		{
+			name:        "unsupported format",
+			format:      "sarif",
+			expectError: "unsupported output format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outBuf bytes.Buffer
+
+			client := &taggedClient{tags: []ghclient.Tag{
+				{Name: "v4.1.0", SHA: tt.checkoutSHA},
+				{Name: "v5.0.0", SHA: "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c"},
+			}}
+			client.On("ResolveActionSHA", mock.Anything, mock.MatchedBy(func(a types.ActionRef) bool { return a.Repo == "checkout" })).
+				Return(tt.checkoutSHA, tt.checkoutErr).Maybe()
+			client.On("ResolveActionSHA", mock.Anything, mock.MatchedBy(func(a types.ActionRef) bool { return a.Repo == "setup-go" })).
+				Return("a81bbbf8298c0fa03ea29cdc473d45769f953675", nil).Maybe()
+
+			memFS := fstest.MapFS{
+				".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(workflow)},
+			}
+			app := &App{
+				Out:    &outBuf,
+				Err:    io.Discard,
+				Client: client,
+				Finder: finder.DefaultFinder{},
+				Parser: parser.DefaultParser{},
+				FS: func(dir string) fs.FS {
+					return memFS
+				},
+				ReadFile: fs.ReadFile,
+			}
+
+			err := app.auditCommand(auditOptions{Dir: ".", Format: tt.format, Timeout: 30, Verbose: tt.verbose})
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Contains(t, outBuf.String(), tt.expectOutput)
+		})
+	}
+}
+
+// fetchingClient serves files from a map keyed by "owner/repo/path@sha"
+type fetchingClient struct {
+	MockGitHubClient
+	files map[string]string
+}
+
+func (c *fetchingClient) FetchFile(ctx context.Context, owner, repo, path, sha string) ([]byte, error) {
+	content, ok := c.files[owner+"/"+repo+"/"+path+"@"+sha]
+	if !ok {
+		return nil, ghclient.ErrFileNotFound
+	}
+	return []byte(content), nil
+}
+
+func TestDepsCommand(t *testing.T) {
+	const (
+		setupSHA = "a81bbbf8298c0fa03ea29cdc473d45769f953675"
+		toolSHA  = "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c"
+	)
+	const workflow = "jobs:\n  test:\n    container: node:20\n    steps:\n" +
+		"      - uses: myorg/setup@" + setupSHA + " # v1.0.0\n" +
+		"      - uses: myorg/internal@main # digest-pinner: ignore\n"
+
+	tests := []struct {
+		name         string
+		format       string
+		setup        string
+		expectError  string
+		expectOutput string
+	}{
+		{
+			name:   "transitive unpinned references",
+			format: "text",
+			setup:  "runs:\n  using: composite\n  steps:\n    - uses: other/tool@main\n",
+			expectOutput: ".github/workflows/ci.yml:5:15: myorg/setup@" + setupSHA + "\n" +
+				"  other/tool@main [not pinned]\n" +
+				"    docker://alpine:3.20 [not pinned]\n" +
+				"Analyzed the dependencies of 1 references: 2 unpinned transitive references\n",
+			expectError: "found 2 unpinned transitive references",
+		},
+		{
+			name:   "pinned dependencies",
+			format: "text",
+			setup:  "runs:\n  using: composite\n  steps:\n    - uses: other/tool@" + toolSHA + "\n",
+			expectOutput: ".github/workflows/ci.yml:5:15: myorg/setup@" + setupSHA + "\n" +
+				"  other/tool@" + toolSHA + "\n" +
+				"    docker://alpine:3.20 [not pinned]\n" +
+				"Analyzed the dependencies of 1 references: 1 unpinned transitive references\n",
+			expectError: "found 1 unpinned transitive references",
+		},
+		{
+			name:   "javascript action",
+			format: "json",
+			setup:  "runs:\n  using: node20\n  main: index.js\n",
+			expectOutput: `[
+  {
+    "file": ".github/workflows/ci.yml",
+    "uses": "myorg/setup@` + setupSHA + `",
+    "line": 5,
+    "column": 15,
+    "pinned": true,
+    "sha": "` + setupSHA + `"
+  }
+]
+`,
+		},
+		{
+			name:        "unsupported format",
+			format:      "sarif",
+			expectError: "unsupported output format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outBuf bytes.Buffer
+
+			client := &fetchingClient{files: map[string]string{
+				"myorg/setup/action.yml@" + setupSHA: tt.setup,
+				"other/tool/action.yml@" + toolSHA:   "runs:\n  using: docker\n  image: docker://alpine:3.20\n",
+			}}
+			client.On("ResolveActionSHA", mock.Anything, mock.MatchedBy(func(a types.ActionRef) bool { return a.Repo == "setup" })).
+				Return(setupSHA, nil).Maybe()
+			client.On("ResolveActionSHA", mock.Anything, mock.MatchedBy(func(a types.ActionRef) bool { return a.Repo == "tool" })).
+				Return(toolSHA, nil).Maybe()
+
+			memFS := fstest.MapFS{
+				".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(workflow)},
+			}
+			app := &App{
+				Out:    &outBuf,
+				Err:    io.Discard,
+				Client: client,
+				Finder: finder.DefaultFinder{},
+				Parser: parser.DefaultParser{},
+				FS: func(dir string) fs.FS {
+					return memFS
+				},
+				ReadFile: fs.ReadFile,
+			}
+
+			err := app.depsCommand(depsOptions{Dir: ".", Format: tt.format, Timeout: 30})
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectOutput, outBuf.String())
+		})
+	}
+
+	// The git resolver cannot fetch files
+	gitClient := new(MockGitHubClient)
+	gitClient.On("ResolveActionSHA", mock.Anything, mock.Anything).Return(setupSHA, nil)
+	app := &App{
+		Out:    io.Discard,
+		Err:    io.Discard,
+		Client: ghclient.NewCachingClient(gitClient),
+		Finder: finder.DefaultFinder{},
+		Parser: parser.DefaultParser{},
+		FS: func(dir string) fs.FS {
+			return fstest.MapFS{".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(workflow)}}
+		},
+		ReadFile: fs.ReadFile,
+	}
+	assert.ErrorIs(t, app.depsCommand(depsOptions{Dir: ".", Format: "text", Timeout: 30}), ghclient.ErrNotSupported)
+}
+
+// verifyingClient reports a fixed set of commits as reachable and another as unverified
+type verifyingClient struct {
+	taggedClient
+	reachable  map[string]bool
+	unverified string
+}
+
+func (c *verifyingClient) IsReachable(ctx context.Context, owner, repo, sha, version string) (bool, error) {
+	if sha == c.unverified {
+		return false, ghclient.ErrUnverified
+	}
+	return c.reachable[sha], nil
+}
+
+func TestAuditCommandVerifyCommits(t *testing.T) {
+	const (
+		pinnedSHA   = "a81bbbf8298c0fa03ea29cdc473d45769f953675"
+		impostorSHA = "d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9"
+	)
+	const workflow = "jobs:\n  test:\n    steps:\n" +
+		"      - uses: actions/checkout@" + pinnedSHA + " # v4.1.0\n" +
+		"      - uses: actions/setup-go@" + impostorSHA + "\n"
+
+	newApp := func(out io.Writer, client ghclient.GitHubClient) *App {
+		memFS := fstest.MapFS{
+			".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(workflow)},
+		}
+		return &App{
+			Out:      out,
+			Err:      io.Discard,
+			Client:   client,
+			Finder:   finder.DefaultFinder{},
+			Parser:   parser.DefaultParser{},
+			FS:       func(dir string) fs.FS { return memFS },
+			ReadFile: fs.ReadFile,
+		}
+	}
+
+	t.Run("impostor commit", func(t *testing.T) {
+		var outBuf bytes.Buffer
+		client := &verifyingClient{
+			taggedClient: taggedClient{tags: []ghclient.Tag{{Name: "v4.1.0", SHA: pinnedSHA}}},
+			reachable:    map[string]bool{pinnedSHA: true},
+		}
+		client.On("ResolveActionSHA", mock.Anything, mock.Anything).Return(pinnedSHA, nil)
+
+		err := newApp(&outBuf, client).auditCommand(auditOptions{Dir: ".", Format: "text", Timeout: 30, VerifyCommits: true})
+		assert.ErrorContains(t, err, "found 1 impostor commits")
+		assert.Equal(t, ".github/workflows/ci.yml:5:15: actions/setup-go@"+impostorSHA+": impostor commit: not reachable from any branch or tag of actions/setup-go\n"+
+			"Audited 2 pinned action references: 0 moved tags, 0 newer releases, 1 impostor commits\n", outBuf.String())
+	})
+
+	t.Run("unverified commit", func(t *testing.T) {
+		var outBuf bytes.Buffer
+		client := &verifyingClient{
+			taggedClient: taggedClient{tags: []ghclient.Tag{{Name: "v4.1.0", SHA: pinnedSHA}}},
+			reachable:    map[string]bool{pinnedSHA: true},
+			unverified:   impostorSHA,
+		}
+		client.On("ResolveActionSHA", mock.Anything, mock.Anything).Return(pinnedSHA, nil)
+
+		err := newApp(&outBuf, client).auditCommand(auditOptions{Dir: ".", Format: "text", Timeout: 30, VerifyCommits: true})
+		assert.NoError(t, err)
+		assert.Equal(t, ".github/workflows/ci.yml:5:15: actions/setup-go@"+impostorSHA+": unverified commit: actions/setup-go has too many branches and tags to check them all\n"+
+			"Audited 2 pinned action references: 0 moved tags, 0 newer releases, 0 impostor commits, 1 unverified commits\n", outBuf.String())
+	})
+
+	t.Run("unsupported resolver", func(t *testing.T) {
+		err := newApp(io.Discard, &MockGitHubClient{}).auditCommand(auditOptions{Dir: ".", Format: "text", Timeout: 30, VerifyCommits: true})
+		assert.ErrorContains(t, err, "commit verification is not supported")
+	})
+}
+
 func TestUpdateCommand(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -271,9 +820,10 @@ func TestUpdateCommand(t *testing.T) {
 			}
 
 			mockFinder.On("FindWorkflowFiles", mock.Anything).Return(tt.mockFiles, nil).Once()
+			mockFinder.On("FindActionFiles", mock.Anything).Return([]string{}, nil).Once()
 			mockUpdater.On("UpdateWorkflows", mock.Anything, mock.Anything).Return(tt.mockUpdates, tt.mockError).Once()
 
-			err := app.updateCommand(".", tt.timeout, tt.verbose)
+			err := app.updateCommand(updateOptions{Dir: ".", Timeout: tt.timeout, Verbose: tt.verbose})
 			if tt.expectError {
 				assert.Error(t, err)
 			} else {
@@ -292,6 +842,865 @@ func TestUpdateCommand(t *testing.T) {
 	}
 }
 
+func TestUpdateCommandDryRun(t *testing.T) {
+	const original = "jobs:\n  test:\n    steps:\n      - uses: actions/checkout@v4\n"
+
+	tests := []struct {
+		name         string
+		diffFormat   string
+		expectError  bool
+		expectOutput string
+	}{
+		{
+			name:       "unified diff",
+			diffFormat: "unified",
+			expectOutput: "--- a/.github/workflows/ci.yml\n" +
+				"+++ b/.github/workflows/ci.yml\n" +
+				"@@ -1,4 +1,4 @@\n" +
+				" jobs:\n" +
+				"   test:\n" +
+				"     steps:\n" +
+				"-      - uses: actions/checkout@v4\n" +
+				"+      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4\n" +
+				"Would update 1 action references in",
+		},
+		{
+			name:       "json",
+			diffFormat: "json",
+			expectOutput: `[
+  {
+    "file": ".github/workflows/ci.yml",
+    "line": 4,
+    "column": 15,
+    "old": "actions/checkout@v4",
+    "new": "actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675",
+    "version": "v4"
+  }
+]
+`,
+		},
+		{
+			name:        "unsupported format",
+			diffFormat:  "xml",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outBuf, errBuf bytes.Buffer
+
+			mockClient := new(MockGitHubClient)
+			mockClient.On("ResolveActionSHA", mock.Anything, mock.Anything).
+				Return("a81bbbf8298c0fa03ea29cdc473d45769f953675", nil).Maybe()
+
+			memFS := fstest.MapFS{
+				".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(original)},
+			}
+
+			app := &App{
+				Out:     &outBuf,
+				Err:     &errBuf,
+				Finder:  finder.DefaultFinder{},
+				Parser:  parser.DefaultParser{},
+				Updater: updater.NewUpdater(mockClient),
+				FS: func(dir string) fs.FS {
+					return memFS
+				},
+				ReadFile: fs.ReadFile,
+			}
+
+			err := app.updateCommand(updateOptions{Dir: ".", Timeout: 30, DryRun: true, DiffFormat: tt.diffFormat})
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Contains(t, outBuf.String(), tt.expectOutput)
+			assert.Equal(t, original, string(memFS[".github/workflows/ci.yml"].Data))
+		})
+	}
+}
+
+func TestUpdateCommandCache(t *testing.T) {
+	const original = "jobs:\n  test:\n    steps:\n      - uses: actions/checkout@v4\n"
+
+	tests := []struct {
+		name      string
+		noCache   bool
+		wantCalls int
+	}{
+		{
+			name:      "second run uses the cache",
+			wantCalls: 1,
+		},
+		{
+			name:      "no cache resolves every run",
+			noCache:   true,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+
+			mockClient := new(MockGitHubClient)
+			mockClient.On("ResolveActionSHA", mock.Anything, mock.Anything).
+				Return("a81bbbf8298c0fa03ea29cdc473d45769f953675", nil)
+
+			for range 2 {
+				memFS := fstest.MapFS{
+					".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(original)},
+				}
+				app := &App{
+					Out:     io.Discard,
+					Err:     io.Discard,
+					Finder:  finder.DefaultFinder{},
+					Parser:  parser.DefaultParser{},
+					Updater: updater.NewUpdater(mockClient),
+					FS: func(dir string) fs.FS {
+						return memFS
+					},
+					ReadFile: fs.ReadFile,
+					CacheDir: cacheDir,
+				}
+
+				opts := updateOptions{Dir: ".", Timeout: 30, DryRun: true, DiffFormat: "unified", NoCache: tt.noCache, CacheTTL: time.Hour}
+				assert.NoError(t, app.updateCommand(opts))
+			}
+
+			mockClient.AssertNumberOfCalls(t, "ResolveActionSHA", tt.wantCalls)
+		})
+	}
+}
+
+// countingImageResolver resolves every image to the same digest and counts the lookups
+type countingImageResolver struct {
+	lookups int
+}
+
+func (r *countingImageResolver) ResolveDigest(ctx context.Context, image, tag string) (string, error) {
+	r.lookups++
+	return "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1", nil
+}
+
+func TestUpdateCommandNoImages(t *testing.T) {
+	const original = "jobs:\n  test:\n    container: node:20\n    steps:\n      - uses: actions/checkout@v4\n"
+
+	for _, noImages := range []bool{false, true} {
+		t.Run(fmt.Sprintf("no images %v", noImages), func(t *testing.T) {
+			mockClient := new(MockGitHubClient)
+			mockClient.On("ResolveActionSHA", mock.Anything, mock.Anything).
+				Return("a81bbbf8298c0fa03ea29cdc473d45769f953675", nil)
+			images := &countingImageResolver{}
+			upd := updater.NewUpdater(mockClient)
+			upd.SetImageResolver(images)
+
+			var outBuf bytes.Buffer
+			memFS := fstest.MapFS{
+				".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(original)},
+			}
+			app := &App{
+				Out:     &outBuf,
+				Err:     io.Discard,
+				Finder:  finder.DefaultFinder{},
+				Parser:  parser.DefaultParser{},
+				Updater: upd,
+				FS: func(dir string) fs.FS {
+					return memFS
+				},
+				ReadFile: fs.ReadFile,
+			}
+
+			opts := updateOptions{Dir: ".", Timeout: 30, DryRun: true, DiffFormat: "unified", NoImages: noImages}
+			assert.NoError(t, app.updateCommand(opts))
+			assert.Contains(t, outBuf.String(), "+      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4")
+			if noImages {
+				assert.Zero(t, images.lookups)
+				assert.NotContains(t, outBuf.String(), "+    container:")
+			} else {
+				assert.Equal(t, 1, images.lookups)
+				assert.Contains(t, outBuf.String(), "+    container: node:20@sha256:")
+			}
+		})
+	}
+}
+
+func TestUpdateCommandLockfile(t *testing.T) {
+	const original = "jobs:\n  test:\n    steps:\n      - uses: actions/checkout@v4\n"
+	const pinned = "jobs:\n  test:\n    steps:\n      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4\n"
+
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, ".github", "workflows", "ci.yml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(workflow), 0o755))
+
+	newApp := func(client ghclient.GitHubClient) *App {
+		return &App{
+			Out:      io.Discard,
+			Err:      io.Discard,
+			Finder:   finder.DefaultFinder{},
+			Parser:   parser.DefaultParser{},
+			Updater:  updater.NewUpdater(client),
+			FS:       os.DirFS,
+			ReadFile: fs.ReadFile,
+		}
+	}
+	opts := updateOptions{Dir: dir, Timeout: 30, Lockfile: lockfile.DefaultPath}
+
+	// A regular update resolves the reference and records it
+	mockClient := new(MockGitHubClient)
+	mockClient.On("ResolveActionSHA", mock.Anything, mock.Anything).
+		Return("a81bbbf8298c0fa03ea29cdc473d45769f953675", nil).Once()
+	assert.NoError(t, os.WriteFile(workflow, []byte(original), 0o644))
+	assert.NoError(t, newApp(mockClient).updateCommand(opts))
+	mockClient.AssertExpectations(t)
+
+	lock, err := lockfile.Load(filepath.Join(dir, lockfile.DefaultPath))
+	assert.NoError(t, err)
+	entry, ok := lock.Lookup("actions/checkout@v4")
+	assert.True(t, ok)
+	assert.Equal(t, "a81bbbf8298c0fa03ea29cdc473d45769f953675", entry.SHA)
+
+	// A frozen update resolves from the lockfile without the client
+	opts.Frozen = true
+	assert.NoError(t, os.WriteFile(workflow, []byte(original), 0o644))
+	assert.NoError(t, newApp(new(MockGitHubClient)).updateCommand(opts))
+	content, err := os.ReadFile(workflow)
+	assert.NoError(t, err)
+	assert.Equal(t, pinned, string(content))
+
+	// A frozen update fails for references missing from the lockfile
+	assert.NoError(t, os.WriteFile(workflow, []byte(strings.Replace(original, "@v4", "@v5", 1)), 0o644))
+	assert.ErrorContains(t, newApp(new(MockGitHubClient)).updateCommand(opts), "references missing from the lockfile: actions/checkout@v5")
+
+	opts.Lockfile = ""
+	assert.ErrorContains(t, newApp(new(MockGitHubClient)).updateCommand(opts), "--frozen requires a lockfile")
+}
+
+func TestUpgradeCommand(t *testing.T) {
+	const original = "jobs:\n  test:\n    steps:\n      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4.1.0\n"
+
+	tests := []struct {
+		name         string
+		opts         upgradeOptions
+		expectError  string
+		expectOutput string
+	}{
+		{
+			name: "minor",
+			opts: upgradeOptions{Level: "minor", DryRun: true, DiffFormat: "unified"},
+			expectOutput: "+      - uses: actions/checkout@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c # v4.2.0\n" +
+				"Would update 1 action references in",
+		},
+		{
+			name:         "major as json",
+			opts:         upgradeOptions{Level: "major", DryRun: true, DiffFormat: "json"},
+			expectOutput: `"version": "v5.0.0",` + "\n" + `    "previous_version": "v4.1.0"`,
+		},
+		{
+			name:         "range",
+			opts:         upgradeOptions{Range: "<4.2", DryRun: true, DiffFormat: "json"},
+			expectOutput: "[]\n",
+		},
+		{
+			name:        "invalid level",
+			opts:        upgradeOptions{Level: "latest"},
+			expectError: "unsupported upgrade level",
+		},
+		{
+			name:        "invalid range",
+			opts:        upgradeOptions{Range: ">=main"},
+			expectError: "invalid version range",
+		},
+		{
+			name:        "unsupported diff format",
+			opts:        upgradeOptions{Level: "minor", DryRun: true, DiffFormat: "xml"},
+			expectError: "unsupported diff format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outBuf bytes.Buffer
+
+			client := &taggedClient{tags: []ghclient.Tag{
+				{Name: "v4.1.0", SHA: "a81bbbf8298c0fa03ea29cdc473d45769f953675"},
+				{Name: "v4.2.0", SHA: "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c"},
+				{Name: "v5.0.0", SHA: "c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8"},
+			}}
+			memFS := fstest.MapFS{
+				".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(original)},
+			}
+			app := &App{
+				Out:     &outBuf,
+				Err:     io.Discard,
+				Updater: updater.NewUpdater(client),
+				FS: func(dir string) fs.FS {
+					return memFS
+				},
+			}
+
+			tt.opts.Dir, tt.opts.Timeout = ".", 30
+			err := app.upgradeCommand(tt.opts)
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Contains(t, outBuf.String(), tt.expectOutput)
+			assert.Equal(t, original, string(memFS[".github/workflows/ci.yml"].Data))
+		})
+	}
+
+	app := &App{Out: io.Discard, Err: io.Discard, Updater: &MockUpdater{}}
+	assert.ErrorContains(t, app.upgradeCommand(upgradeOptions{Dir: ".", Level: "minor"}), "not supported")
+}
+
+func TestCacheCleanCommand(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	assert.NoError(t, os.MkdirAll(cacheDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(cacheDir, "entry.json"), []byte("{}"), 0o644))
+
+	var outBuf bytes.Buffer
+	app := &App{Out: &outBuf, Err: io.Discard, CacheDir: cacheDir}
+
+	assert.NoError(t, app.cacheCleanCommand())
+	assert.Equal(t, "Removed cache directory "+cacheDir+"\n", outBuf.String())
+	assert.NoDirExists(t, cacheDir)
+
+	app.CacheDir = ""
+	assert.Error(t, app.cacheCleanCommand())
+}
+
+func TestPolicyCheckCommand(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".github"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".github", "digest-pinner-policy.yml"), []byte(`rules:
+  - name: no-branches
+    match: "*"
+    refs: [branch]
+    effect: deny
+  - name: trusted-on-tags
+    match: actions
+    effect: allow
+  - name: must-pin
+    match: "*"
+    effect: must-pin
+    severity: warning
+`), 0o644))
+
+	tests := []struct {
+		name         string
+		format       string
+		mockActions  []types.ActionRef
+		expectError  bool
+		expectOutput string
+	}{
+		{
+			name:   "warnings only",
+			format: "text",
+			mockActions: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Line: 7, Column: 15},
+				{Owner: "myorg", Repo: "tool", Ref: "v1", Line: 8, Column: 15},
+			},
+			expectOutput: "test.yml:8:15: warning: myorg/tool@v1 is not pinned to a commit SHA [must-pin]\n" +
+				"Checked 2 action references against 3 rules: 0 errors, 1 warnings, 0 notes\n",
+		},
+		{
+			name:   "errors fail",
+			format: "text",
+			mockActions: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "main", Line: 7, Column: 15},
+				{Owner: "myorg", Repo: "internal", Ref: "main", Line: 9, Column: 15, Ignored: true, IgnoreReason: "under development"},
+			},
+			expectError: true,
+			expectOutput: "test.yml:7:15: error: actions/checkout@main is denied [no-branches]\n" +
+				"Checked 1 action references against 3 rules: 1 errors, 0 warnings, 0 notes\n",
+		},
+		{
+			name:   "json",
+			format: "json",
+			mockActions: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "main", Line: 7, Column: 15},
+			},
+			expectError: true,
+			expectOutput: `[
+  {
+    "file": "test.yml",
+    "line": 7,
+    "column": 15,
+    "uses": "actions/checkout@main",
+    "rule": "no-branches",
+    "severity": "error",
+    "message": "actions/checkout@main is denied"
+  }
+]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outBuf bytes.Buffer
+
+			mockFinder := new(MockFinder)
+			mockParser := new(MockParser)
+			mockFS := &MockFS{files: map[string]*MockFile{"test.yml": {content: []byte("dummy content")}}}
+
+			app := &App{
+				Out:    &outBuf,
+				Err:    io.Discard,
+				Finder: mockFinder,
+				Parser: mockParser,
+				FS: func(dir string) fs.FS {
+					return mockFS
+				},
+				ReadFile: fs.ReadFile,
+			}
+
+			mockFinder.On("FindWorkflowFiles", mock.Anything).Return([]string{"test.yml"}, nil).Once()
+			mockFinder.On("FindActionFiles", mock.Anything).Return([]string{}, nil).Once()
+			mockParser.On("ParseWorkflowActions", []byte("dummy content")).Return(tt.mockActions, nil).Once()
+
+			err := app.policyCheckCommand(policyOptions{Dir: dir, Policy: ".github/digest-pinner-policy.yml", Format: tt.format})
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectOutput, outBuf.String())
+		})
+	}
+
+	app := &App{Out: io.Discard, Err: io.Discard}
+	assert.ErrorIs(t, app.policyCheckCommand(policyOptions{Dir: dir, Policy: "missing.yml", Format: "text"}), fs.ErrNotExist)
+	assert.Error(t, app.policyCheckCommand(policyOptions{Dir: dir, Policy: ".github/digest-pinner-policy.yml", Format: "sarif"}))
+}
+
+// testAdvisory affects tj-actions/changed-files up to v45.0.7 and its compromised commit
+const testAdvisory = `{
+  "id": "GHSA-mrrh-fwg8-r2c3",
+  "modified": "2025-03-20T00:00:00Z",
+  "summary": "tj-actions changed-files through 45.0.7 allows remote attackers to discover secrets",
+  "affected": [{
+    "package": {"ecosystem": "GitHub Actions", "name": "tj-actions/changed-files"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "46.0.1"}]}],
+    "versions": ["0e58ed8671d6b60d0890c21b07f8835ace038e67"]
+  }],
+  "database_specific": {"severity": "HIGH"}
+}`
+
+// writeAdvisory writes testAdvisory to a file in dir and returns its path
+func writeAdvisory(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "GHSA-mrrh-fwg8-r2c3.json")
+	assert.NoError(t, os.WriteFile(path, []byte(testAdvisory), 0o644))
+	return path
+}
+
+func TestAdvisories(t *testing.T) {
+	path := writeAdvisory(t, t.TempDir())
+	actions := []types.ActionRef{
+		{Owner: "tj-actions", Repo: "changed-files", Ref: "v45", Line: 7, Column: 15},
+		{Owner: "tj-actions", Repo: "changed-files", Ref: "0e58ed8671d6b60d0890c21b07f8835ace038e67", Line: 8, Column: 15,
+			Ignored: true, IgnoreReason: "reviewed"},
+		{Owner: "actions", Repo: "checkout", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675", Comment: "v4", Line: 9, Column: 15},
+	}
+
+	newApp := func(out io.Writer) *App {
+		mockFinder := new(MockFinder)
+		mockParser := new(MockParser)
+		mockFS := &MockFS{files: map[string]*MockFile{"test.yml": {content: []byte("dummy content")}}}
+		mockFinder.On("FindWorkflowFiles", mock.Anything).Return([]string{"test.yml"}, nil).Once()
+		mockFinder.On("FindActionFiles", mock.Anything).Return([]string{}, nil).Once()
+		mockParser.On("ParseWorkflowActions", []byte("dummy content")).Return(actions, nil).Once()
+		return &App{
+			Out:      out,
+			Err:      io.Discard,
+			Finder:   mockFinder,
+			Parser:   mockParser,
+			FS:       func(dir string) fs.FS { return mockFS },
+			ReadFile: fs.ReadFile,
+		}
+	}
+
+	hits := "test.yml:7:15: tj-actions/changed-files@v45 is affected by GHSA-mrrh-fwg8-r2c3 (high): " +
+		"tj-actions changed-files through 45.0.7 allows remote attackers to discover secrets\n" +
+		"test.yml:8:15: tj-actions/changed-files@0e58ed8671d6b60d0890c21b07f8835ace038e67 is affected by GHSA-mrrh-fwg8-r2c3 (high): " +
+		"tj-actions changed-files through 45.0.7 allows remote attackers to discover secrets\n"
+
+	t.Run("scan", func(t *testing.T) {
+		var outBuf bytes.Buffer
+		assert.NoError(t, newApp(&outBuf).scanCommand(scanOptions{Dir: ".", Format: "text", Advisories: path}))
+		assert.Equal(t, "test.yml: 3 actions found, 1 ignored\n"+hits, outBuf.String())
+	})
+
+	t.Run("check", func(t *testing.T) {
+		var outBuf bytes.Buffer
+		err := newApp(&outBuf).checkCommand(checkOptions{Dir: ".", Format: "text", Advisories: path})
+		assert.EqualError(t, err, "found 1 unpinned action references and 2 references affected by advisories")
+		assert.Equal(t, "test.yml:7:15: tj-actions/changed-files@v45 is not pinned to a commit SHA\n"+hits, outBuf.String())
+	})
+
+	t.Run("check json", func(t *testing.T) {
+		var outBuf bytes.Buffer
+		err := newApp(&outBuf).checkCommand(checkOptions{Dir: ".", Format: "json", Advisories: path})
+		assert.Error(t, err)
+
+		var records []report.Record
+		assert.NoError(t, json.Unmarshal(outBuf.Bytes(), &records))
+		if assert.Len(t, records, 2) {
+			assert.Equal(t, []string{"GHSA-mrrh-fwg8-r2c3"}, records[0].Advisories)
+			assert.Equal(t, 8, records[1].Line)
+			assert.Equal(t, []string{"GHSA-mrrh-fwg8-r2c3"}, records[1].Advisories)
+		}
+	})
+
+	t.Run("check sarif with allowed reference", func(t *testing.T) {
+		var outBuf bytes.Buffer
+		err := newApp(&outBuf).checkCommand(checkOptions{Dir: ".", Format: "sarif", Allow: []string{"tj-actions/*"}, Advisories: path})
+		assert.EqualError(t, err, "found 2 references affected by advisories")
+
+		var log struct {
+			Runs []struct {
+				Results []struct {
+					RuleID string `json:"ruleId"`
+				} `json:"results"`
+			} `json:"runs"`
+		}
+		assert.NoError(t, json.Unmarshal(outBuf.Bytes(), &log))
+		if assert.Len(t, log.Runs, 1) && assert.Len(t, log.Runs[0].Results, 2) {
+			for _, result := range log.Runs[0].Results {
+				assert.Equal(t, "known-vulnerable-action", result.RuleID)
+			}
+		}
+	})
+
+	t.Run("missing database", func(t *testing.T) {
+		app := &App{Out: io.Discard, Err: io.Discard, AdvisoryDB: filepath.Join(t.TempDir(), "advisories.json")}
+		db, err := app.loadAdvisories(".", "", false)
+		assert.NoError(t, err)
+		assert.Nil(t, db)
+
+		_, err = app.loadAdvisories(".", filepath.Join(t.TempDir(), "missing.json"), false)
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+func TestAdvisoriesImportCommand(t *testing.T) {
+	source := t.TempDir()
+	writeAdvisory(t, source)
+	assert.NoError(t, os.WriteFile(filepath.Join(source, "GHSA-npm.json"),
+		[]byte(`{"id": "GHSA-npm", "affected": [{"package": {"ecosystem": "npm", "name": "left-pad"}}]}`), 0o644))
+
+	output := filepath.Join(t.TempDir(), "db", "advisories.json")
+	var outBuf bytes.Buffer
+	app := &App{Out: &outBuf, Err: io.Discard}
+
+	assert.NoError(t, app.advisoriesImportCommand(advisoriesImportOptions{Sources: []string{source}, Output: output}))
+	assert.Equal(t, "Imported 1 advisories for GitHub Actions into "+output+" (1 in total)\n", outBuf.String())
+
+	// Importing again merges into the existing database
+	outBuf.Reset()
+	assert.NoError(t, app.advisoriesImportCommand(advisoriesImportOptions{Sources: []string{source}, Output: output}))
+	assert.Equal(t, "Imported 1 advisories for GitHub Actions into "+output+" (1 in total)\n", outBuf.String())
+
+	db, err := app.loadAdvisories(".", output, false)
+	assert.NoError(t, err)
+	assert.Len(t, db.Advisories(), 1)
+
+	assert.Error(t, app.advisoriesImportCommand(advisoriesImportOptions{Sources: []string{source}}))
+	assert.Error(t, app.advisoriesImportCommand(advisoriesImportOptions{Sources: []string{filepath.Join(source, "missing")}, Output: output}))
+}
+
+// writeConfig writes a configuration file to the default location in dir
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".github"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, config.DefaultPath), []byte(content), 0o644))
+}
+
+func TestApplyConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `exclude: [".github/workflows/legacy-*.yml"]
+allow: [myorg]
+resolver: git
+format: sarif
+timeout: 60
+`)
+	t.Setenv("DIGEST_PINNER_RESOLVER", "graphql")
+
+	app := &App{}
+	cmd := newRootCommand(app)
+	checkCmd, _, err := cmd.Find([]string{"check"})
+	assert.NoError(t, err)
+	assert.NoError(t, checkCmd.ParseFlags([]string{"--dir", dir, "--format", "json"}))
+
+	assert.NoError(t, app.applyConfig(checkCmd))
+
+	allow, _ := checkCmd.Flags().GetStringSlice("allow")
+	assert.Equal(t, []string{"myorg"}, allow)
+	resolver, _ := checkCmd.Flags().GetString("resolver")
+	assert.Equal(t, "graphql", resolver, "environment overrides the file")
+	format, _ := checkCmd.Flags().GetString("format")
+	assert.Equal(t, "json", format, "flags override the file")
+	assert.Equal(t, finder.Filter{Include: []string{}, Exclude: []string{".github/workflows/legacy-*.yml"}}, app.Filter)
+
+	updateCmd, _, err := cmd.Find([]string{"update"})
+	assert.NoError(t, err)
+	assert.NoError(t, updateCmd.ParseFlags([]string{"--dir", dir}))
+	assert.NoError(t, app.applyConfig(updateCmd))
+	timeout, _ := updateCmd.Flags().GetInt("timeout")
+	assert.Equal(t, 60, timeout)
+
+	scanCmd, _, err := cmd.Find([]string{"scan"})
+	assert.NoError(t, err)
+	assert.NoError(t, scanCmd.ParseFlags([]string{"--dir", dir, "--config", filepath.Join(dir, "missing.yml")}))
+	assert.ErrorIs(t, app.applyConfig(scanCmd), fs.ErrNotExist)
+}
+
+func TestConfigCommands(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DIGEST_PINNER_TIMEOUT", "10")
+
+	var outBuf bytes.Buffer
+	app := &App{Out: &outBuf, Err: io.Discard}
+
+	assert.NoError(t, app.configValidateCommand(configOptions{Dir: dir}))
+	assert.Equal(t, "No configuration file found at "+filepath.Join(dir, config.DefaultPath)+", using defaults\n", outBuf.String())
+
+	writeConfig(t, dir, "include: [\".github/**\"]\nresolver: git\nconcurrency: 4\n")
+	outBuf.Reset()
+	assert.NoError(t, app.configValidateCommand(configOptions{Dir: dir}))
+	assert.Equal(t, "Configuration "+filepath.Join(dir, config.DefaultPath)+" is valid\n", outBuf.String())
+
+	outBuf.Reset()
+	assert.NoError(t, app.configPrintCommand(configOptions{Dir: dir, Flags: config.Config{Resolver: "graphql"}}))
+	expected := `# Configuration file: ` + filepath.Join(dir, config.DefaultPath) + `
+include:
+  - .github/**
+resolver: graphql
+format: text
+concurrency: 4
+timeout: 10
+`
+	assert.Equal(t, expected, outBuf.String())
+
+	writeConfig(t, dir, "resolver: soap\n")
+	assert.ErrorContains(t, app.configValidateCommand(configOptions{Dir: dir}), `unsupported resolver "soap"`)
+}
+
+func TestConfigureClient(t *testing.T) {
+	// newServer starts a fake GitHub API that resolves every tag to sha and records the owners it served
+	newServer := func(sha string, owners *[]string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v3/repos/"), "/")
+			*owners = append(*owners, parts[0])
+			_, _ = fmt.Fprintf(w, `{"object":{"type":"commit","sha":%q}}`, sha)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	var defaultOwners, ghesOwners []string
+	defaultServer := newServer(strings.Repeat("a", 40), &defaultOwners)
+	ghesServer := newServer(strings.Repeat("b", 40), &ghesOwners)
+
+	tests := []struct {
+		name        string
+		opts        clientOptions
+		expectError bool
+	}{
+		{
+			name: "default and routed owners",
+			opts: clientOptions{
+				GitHubURL: defaultServer.URL + "/api/v3",
+				Routes:    []string{"corp=" + ghesServer.URL + "/api/v3", "platform=" + ghesServer.URL + "/api/v3"},
+			},
+		},
+		{
+			name:        "route without url",
+			opts:        clientOptions{Routes: []string{"corp"}},
+			expectError: true,
+		},
+		{
+			name:        "invalid url",
+			opts:        clientOptions{GitHubURL: "ghes.example.com"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaultOwners, ghesOwners = nil, nil
+			upd := updater.NewUpdater(new(MockGitHubClient))
+			app := &App{Out: io.Discard, Err: io.Discard, Updater: upd}
+
+			err := app.configureClient(tt.opts)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Same(t, app.Client, upd.Client)
+
+			for _, owner := range []string{"actions", "corp", "Platform"} {
+				_, err := app.Client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: owner, Repo: "repo", Ref: "v1"})
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, []string{"actions"}, defaultOwners)
+			assert.Equal(t, []string{"corp", "Platform"}, ghesOwners)
+		})
+	}
+}
+
+func TestConfigureClientGitResolver(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		_, _ = fmt.Fprint(w, "001e# service=git-upload-pack\n0000"+
+			"003a"+strings.Repeat("a", 40)+" refs/tags/v4\n0000")
+	}))
+	t.Cleanup(server.Close)
+
+	upd := updater.NewUpdater(new(MockGitHubClient))
+	app := &App{Updater: upd}
+	assert.NoError(t, app.configureClient(clientOptions{GitHubURL: server.URL + "/api/v3", Resolver: "git"}))
+
+	sha, err := upd.Client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"})
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("a", 40), sha)
+	assert.Equal(t, []string{"/actions/checkout.git/info/refs"}, paths)
+}
+
+func TestConfigureClientDefault(t *testing.T) {
+	client := new(MockGitHubClient)
+	app := &App{Client: client}
+
+	assert.NoError(t, app.configureClient(clientOptions{}))
+	assert.Same(t, client, app.Client)
+}
+
+func TestClientOptionsFromFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		want        clientOptions
+		expectError bool
+	}{
+		{
+			name: "flags",
+			args: []string{"--github-url", "https://ghes.example.com/api/v3", "--app-id", "42", "--app-private-key", "key.pem", "--installation-id", "7", "--resolver", "git"},
+			env:  map[string]string{"GITHUB_API_URL": "https://api.github.com", "GITHUB_APP_ID": "1", "GITHUB_APP_PRIVATE_KEY": "pem"},
+			want: clientOptions{GitHubURL: "https://ghes.example.com/api/v3", Routes: []string{}, Resolver: "git", AppID: 42, AppPrivateKeyFile: "key.pem", InstallationID: 7},
+		},
+		{
+			name: "environment",
+			env: map[string]string{
+				"GITHUB_API_URL":             "https://api.github.com",
+				"GITHUB_APP_ID":              "42",
+				"GITHUB_APP_PRIVATE_KEY":     "pem",
+				"GITHUB_APP_INSTALLATION_ID": "7",
+			},
+			want: clientOptions{GitHubURL: "https://api.github.com", Routes: []string{}, Resolver: "api", AppID: 42, AppPrivateKey: "pem", InstallationID: 7},
+		},
+		{
+			name:        "invalid app id",
+			env:         map[string]string{"GITHUB_APP_ID": "my-app"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"GITHUB_API_URL", "GITHUB_APP_ID", "GITHUB_APP_PRIVATE_KEY", "GITHUB_APP_INSTALLATION_ID"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			cmd := newRootCommand(&App{})
+			assert.NoError(t, cmd.ParseFlags(tt.args))
+
+			opts, err := clientOptionsFromFlags(cmd)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, opts)
+		})
+	}
+}
+
+func TestNewDefaultClient(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	assert.NoError(t, os.WriteFile(keyFile, pemKey, 0o600))
+
+	tests := []struct {
+		name        string
+		opts        clientOptions
+		expectError bool
+	}{
+		{name: "token", opts: clientOptions{}},
+		{name: "app with key file", opts: clientOptions{AppID: 42, AppPrivateKeyFile: keyFile}},
+		{name: "app with key from environment", opts: clientOptions{AppID: 42, AppPrivateKey: string(pemKey), InstallationID: 7}},
+		{name: "app without key", opts: clientOptions{AppID: 42}, expectError: true},
+		{name: "missing key file", opts: clientOptions{AppID: 42, AppPrivateKeyFile: keyFile + ".missing"}, expectError: true},
+		{name: "invalid key", opts: clientOptions{AppID: 42, AppPrivateKey: "not a key"}, expectError: true},
+		{name: "installation without app", opts: clientOptions{InstallationID: 7}, expectError: true},
+		{name: "git resolver", opts: clientOptions{Resolver: "git"}},
+		{name: "app with git resolver", opts: clientOptions{Resolver: "git", AppID: 42, AppPrivateKey: string(pemKey)}, expectError: true},
+		{name: "graphql resolver", opts: clientOptions{Resolver: "graphql"}},
+		{name: "app with graphql resolver", opts: clientOptions{Resolver: "graphql", AppID: 42, AppPrivateKey: string(pemKey)}},
+		{name: "unsupported resolver", opts: clientOptions{Resolver: "soap"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newDefaultClient(tt.opts)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, client)
+		})
+	}
+}
+
+// rateLimitedClient reports a fixed API quota
+type rateLimitedClient struct {
+	MockGitHubClient
+	rate ghclient.Rate
+}
+
+func (c *rateLimitedClient) RateLimit() (ghclient.Rate, bool) {
+	return c.rate, true
+}
+
+func TestLogRateLimit(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	client := &rateLimitedClient{rate: ghclient.Rate{Limit: 60, Remaining: 12, Reset: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}}
+	app := &App{Client: new(MockGitHubClient), Updater: updater.NewUpdater(ghclient.NewCachingClient(client))}
+
+	app.logRateLimit()
+	assert.Contains(t, logBuf.String(), "GitHub API quota: 12 of 60 requests remaining, resets at 2025-01-01T12:00:00Z")
+
+	logBuf.Reset()
+	app = &App{Client: new(MockGitHubClient), Updater: &MockUpdater{}}
+	app.logRateLimit()
+	assert.Empty(t, logBuf.String())
+}
+
 func TestRootCommand(t *testing.T) {
 	app := &App{
 		Out:     os.Stdout,
@@ -305,15 +1714,39 @@ func TestRootCommand(t *testing.T) {
 	cmd := newRootCommand(app)
 
 	assert.Equal(t, "github-actions-digest-pinner", cmd.Use)
-	assert.Len(t, cmd.Commands(), 3)
+	assert.Len(t, cmd.Commands(), 11)
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("github-url"))
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("github-route"))
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("app-id"))
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("app-private-key"))
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("installation-id"))
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("config"))
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("include"))
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("exclude"))
 
-	var scanCmd, updateCmd *cobra.Command
+	var scanCmd, checkCmd, auditCmd, depsCmd, updateCmd, upgradeCmd, policyCmd, advisoriesCmd, cacheCmd, configCmd *cobra.Command
 	for _, c := range cmd.Commands() {
 		switch c.Use {
 		case "scan":
 			scanCmd = c
+		case "check":
+			checkCmd = c
+		case "audit":
+			auditCmd = c
+		case "deps":
+			depsCmd = c
 		case "update":
 			updateCmd = c
+		case "upgrade":
+			upgradeCmd = c
+		case "policy":
+			policyCmd = c
+		case "advisories":
+			advisoriesCmd = c
+		case "cache":
+			cacheCmd = c
+		case "config":
+			configCmd = c
 		}
 	}
 
@@ -326,8 +1759,62 @@ func TestRootCommand(t *testing.T) {
 	assert.NotNil(t, verboseFlag)
 	assert.Equal(t, "false", verboseFlag.DefValue)
 
+	assert.NotNil(t, checkCmd)
+	assert.Contains(t, checkCmd.Aliases, "verify")
+	allowFlag := checkCmd.Flags().Lookup("allow")
+	assert.NotNil(t, allowFlag)
+	assert.Equal(t, "[]", allowFlag.DefValue)
+	assert.NotNil(t, checkCmd.Flags().Lookup("advisories"))
+	assert.NotNil(t, scanCmd.Flags().Lookup("advisories"))
+
+	assert.NotNil(t, auditCmd)
+	assert.Equal(t, "text", auditCmd.Flags().Lookup("format").DefValue)
+	assert.NotNil(t, auditCmd.Flags().Lookup("timeout"))
+
+	assert.NotNil(t, depsCmd)
+	assert.Equal(t, "text", depsCmd.Flags().Lookup("format").DefValue)
+	assert.NotNil(t, depsCmd.Flags().Lookup("no-cache"))
+
 	assert.NotNil(t, updateCmd)
 	timeoutFlag := updateCmd.Flags().Lookup("timeout")
 	assert.NotNil(t, timeoutFlag)
 	assert.Equal(t, "30", timeoutFlag.DefValue)
+
+	diffFormatFlag := updateCmd.Flags().Lookup("diff-format")
+	assert.NotNil(t, diffFormatFlag)
+	assert.Equal(t, "unified", diffFormatFlag.DefValue)
+
+	concurrencyFlag := updateCmd.Flags().Lookup("concurrency")
+	assert.NotNil(t, concurrencyFlag)
+	assert.Equal(t, "8", concurrencyFlag.DefValue)
+
+	cacheTTLFlag := updateCmd.Flags().Lookup("cache-ttl")
+	assert.NotNil(t, cacheTTLFlag)
+	assert.Equal(t, "24h0m0s", cacheTTLFlag.DefValue)
+	assert.NotNil(t, updateCmd.Flags().Lookup("no-cache"))
+
+	lockfileFlag := updateCmd.Flags().Lookup("lockfile")
+	assert.NotNil(t, lockfileFlag)
+	assert.Empty(t, lockfileFlag.DefValue)
+	assert.NotNil(t, updateCmd.Flags().Lookup("frozen"))
+
+	assert.NotNil(t, upgradeCmd)
+	assert.Equal(t, "minor", upgradeCmd.Flags().Lookup("level").DefValue)
+	assert.NotNil(t, upgradeCmd.Flags().Lookup("range"))
+	assert.NotNil(t, upgradeCmd.Flags().Lookup("dry-run"))
+
+	assert.NotNil(t, policyCmd)
+	assert.Len(t, policyCmd.Commands(), 1)
+	assert.Equal(t, ".github/digest-pinner-policy.yml", policyCmd.Commands()[0].Flags().Lookup("policy").DefValue)
+
+	assert.NotNil(t, advisoriesCmd)
+	assert.Len(t, advisoriesCmd.Commands(), 1)
+	assert.NotNil(t, advisoriesCmd.Commands()[0].Flags().Lookup("output"))
+
+	assert.NotNil(t, cacheCmd)
+	assert.Len(t, cacheCmd.Commands(), 1)
+	assert.Equal(t, "clean", cacheCmd.Commands()[0].Use)
+
+	assert.NotNil(t, configCmd)
+	assert.Len(t, configCmd.Commands(), 2)
 }
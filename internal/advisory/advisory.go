@@ -0,0 +1,247 @@
+package advisory
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/checker"
+	"github.com/zisuu/github-actions-digest-pinner/internal/semver"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// Ecosystem is the OSV ecosystem of GitHub Actions
+const Ecosystem = "GitHub Actions"
+
+var shaRegex = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// Advisory is an OSV advisory, as published by the GitHub Advisory Database. Only the fields
+// needed to match GitHub Actions references are kept.
+type Advisory struct {
+	ID               string           `json:"id"`
+	Modified         string           `json:"modified,omitempty"`
+	Withdrawn        string           `json:"withdrawn,omitempty"`
+	Aliases          []string         `json:"aliases,omitempty"`
+	Summary          string           `json:"summary,omitempty"`
+	Affected         []Affected       `json:"affected"`
+	DatabaseSpecific DatabaseSpecific `json:"database_specific,omitzero"`
+}
+
+// Affected lists the affected versions of a package
+type Affected struct {
+	Package  Package  `json:"package"`
+	Ranges   []Range  `json:"ranges,omitempty"`
+	Versions []string `json:"versions,omitempty"`
+}
+
+// Package identifies an action repository, e.g. "tj-actions/changed-files"
+type Package struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+}
+
+// Range is an OSV range. ECOSYSTEM and SEMVER ranges are compared as versions; of GIT ranges only
+// the commits named by introduced and last_affected events are matched, as no commit graph is available.
+type Range struct {
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+// Event is the start or end of an affected interval
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// DatabaseSpecific holds the GHSA severity, e.g. "CRITICAL"
+type DatabaseSpecific struct {
+	Severity string `json:"severity,omitempty"`
+}
+
+// Severity returns the severity of the advisory in lower case, or "unknown"
+func (a Advisory) Severity() string {
+	if a.DatabaseSpecific.Severity == "" {
+		return "unknown"
+	}
+	return strings.ToLower(a.DatabaseSpecific.Severity)
+}
+
+// affects reports whether the advisory applies to a version or commit of the named package.
+// Either version or sha may be unset.
+func (a Advisory) affects(name string, version semver.Version, hasVersion bool, sha string) bool {
+	for _, affected := range a.Affected {
+		if affected.Package.Ecosystem != Ecosystem || !strings.EqualFold(affected.Package.Name, name) {
+			continue
+		}
+		if sha != "" && affected.hasCommit(sha) {
+			return true
+		}
+		if hasVersion && affected.hasVersion(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCommit reports whether a commit is listed as affected, either as a version or as the
+// introduced or last affected commit of a range
+func (a Affected) hasCommit(sha string) bool {
+	for _, version := range a.Versions {
+		if strings.EqualFold(version, sha) {
+			return true
+		}
+	}
+	for _, r := range a.Ranges {
+		for _, event := range r.Events {
+			for _, commit := range []string{event.Introduced, event.LastAffected} {
+				if shaRegex.MatchString(commit) && strings.EqualFold(commit, sha) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasVersion reports whether a version is listed as affected or lies within an affected range.
+// A floating version such as v45 is affected if any release of its line may be.
+func (a Affected) hasVersion(v semver.Version) bool {
+	for _, listed := range a.Versions {
+		if lv, ok := semver.Parse(listed); ok && v.Contains(lv) {
+			return true
+		}
+	}
+
+	low, high := v, nextLine(v)
+	for _, r := range a.Ranges {
+		if r.Type != "ECOSYSTEM" && r.Type != "SEMVER" {
+			continue
+		}
+		for _, interval := range r.intervals() {
+			if interval.overlaps(low, high) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// interval is an affected version interval [introduced, end). end is the fixed version, or the
+// last affected version if inclusive is set, and unbounded if open is set.
+type interval struct {
+	introduced semver.Version
+	end        semver.Version
+	inclusive  bool
+	open       bool
+}
+
+// intervals returns the affected intervals of a range. Events that are not versions are skipped.
+func (r Range) intervals() []interval {
+	var intervals []interval
+	var current *interval
+	for _, event := range r.Events {
+		switch {
+		case event.Introduced != "":
+			v, ok := semver.Parse(event.Introduced)
+			if !ok {
+				continue
+			}
+			intervals = append(intervals, interval{introduced: v, open: true})
+			current = &intervals[len(intervals)-1]
+		case current != nil && (event.Fixed != "" || event.LastAffected != ""):
+			end, inclusive := event.Fixed, false
+			if end == "" {
+				end, inclusive = event.LastAffected, true
+			}
+			v, ok := semver.Parse(end)
+			if !ok {
+				continue
+			}
+			current.end, current.inclusive, current.open = v, inclusive, false
+			current = nil
+		}
+	}
+	return intervals
+}
+
+// overlaps reports whether the interval intersects the versions from low up to, but excluding, high
+func (i interval) overlaps(low, high semver.Version) bool {
+	if semver.Compare(i.introduced, high) >= 0 {
+		return false
+	}
+	if i.open {
+		return true
+	}
+	if i.inclusive {
+		return semver.Compare(low, i.end) <= 0
+	}
+	return semver.Compare(low, i.end) < 0
+}
+
+// nextLine returns the first version after the release line of a floating version, e.g. 46.0.0 for
+// v45 and 45.1.0 for v45.0. For a full version it returns the next patch release.
+func nextLine(v semver.Version) semver.Version {
+	next := semver.Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch, Components: 3}
+	switch v.Components {
+	case 1:
+		next.Major, next.Minor, next.Patch = v.Major+1, 0, 0
+	case 2:
+		next.Minor, next.Patch = v.Minor+1, 0
+	default:
+		if v.Prerelease != "" {
+			return next
+		}
+		next.Patch++
+	}
+	return next
+}
+
+// Database holds the advisories for GitHub Actions
+type Database struct {
+	advisories []Advisory
+}
+
+// NewDatabase creates a database of the advisories for the GitHub Actions ecosystem that were not withdrawn
+func NewDatabase(advisories []Advisory) *Database {
+	db := &Database{}
+	for _, a := range advisories {
+		if a.Withdrawn != "" {
+			continue
+		}
+		if slices.ContainsFunc(a.Affected, func(affected Affected) bool { return affected.Package.Ecosystem == Ecosystem }) {
+			db.advisories = append(db.advisories, a)
+		}
+	}
+	return db
+}
+
+// Advisories returns the advisories of the database
+func (db *Database) Advisories() []Advisory {
+	return db.advisories
+}
+
+// Match returns the advisories affecting an action reference. Pinned references are matched by
+// their commit SHA and by the version in their comment (e.g. "# v45.0.7"); other references by
+// their tag. Images are not matched.
+func (db *Database) Match(action types.ActionRef) []Advisory {
+	if db == nil || action.IsImage() {
+		return nil
+	}
+
+	name := action.Owner + "/" + action.Repo
+	sha := ""
+	version, hasVersion := semver.Parse(action.Ref)
+	if checker.IsPinned(action) {
+		sha = action.Ref
+		version, hasVersion = semver.FromComment(action.Comment)
+	}
+
+	var matches []Advisory
+	for _, a := range db.advisories {
+		if a.affects(name, version, hasVersion, sha) {
+			matches = append(matches, a)
+		}
+	}
+	return matches
+}
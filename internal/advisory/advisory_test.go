@@ -0,0 +1,111 @@
+package advisory
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// testAdvisory is the advisory of the tj-actions/changed-files compromise
+const testAdvisory = `{
+  "id": "GHSA-mrrh-fwg8-r2c3",
+  "modified": "2025-03-20T12:00:00Z",
+  "aliases": ["CVE-2025-30066"],
+  "summary": "tj-actions changed-files through 45.0.7 allows remote attackers to discover secrets by reading actions logs",
+  "affected": [
+    {
+      "package": {"ecosystem": "GitHub Actions", "name": "tj-actions/changed-files"},
+      "ranges": [
+        {"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "46.0.1"}]},
+        {"type": "GIT", "repo": "https://github.com/tj-actions/changed-files", "events": [{"introduced": "0e58ed8671d6b60d0890c21b07f8835ace038e67"}]}
+      ]
+    }
+  ],
+  "database_specific": {"severity": "HIGH"}
+}`
+
+func testDatabase(t *testing.T) *Database {
+	t.Helper()
+	var a Advisory
+	if err := json.Unmarshal([]byte(testAdvisory), &a); err != nil {
+		t.Fatalf("failed to parse advisory: %v", err)
+	}
+
+	other := Advisory{
+		ID: "GHSA-test-0000-0001",
+		Affected: []Affected{{
+			Package:  Package{Ecosystem: Ecosystem, Name: "myorg/deploy"},
+			Versions: []string{"v2.1.0", "a81bbbf8298c0fa03ea29cdc473d45769f953675"},
+			Ranges: []Range{{Type: "SEMVER", Events: []Event{
+				{Introduced: "3.0.0"}, {LastAffected: "3.2.0"},
+				{Introduced: "5.0.0"},
+			}}},
+		}},
+	}
+	withdrawn := Advisory{ID: "GHSA-test-0000-0002", Withdrawn: "2025-01-01T00:00:00Z", Affected: other.Affected}
+	npm := Advisory{ID: "GHSA-test-0000-0003", Affected: []Affected{{Package: Package{Ecosystem: "npm", Name: "myorg/deploy"}}}}
+
+	return NewDatabase([]Advisory{a, other, withdrawn, npm})
+}
+
+func TestDatabase_Match(t *testing.T) {
+	db := testDatabase(t)
+	if len(db.Advisories()) != 2 {
+		t.Fatalf("expected withdrawn and other ecosystem advisories to be dropped, got %d", len(db.Advisories()))
+	}
+
+	tests := []struct {
+		name     string
+		action   types.ActionRef
+		expected []string
+	}{
+		{name: "affected tag", action: types.ActionRef{Owner: "tj-actions", Repo: "changed-files", Ref: "v45"}, expected: []string{"GHSA-mrrh-fwg8-r2c3"}},
+		{name: "fixed tag", action: types.ActionRef{Owner: "tj-actions", Repo: "changed-files", Ref: "v46.0.1"}},
+		{name: "floating tag overlapping the range", action: types.ActionRef{Owner: "tj-actions", Repo: "changed-files", Ref: "v46"}, expected: []string{"GHSA-mrrh-fwg8-r2c3"}},
+		{name: "case-insensitive name", action: types.ActionRef{Owner: "TJ-Actions", Repo: "Changed-Files", Ref: "v44.5.1"}, expected: []string{"GHSA-mrrh-fwg8-r2c3"}},
+		{name: "malicious commit", action: types.ActionRef{Owner: "tj-actions", Repo: "changed-files", Ref: "0e58ed8671d6b60d0890c21b07f8835ace038e67"}, expected: []string{"GHSA-mrrh-fwg8-r2c3"}},
+		{
+			name:     "pinned commit with affected version comment",
+			action:   types.ActionRef{Owner: "tj-actions", Repo: "changed-files", Ref: "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c", Comment: "v45.0.7"},
+			expected: []string{"GHSA-mrrh-fwg8-r2c3"},
+		},
+		{name: "pinned commit with fixed version comment", action: types.ActionRef{Owner: "tj-actions", Repo: "changed-files", Ref: "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c", Comment: "v46.0.1"}},
+		{name: "branch", action: types.ActionRef{Owner: "tj-actions", Repo: "changed-files", Ref: "main"}},
+		{name: "listed version", action: types.ActionRef{Owner: "myorg", Repo: "deploy", Ref: "v2.1.0"}, expected: []string{"GHSA-test-0000-0001"}},
+		{name: "listed commit", action: types.ActionRef{Owner: "myorg", Repo: "deploy", Ref: "A81BBBF8298C0FA03EA29CDC473D45769F953675"}, expected: []string{"GHSA-test-0000-0001"}},
+		{name: "last affected", action: types.ActionRef{Owner: "myorg", Repo: "deploy", Ref: "v3.2.0"}, expected: []string{"GHSA-test-0000-0001"}},
+		{name: "between ranges", action: types.ActionRef{Owner: "myorg", Repo: "deploy", Ref: "v4.0.0"}},
+		{name: "open range", action: types.ActionRef{Owner: "myorg", Repo: "deploy", Path: "sub", Ref: "v7.1"}, expected: []string{"GHSA-test-0000-0001"}},
+		{name: "image", action: types.ActionRef{Image: "tj-actions/changed-files", Ref: "v45", Kind: types.KindDockerImage}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := db.Match(tt.action)
+			if len(matches) != len(tt.expected) {
+				t.Fatalf("expected %v, got %d matches: %+v", tt.expected, len(matches), matches)
+			}
+			for i, a := range matches {
+				if a.ID != tt.expected[i] {
+					t.Errorf("expected %s, got %s", tt.expected[i], a.ID)
+				}
+			}
+		})
+	}
+
+	var nilDB *Database
+	if matches := nilDB.Match(types.ActionRef{Owner: "tj-actions", Repo: "changed-files", Ref: "v45"}); matches != nil {
+		t.Errorf("expected no matches without a database, got %+v", matches)
+	}
+}
+
+func TestAdvisory_Severity(t *testing.T) {
+	db := testDatabase(t)
+	if severity := db.Advisories()[0].Severity(); severity != "high" {
+		t.Errorf("expected high, got %s", severity)
+	}
+	if severity := db.Advisories()[1].Severity(); severity != "unknown" {
+		t.Errorf("expected unknown, got %s", severity)
+	}
+}
@@ -0,0 +1,169 @@
+package advisory
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// databaseFileName is the name of the imported advisory database in the data directory
+const databaseFileName = "advisories.json"
+
+// DefaultDatabasePath returns the location advisories are imported to by default, in the user
+// configuration directory so that cleaning the cache keeps them.
+func DefaultDatabasePath() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user configuration directory: %w", err)
+	}
+	return filepath.Join(base, "github-actions-digest-pinner", databaseFileName), nil
+}
+
+// Load loads a database from an OSV JSON file holding a single advisory or an array of them, from a
+// ZIP archive of such files (e.g. the all.zip dump of an OSV ecosystem), or from a directory of them.
+func Load(path string) (*Database, error) {
+	advisories, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewDatabase(advisories), nil
+}
+
+// Read reads all advisories from a file, ZIP archive or directory without filtering them
+func Read(path string) ([]Advisory, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read advisories: %w", err)
+	}
+
+	if !info.IsDir() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read advisories: %w", err)
+		}
+		if strings.EqualFold(filepath.Ext(path), ".zip") {
+			return readZip(path, content)
+		}
+		return decode(path, content)
+	}
+
+	var advisories []Advisory
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(file))
+		if d.IsDir() || (ext != ".json" && ext != ".zip") {
+			return nil
+		}
+		found, err := Read(file)
+		if err != nil {
+			return err
+		}
+		advisories = append(advisories, found...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read advisories from %s: %w", path, err)
+	}
+	return advisories, nil
+}
+
+// readZip reads the JSON files of a ZIP archive
+func readZip(path string, content []byte) ([]Advisory, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+
+	var advisories []Advisory
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(f.Name), ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in %s: %w", f.Name, path, err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s in %s: %w", f.Name, path, err)
+		}
+
+		found, err := decode(path+":"+f.Name, data)
+		if err != nil {
+			return nil, err
+		}
+		advisories = append(advisories, found...)
+	}
+	return advisories, nil
+}
+
+// decode parses a JSON advisory or array of advisories
+func decode(name string, content []byte) ([]Advisory, error) {
+	content = bytes.TrimSpace(content)
+	if bytes.HasPrefix(content, []byte("[")) {
+		var advisories []Advisory
+		if err := json.Unmarshal(content, &advisories); err != nil {
+			return nil, fmt.Errorf("failed to parse advisories in %s: %w", name, err)
+		}
+		return advisories, nil
+	}
+
+	var a Advisory
+	if err := json.Unmarshal(content, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse advisory %s: %w", name, err)
+	}
+	if a.ID == "" {
+		return nil, fmt.Errorf("failed to parse advisory %s: missing id", name)
+	}
+	return []Advisory{a}, nil
+}
+
+// Merge merges advisories into a database, keeping the most recently modified version of each ID.
+// The result is sorted by ID.
+func Merge(existing, imported []Advisory) []Advisory {
+	byID := make(map[string]Advisory, len(existing)+len(imported))
+	for _, a := range slices.Concat(existing, imported) {
+		if current, ok := byID[a.ID]; ok && current.Modified > a.Modified {
+			continue
+		}
+		byID[a.ID] = a
+	}
+
+	merged := make([]Advisory, 0, len(byID))
+	for _, a := range byID {
+		merged = append(merged, a)
+	}
+	slices.SortFunc(merged, func(a, b Advisory) int { return strings.Compare(a.ID, b.ID) })
+	return merged
+}
+
+// Save writes the advisories of the database as an indented JSON array, creating the parent directory
+func (db *Database) Save(path string) error {
+	advisories := db.advisories
+	if advisories == nil {
+		advisories = []Advisory{}
+	}
+
+	data, err := json.MarshalIndent(advisories, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode advisories: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create advisory directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write advisories %s: %w", path, err)
+	}
+	return nil
+}
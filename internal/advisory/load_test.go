@@ -0,0 +1,132 @@
+package advisory
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	single := filepath.Join(dir, "single.json")
+	if err := os.WriteFile(single, []byte(testAdvisory), 0o644); err != nil {
+		t.Fatalf("failed to write advisory: %v", err)
+	}
+
+	nested := filepath.Join(dir, "nested")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	array := `[{"id": "GHSA-test-0000-0001", "affected": []}, {"id": "GHSA-test-0000-0002", "affected": []}]`
+	if err := os.WriteFile(filepath.Join(nested, "array.json"), []byte(array), 0o644); err != nil {
+		t.Fatalf("failed to write advisories: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(nested, "README.md"), []byte("not an advisory"), 0o644); err != nil {
+		t.Fatalf("failed to write readme: %v", err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "all.zip")
+	writeZip(t, archive, map[string]string{"GHSA-mrrh-fwg8-r2c3.json": testAdvisory})
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{name: "single advisory", path: single, expected: 1},
+		{name: "directory", path: dir, expected: 3},
+		{name: "zip archive", path: archive, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advisories, err := Read(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(advisories) != tt.expected {
+				t.Errorf("expected %d advisories, got %d", tt.expected, len(advisories))
+			}
+		})
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"summary": "no id"}`), 0o644); err != nil {
+		t.Fatalf("failed to write advisory: %v", err)
+	}
+	if _, err := Read(invalid); err == nil {
+		t.Error("expected error for an advisory without id, got nil")
+	}
+	if _, err := Read(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error for a missing file, got nil")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	existing := []Advisory{
+		{ID: "GHSA-b", Modified: "2025-03-20T12:00:00Z", Summary: "current"},
+		{ID: "GHSA-c", Modified: "2025-01-01T00:00:00Z", Summary: "old"},
+	}
+	imported := []Advisory{
+		{ID: "GHSA-c", Modified: "2025-02-01T00:00:00Z", Summary: "updated"},
+		{ID: "GHSA-b", Modified: "2025-03-01T00:00:00Z", Summary: "stale"},
+		{ID: "GHSA-a", Summary: "new"},
+	}
+
+	merged := Merge(existing, imported)
+	var summaries []string
+	for _, a := range merged {
+		summaries = append(summaries, a.ID+"="+a.Summary)
+	}
+	expected := []string{"GHSA-a=new", "GHSA-b=current", "GHSA-c=updated"}
+	if len(summaries) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, summaries)
+	}
+	for i := range expected {
+		if summaries[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, summaries)
+			break
+		}
+	}
+}
+
+func TestDatabase_Save(t *testing.T) {
+	db := testDatabase(t)
+	path := filepath.Join(t.TempDir(), "data", "advisories.json")
+	if err := db.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded.Advisories()) != 2 || loaded.Advisories()[0].ID != "GHSA-mrrh-fwg8-r2c3" {
+		t.Errorf("unexpected advisories after round trip: %+v", loaded.Advisories())
+	}
+}
+
+// writeZip writes a ZIP archive with the given files
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/checker"
+	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/internal/semver"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// Status is the outcome of auditing a pinned reference
+type Status string
+
+const (
+	// StatusOK means the version tag still points to the pinned commit and no newer release exists.
+	StatusOK Status = "ok"
+	// StatusTagMoved means the version tag now points to a commit that no release of that
+	// version points to, i.e. the tag was re-pointed after the reference was pinned.
+	StatusTagMoved Status = "tag-moved"
+	// StatusNewerRelease means the pinned commit is still what its version tag points to, but a
+	// newer release is available.
+	StatusNewerRelease Status = "newer-release"
+	// StatusImpostor means the pinned commit is not reachable from any branch or tag of the named
+	// repository. GitHub serves commits of forks from the parent repository, so such a commit may
+	// have been pushed to a fork by anyone.
+	StatusImpostor Status = "impostor-commit"
+	// StatusUnverified means the pinned commit was not found on the default branch or its version
+	// tag, and the repository has too many other branches and tags to compare it with all of them.
+	StatusUnverified Status = "unverified"
+	// StatusUnresolved means the version tag could not be resolved, e.g. because it was deleted or
+	// renamed after the reference was pinned.
+	StatusUnresolved Status = "unresolved"
+)
+
+// Finding is the result of auditing a SHA-pinned reference against its version comment
+type Finding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Uses    string `json:"uses"`
+	Version string `json:"version,omitempty"`
+	Status  Status `json:"status"`
+	// CurrentSHA is the commit the version tag points to now
+	CurrentSHA string `json:"current_sha,omitempty"`
+	// Latest is the newest release tag, if it is newer than the pinned version
+	Latest string `json:"latest,omitempty"`
+	// Error tells why the version tag could not be resolved
+	Error string `json:"error,omitempty"`
+}
+
+// Auditor re-resolves the version tags recorded next to pinned SHAs
+type Auditor struct {
+	client   ghclient.GitHubClient
+	verifier ghclient.CommitVerifier
+	// verified caches verification results by owner/repo@sha#version
+	verified map[string]Status
+}
+
+// NewAuditor creates an Auditor resolving tags with the given client
+func NewAuditor(client ghclient.GitHubClient) *Auditor {
+	return &Auditor{client: client}
+}
+
+// EnableCommitVerification makes the auditor check that every pinned commit is reachable from a
+// branch or tag of its repository. It fails if the client cannot verify commits.
+func (a *Auditor) EnableCommitVerification() error {
+	verifier, ok := ghclient.AsCommitVerifier(a.client)
+	if !ok {
+		return errors.New("commit verification is not supported by the configured resolver")
+	}
+	a.verifier = verifier
+	a.verified = make(map[string]Status)
+	return nil
+}
+
+// Audit returns a finding for every SHA-pinned reference in a file that carries a version
+// comment. References without a version comment are skipped, as there is no tag to compare,
+// unless commit verification is enabled.
+func (a *Auditor) Audit(ctx context.Context, file string, actions []types.ActionRef) ([]Finding, error) {
+	var findings []Finding
+	for _, action := range actions {
+		if action.IsImage() || !checker.IsPinned(action) {
+			continue
+		}
+
+		var finding Finding
+		version, ok := semver.FromComment(action.Comment)
+		if ok {
+			var err error
+			if finding, err = a.auditAction(ctx, action, version); err != nil {
+				return nil, err
+			}
+		} else if a.verifier != nil {
+			finding = Finding{Line: action.Line, Column: action.Column, Uses: action.String(), Status: StatusOK}
+		} else {
+			continue
+		}
+
+		if a.verifier != nil {
+			status, err := a.verify(ctx, action, version.Original)
+			if err != nil {
+				return nil, err
+			}
+			// A moved or unresolved tag fails the audit on its own, so only an impostor takes precedence over it
+			unverified := status == StatusUnverified && (finding.Status == StatusOK || finding.Status == StatusNewerRelease)
+			if status == StatusImpostor || unverified {
+				finding.Status = status
+			}
+		}
+		finding.File = file
+		findings = append(findings, finding)
+	}
+	return findings, nil
+}
+
+// verify checks that the pinned commit of a reference belongs to its repository. It returns
+// StatusOK, StatusImpostor or StatusUnverified.
+func (a *Auditor) verify(ctx context.Context, action types.ActionRef, version string) (Status, error) {
+	key := action.Owner + "/" + action.Repo + "@" + strings.ToLower(action.Ref) + "#" + version
+	if status, ok := a.verified[key]; ok {
+		return status, nil
+	}
+
+	status := StatusOK
+	reachable, err := a.verifier.IsReachable(ctx, action.Owner, action.Repo, action.Ref, version)
+	switch {
+	case errors.Is(err, ghclient.ErrUnverified):
+		status = StatusUnverified
+	case err != nil:
+		return "", fmt.Errorf("failed to verify %s: %w", action, err)
+	case !reachable:
+		status = StatusImpostor
+	}
+	a.verified[key] = status
+	return status, nil
+}
+
+// auditAction compares the pinned SHA of a reference with the commit its version tag points to
+// now. A tag that cannot be resolved is reported as StatusUnresolved; an error is only returned if
+// the tags cannot be listed or the context is done.
+func (a *Auditor) auditAction(ctx context.Context, action types.ActionRef, version semver.Version) (Finding, error) {
+	finding := Finding{
+		Line:    action.Line,
+		Column:  action.Column,
+		Uses:    action.String(),
+		Version: version.Original,
+		Status:  StatusOK,
+	}
+
+	tagged := action
+	tagged.Ref = version.Original
+	current, err := a.client.ResolveActionSHA(ctx, tagged)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Finding{}, ctx.Err()
+		}
+		finding.Status = StatusUnresolved
+		finding.Error = err.Error()
+		return finding, nil
+	}
+	finding.CurrentSHA = current
+
+	tags, err := a.listTags(ctx, action)
+	if err != nil {
+		return Finding{}, err
+	}
+
+	// A floating tag such as v4 is expected to move on to newer releases; the pinned commit is
+	// then identified by the most specific release tag of that line still pointing at it.
+	pinned, released := pinnedRelease(tags, version, action.Ref)
+	if latest, ok := latestRelease(tags, pinned, action.Ref); ok {
+		finding.Latest = latest
+		finding.Status = StatusNewerRelease
+	}
+	if !strings.EqualFold(current, action.Ref) && (version.Components == 3 || !released) {
+		finding.Status = StatusTagMoved
+	}
+	return finding, nil
+}
+
+// listTags lists the tags of the reference's repository, or none if the client cannot list tags
+func (a *Auditor) listTags(ctx context.Context, action types.ActionRef) ([]ghclient.Tag, error) {
+	lister, ok := ghclient.AsTagLister(a.client)
+	if !ok {
+		return nil, nil
+	}
+
+	tags, err := lister.ListTags(ctx, action.Owner, action.Repo)
+	if errors.Is(err, ghclient.ErrNotSupported) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s/%s: %w", action.Owner, action.Repo, err)
+	}
+	return tags, nil
+}
+
+// pinnedRelease returns the most specific release tag within the version's line that points to
+// sha, and whether one was found. Without such a tag the version itself is returned.
+func pinnedRelease(tags []ghclient.Tag, version semver.Version, sha string) (semver.Version, bool) {
+	best, found := version, false
+	for _, tag := range tags {
+		if !strings.EqualFold(tag.SHA, sha) {
+			continue
+		}
+		v, ok := semver.Parse(tag.Name)
+		if !ok || v.Prerelease != "" || !version.Contains(v) {
+			continue
+		}
+		if !found || v.Components > best.Components || (v.Components == best.Components && semver.Compare(v, best) > 0) {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
+
+// latestRelease returns the highest release tag newer than pinned that points to another commit than sha
+func latestRelease(tags []ghclient.Tag, pinned semver.Version, sha string) (string, bool) {
+	var latest semver.Version
+	found := false
+	for _, tag := range tags {
+		v, ok := semver.Parse(tag.Name)
+		if !ok || v.Prerelease != "" || strings.EqualFold(tag.SHA, sha) || semver.Compare(v, pinned) <= 0 {
+			continue
+		}
+		if !found || semver.Compare(v, latest) > 0 || (semver.Compare(v, latest) == 0 && v.Components > latest.Components) {
+			latest, found = v, true
+		}
+	}
+	return latest.Original, found
+}
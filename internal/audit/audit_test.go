@@ -0,0 +1,209 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+const (
+	pinnedSHA = "a81bbbf8298c0fa03ea29cdc473d45769f953675"
+	newerSHA  = "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c"
+	movedSHA  = "c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8"
+)
+
+// mockClient resolves tags from a fixed list
+type mockClient struct {
+	tags []ghclient.Tag
+}
+
+func (m *mockClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	for _, tag := range m.tags {
+		if tag.Name == action.Ref {
+			return tag.SHA, nil
+		}
+	}
+	return "", errors.New("not found")
+}
+
+func (m *mockClient) ListTags(ctx context.Context, owner, repo string) ([]ghclient.Tag, error) {
+	return m.tags, nil
+}
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name        string
+		comment     string
+		tags        []ghclient.Tag
+		wantStatus  Status
+		wantCurrent string
+		wantLatest  string
+	}{
+		{
+			name:        "tag unchanged",
+			comment:     "v4.1.0",
+			tags:        []ghclient.Tag{{Name: "v4", SHA: pinnedSHA}, {Name: "v4.1.0", SHA: pinnedSHA}},
+			wantStatus:  StatusOK,
+			wantCurrent: pinnedSHA,
+		},
+		{
+			name:        "newer release",
+			comment:     "v4.1.0 # x-release-please-version",
+			tags:        []ghclient.Tag{{Name: "v4.1.0", SHA: pinnedSHA}, {Name: "v4.2.0", SHA: newerSHA}, {Name: "v5.0.0-rc.1", SHA: movedSHA}},
+			wantStatus:  StatusNewerRelease,
+			wantCurrent: pinnedSHA,
+			wantLatest:  "v4.2.0",
+		},
+		{
+			name:        "release tag moved",
+			comment:     "v4.1.0",
+			tags:        []ghclient.Tag{{Name: "v4.1.0", SHA: movedSHA}},
+			wantStatus:  StatusTagMoved,
+			wantCurrent: movedSHA,
+		},
+		{
+			name:        "release tag moved while a newer release exists",
+			comment:     "v4.1.0",
+			tags:        []ghclient.Tag{{Name: "v4.1.0", SHA: movedSHA}, {Name: "v4.2.0", SHA: newerSHA}},
+			wantStatus:  StatusTagMoved,
+			wantCurrent: movedSHA,
+			wantLatest:  "v4.2.0",
+		},
+		{
+			name:        "floating tag advanced to a newer release",
+			comment:     "v4",
+			tags:        []ghclient.Tag{{Name: "v4", SHA: newerSHA}, {Name: "v4.1.0", SHA: pinnedSHA}, {Name: "v4.2.0", SHA: newerSHA}},
+			wantStatus:  StatusNewerRelease,
+			wantCurrent: newerSHA,
+			wantLatest:  "v4.2.0",
+		},
+		{
+			name:        "floating tag moved off any release",
+			comment:     "v4",
+			tags:        []ghclient.Tag{{Name: "v4", SHA: movedSHA}, {Name: "v4.1.0", SHA: newerSHA}},
+			wantStatus:  StatusTagMoved,
+			wantCurrent: movedSHA,
+			wantLatest:  "v4.1.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions := []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: pinnedSHA, Line: 7, Column: 15, Comment: tt.comment},
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Line: 8, Column: 15, Comment: "v4"},
+				{Owner: "actions", Repo: "checkout", Ref: pinnedSHA, Line: 9, Column: 15},
+				{Owner: "actions", Repo: "checkout", Ref: pinnedSHA, Line: 10, Column: 15, Comment: "keep"},
+			}
+
+			findings, err := NewAuditor(&mockClient{tags: tt.tags}).Audit(context.Background(), "ci.yml", actions)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(findings) != 1 {
+				t.Fatalf("expected 1 finding, got %d: %+v", len(findings), findings)
+			}
+
+			finding := findings[0]
+			if finding.File != "ci.yml" || finding.Line != 7 || finding.Uses != "actions/checkout@"+pinnedSHA {
+				t.Errorf("unexpected location %s:%d for %s", finding.File, finding.Line, finding.Uses)
+			}
+			if finding.Status != tt.wantStatus {
+				t.Errorf("expected status %s, got %s", tt.wantStatus, finding.Status)
+			}
+			if finding.CurrentSHA != tt.wantCurrent {
+				t.Errorf("expected current SHA %s, got %s", tt.wantCurrent, finding.CurrentSHA)
+			}
+			if finding.Latest != tt.wantLatest {
+				t.Errorf("expected latest %q, got %q", tt.wantLatest, finding.Latest)
+			}
+		})
+	}
+}
+
+func TestAudit_ResolveError(t *testing.T) {
+	client := &mockClient{tags: []ghclient.Tag{{Name: "v4.1.0", SHA: pinnedSHA}}}
+	actions := []types.ActionRef{
+		{Owner: "actions", Repo: "checkout", Ref: pinnedSHA, Line: 7, Comment: "v4.0.0"},
+		{Owner: "actions", Repo: "checkout", Ref: pinnedSHA, Line: 8, Comment: "v4.1.0"},
+	}
+
+	findings, err := NewAuditor(client).Audit(context.Background(), "ci.yml", actions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("expected the audit to continue after a deleted tag, got %d findings: %+v", len(findings), findings)
+	}
+	if findings[0].Status != StatusUnresolved || findings[0].Error == "" {
+		t.Errorf("expected status %s with the resolve error for a deleted tag, got %+v", StatusUnresolved, findings[0])
+	}
+	if findings[1].Status != StatusOK {
+		t.Errorf("expected status %s, got %s", StatusOK, findings[1].Status)
+	}
+}
+
+// verifyingClient reports a fixed set of commits as reachable and another as unverified, and
+// counts verifications
+type verifyingClient struct {
+	mockClient
+	reachable  map[string]bool
+	unverified string
+	calls      int
+}
+
+func (v *verifyingClient) IsReachable(ctx context.Context, owner, repo, sha, version string) (bool, error) {
+	v.calls++
+	if sha == v.unverified {
+		return false, ghclient.ErrUnverified
+	}
+	return v.reachable[sha], nil
+}
+
+func TestAudit_VerifyCommits(t *testing.T) {
+	client := &verifyingClient{
+		mockClient: mockClient{tags: []ghclient.Tag{{Name: "v4.1.0", SHA: pinnedSHA}, {Name: "v4.2.0", SHA: newerSHA}}},
+		reachable:  map[string]bool{pinnedSHA: true},
+		unverified: newerSHA,
+	}
+	actions := []types.ActionRef{
+		{Owner: "actions", Repo: "checkout", Ref: pinnedSHA, Line: 7, Comment: "v4.1.0"},
+		{Owner: "actions", Repo: "checkout", Ref: movedSHA, Line: 8, Comment: "v4.2.0"},
+		{Owner: "actions", Repo: "checkout", Ref: movedSHA, Line: 9},
+		{Owner: "actions", Repo: "checkout", Ref: pinnedSHA, Line: 10},
+		{Owner: "actions", Repo: "checkout", Ref: "v4", Line: 11},
+		{Owner: "actions", Repo: "checkout", Ref: pinnedSHA, Line: 12, Comment: "v4.1.0"},
+		{Owner: "actions", Repo: "checkout", Ref: newerSHA, Line: 13, Comment: "v4.2.0"},
+	}
+
+	auditor := NewAuditor(client)
+	if err := auditor.EnableCommitVerification(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	findings, err := auditor.Audit(context.Background(), "ci.yml", actions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[int]Status{7: StatusNewerRelease, 8: StatusImpostor, 9: StatusImpostor, 10: StatusOK, 12: StatusNewerRelease, 13: StatusUnverified}
+	if len(findings) != len(want) {
+		t.Fatalf("expected %d findings, got %d: %+v", len(want), len(findings), findings)
+	}
+	for _, finding := range findings {
+		if finding.Status != want[finding.Line] {
+			t.Errorf("line %d: expected status %s, got %s", finding.Line, want[finding.Line], finding.Status)
+		}
+	}
+	if client.calls != 5 {
+		t.Errorf("expected each commit and version to be verified once, got %d verifications", client.calls)
+	}
+}
+
+func TestAuditor_EnableCommitVerification(t *testing.T) {
+	if err := NewAuditor(&mockClient{}).EnableCommitVerification(); err == nil {
+		t.Error("expected error for a client that cannot verify commits, got nil")
+	}
+}
@@ -0,0 +1,86 @@
+package checker
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+var (
+	shaRegex    = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+	digestRegex = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+)
+
+// Finding is an action reference that is not pinned to a commit SHA
+type Finding struct {
+	File   string
+	Action types.ActionRef
+}
+
+// Checker reports action references that are not pinned to a commit SHA. References to
+// trusted owners or repositories are allowed to use tags and branches.
+type Checker struct {
+	allow []string
+}
+
+// NewChecker creates a Checker with the given allowlist. Each entry is either an owner
+// (e.g. "myorg") or an owner/repo pattern using path.Match syntax (e.g. "actions/*").
+func NewChecker(allow []string) *Checker {
+	patterns := make([]string, 0, len(allow))
+	for _, entry := range allow {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			entry += "/*"
+		}
+		patterns = append(patterns, strings.ToLower(entry))
+	}
+	return &Checker{allow: patterns}
+}
+
+// Check returns a finding for every action reference in a file that is neither pinned nor allowed
+func (c *Checker) Check(file string, actions []types.ActionRef) []Finding {
+	var findings []Finding
+	for _, action := range actions {
+		if IsPinned(action) || c.IsAllowed(action) {
+			continue
+		}
+		findings = append(findings, Finding{File: file, Action: action})
+	}
+	return findings
+}
+
+// IsAllowed reports whether the action's repository, or the name of an image, matches an allowlist entry
+func (c *Checker) IsAllowed(action types.ActionRef) bool {
+	// GitHub owner and repository names are case-insensitive
+	name := strings.ToLower(action.Owner + "/" + action.Repo)
+	if action.IsImage() {
+		name = strings.ToLower(action.Image)
+	}
+	for _, pattern := range c.allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPinned reports whether the action reference is a full commit SHA, or an image is pinned to a digest
+func IsPinned(action types.ActionRef) bool {
+	if action.IsImage() {
+		return digestRegex.MatchString(action.Digest)
+	}
+	return shaRegex.MatchString(action.Ref)
+}
+
+// PinTarget describes what a reference has to be pinned to, for use in messages
+func PinTarget(action types.ActionRef) string {
+	if action.IsImage() {
+		return "a digest"
+	}
+	return "a commit SHA"
+}
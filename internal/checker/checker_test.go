@@ -0,0 +1,124 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+func TestCheck(t *testing.T) {
+	actions := []types.ActionRef{
+		{Owner: "actions", Repo: "checkout", Ref: "v4", Line: 7, Column: 15},
+		{Owner: "actions", Repo: "setup-go", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675", Line: 8, Column: 15},
+		{Owner: "MyOrg", Repo: "shared", Path: ".github/workflows/build.yml", Ref: "main", Line: 3, Column: 11},
+		{Owner: "super-linter", Repo: "super-linter", Ref: "v6.7.0", Line: 9, Column: 15},
+	}
+
+	tests := []struct {
+		name     string
+		allow    []string
+		expected []string
+	}{
+		{
+			name:     "no allowlist",
+			expected: []string{"actions/checkout@v4", "MyOrg/shared/.github/workflows/build.yml@main", "super-linter/super-linter@v6.7.0"},
+		},
+		{
+			name:     "owner entries match case-insensitively",
+			allow:    []string{"myorg"},
+			expected: []string{"actions/checkout@v4", "super-linter/super-linter@v6.7.0"},
+		},
+		{
+			name:     "owner/repo patterns",
+			allow:    []string{"actions/*", " ", "super-linter/super-*"},
+			expected: []string{"MyOrg/shared/.github/workflows/build.yml@main"},
+		},
+		{
+			name:     "exact repository",
+			allow:    []string{"actions/setup-go", "actions/checkout"},
+			expected: []string{"MyOrg/shared/.github/workflows/build.yml@main", "super-linter/super-linter@v6.7.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := NewChecker(tt.allow).Check(".github/workflows/ci.yml", actions)
+
+			if len(findings) != len(tt.expected) {
+				t.Fatalf("expected %d findings, got %d: %v", len(tt.expected), len(findings), findings)
+			}
+			for i, finding := range findings {
+				if finding.File != ".github/workflows/ci.yml" {
+					t.Errorf("finding %d: unexpected file %q", i, finding.File)
+				}
+				if finding.Action.String() != tt.expected[i] {
+					t.Errorf("finding %d: expected %s, got %s", i, tt.expected[i], finding.Action)
+				}
+			}
+		})
+	}
+}
+
+func TestIsPinned(t *testing.T) {
+	tests := []struct {
+		ref      string
+		expected bool
+	}{
+		{ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675", expected: true},
+		{ref: "A81BBBF8298C0FA03EA29CDC473D45769F953675", expected: true},
+		{ref: "a81bbbf", expected: false},
+		{ref: "v4", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := IsPinned(types.ActionRef{Ref: tt.ref}); got != tt.expected {
+				t.Errorf("IsPinned(%q) = %v, expected %v", tt.ref, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheck_Images(t *testing.T) {
+	const digest = "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1"
+	actions := []types.ActionRef{
+		{Image: "alpine", Ref: "3.19", Kind: types.KindDockerImage},
+		{Image: "redis", Ref: "7", Digest: digest, Kind: types.KindContainerImage},
+		{Image: "ghcr.io/MyOrg/postgres", Kind: types.KindContainerImage},
+		{Image: "node", Digest: "sha256:4bcff6", Kind: types.KindContainerImage},
+	}
+
+	tests := []struct {
+		name     string
+		allow    []string
+		expected []string
+	}{
+		{
+			name:     "no allowlist",
+			expected: []string{"docker://alpine:3.19", "ghcr.io/MyOrg/postgres", "node@sha256:4bcff6"},
+		},
+		{
+			name:     "image patterns",
+			allow:    []string{"ghcr.io/myorg/*"},
+			expected: []string{"docker://alpine:3.19", "node@sha256:4bcff6"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := NewChecker(tt.allow).Check(".github/workflows/ci.yml", actions)
+
+			if len(findings) != len(tt.expected) {
+				t.Fatalf("expected %d findings, got %d: %v", len(tt.expected), len(findings), findings)
+			}
+			for i, finding := range findings {
+				if finding.Action.String() != tt.expected[i] {
+					t.Errorf("finding %d: expected %s, got %s", i, tt.expected[i], finding.Action)
+				}
+				if PinTarget(finding.Action) != "a digest" {
+					t.Errorf("finding %d: expected images to be pinned to a digest", i)
+				}
+			}
+		})
+	}
+}
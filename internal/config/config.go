@@ -0,0 +1,173 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/finder"
+	"github.com/zisuu/github-actions-digest-pinner/internal/report"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is where the configuration file is looked up, relative to the scanned directory.
+const DefaultPath = ".github/digest-pinner.yml"
+
+// EnvPrefix starts the names of the environment variables overriding settings of the file,
+// e.g. DIGEST_PINNER_TIMEOUT.
+const EnvPrefix = "DIGEST_PINNER_"
+
+// resolvers lists the supported values of the resolver setting.
+var resolvers = []string{"api", "git", "graphql"}
+
+// Config holds the settings of a project. Empty values are unset, so that settings from
+// several sources can be merged.
+type Config struct {
+	// Include and Exclude select the workflow and action files by glob (see finder.Filter).
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Allow lists the trusted owners and owner/repo patterns check accepts unpinned.
+	Allow       []string `yaml:"allow,omitempty"`
+	Resolver    string   `yaml:"resolver,omitempty"`
+	Format      string   `yaml:"format,omitempty"`
+	Concurrency int      `yaml:"concurrency,omitempty"`
+	// Timeout is the API timeout in seconds.
+	Timeout int `yaml:"timeout,omitempty"`
+	// Policy is the policy file of policy check, relative to the scanned directory.
+	Policy string `yaml:"policy,omitempty"`
+	// Advisories is the advisory file or directory checked by scan and check, relative to the scanned directory.
+	Advisories string `yaml:"advisories,omitempty"`
+}
+
+// Default returns the settings used when no source sets them.
+func Default() Config {
+	return Config{Resolver: "api", Format: "text", Concurrency: 8, Timeout: 30}
+}
+
+// Load reads and validates a configuration file. Unknown keys are rejected, so that typos do not
+// go unnoticed. A missing file is reported as an error wrapping fs.ErrNotExist.
+func Load(path string) (Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// FromEnv reads the settings from DIGEST_PINNER_* environment variables using getenv. Lists
+// (INCLUDE, EXCLUDE and ALLOW) are comma-separated.
+func FromEnv(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Include:    splitList(getenv(EnvPrefix + "INCLUDE")),
+		Exclude:    splitList(getenv(EnvPrefix + "EXCLUDE")),
+		Allow:      splitList(getenv(EnvPrefix + "ALLOW")),
+		Resolver:   getenv(EnvPrefix + "RESOLVER"),
+		Format:     getenv(EnvPrefix + "FORMAT"),
+		Policy:     getenv(EnvPrefix + "POLICY"),
+		Advisories: getenv(EnvPrefix + "ADVISORIES"),
+	}
+
+	for name, value := range map[string]*int{"CONCURRENCY": &cfg.Concurrency, "TIMEOUT": &cfg.Timeout} {
+		raw := getenv(EnvPrefix + name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s%s: %w", EnvPrefix, name, err)
+		}
+		*value = parsed
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid %s* environment: %w", EnvPrefix, err)
+	}
+	return cfg, nil
+}
+
+// Merge returns base with every setting that overlay sets replaced.
+func Merge(base, overlay Config) Config {
+	if overlay.Include != nil {
+		base.Include = overlay.Include
+	}
+	if overlay.Exclude != nil {
+		base.Exclude = overlay.Exclude
+	}
+	if overlay.Allow != nil {
+		base.Allow = overlay.Allow
+	}
+	if overlay.Resolver != "" {
+		base.Resolver = overlay.Resolver
+	}
+	if overlay.Format != "" {
+		base.Format = overlay.Format
+	}
+	if overlay.Concurrency != 0 {
+		base.Concurrency = overlay.Concurrency
+	}
+	if overlay.Timeout != 0 {
+		base.Timeout = overlay.Timeout
+	}
+	if overlay.Policy != "" {
+		base.Policy = overlay.Policy
+	}
+	if overlay.Advisories != "" {
+		base.Advisories = overlay.Advisories
+	}
+	return base
+}
+
+// Filter returns the file filter of the include and exclude settings.
+func (c Config) Filter() finder.Filter {
+	return finder.Filter{Include: c.Include, Exclude: c.Exclude}
+}
+
+// Validate reports every invalid setting.
+func (c Config) Validate() error {
+	var errs []error
+	if err := c.Filter().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Resolver != "" && !slices.Contains(resolvers, c.Resolver) {
+		errs = append(errs, fmt.Errorf("unsupported resolver %q, expected %s", c.Resolver, strings.Join(resolvers, ", ")))
+	}
+	if c.Format != "" {
+		if _, err := report.ParseFormat(c.Format); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Concurrency < 0 {
+		errs = append(errs, fmt.Errorf("concurrency must not be negative, got %d", c.Concurrency))
+	}
+	if c.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout must not be negative, got %d", c.Timeout))
+	}
+	return errors.Join(errs...)
+}
+
+// splitList splits a comma-separated list, returning nil for an empty value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected Config
+		wantErr  string
+	}{
+		{
+			name: "all settings",
+			content: `include:
+  - .github/**
+exclude: [".github/workflows/legacy-*.yml"]
+allow: [myorg, actions/*]
+resolver: graphql
+format: sarif
+concurrency: 4
+timeout: 60
+policy: .github/policy.yml
+advisories: .github/advisories
+`,
+			expected: Config{
+				Include:     []string{".github/**"},
+				Exclude:     []string{".github/workflows/legacy-*.yml"},
+				Allow:       []string{"myorg", "actions/*"},
+				Resolver:    "graphql",
+				Format:      "sarif",
+				Concurrency: 4,
+				Timeout:     60,
+				Policy:      ".github/policy.yml",
+				Advisories:  ".github/advisories",
+			},
+		},
+		{
+			name:     "empty file",
+			content:  "",
+			expected: Config{},
+		},
+		{
+			name:    "unknown key",
+			content: "concurency: 4\n",
+			wantErr: "field concurency not found",
+		},
+		{
+			name:    "invalid values",
+			content: "resolver: soap\nformat: xml\ntimeout: -1\nexclude: ['[a']\n",
+			wantErr: `unsupported resolver "soap"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "digest-pinner.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, cfg)
+			}
+		})
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yml"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	env := map[string]string{
+		"DIGEST_PINNER_EXCLUDE":     "tools/**, vendor/**",
+		"DIGEST_PINNER_RESOLVER":    "git",
+		"DIGEST_PINNER_CONCURRENCY": "2",
+	}
+	cfg, err := FromEnv(func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := Config{Exclude: []string{"tools/**", "vendor/**"}, Resolver: "git", Concurrency: 2}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected %+v, got %+v", expected, cfg)
+	}
+
+	env["DIGEST_PINNER_TIMEOUT"] = "soon"
+	if _, err := FromEnv(func(name string) string { return env[name] }); err == nil {
+		t.Error("expected error for a non-numeric timeout, got nil")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	file := Config{Include: []string{".github/**"}, Allow: []string{"myorg"}, Resolver: "graphql", Timeout: 60}
+	env := Config{Allow: []string{"actions/*"}, Timeout: 10}
+
+	got := Merge(Merge(Default(), file), env)
+	expected := Config{
+		Include:     []string{".github/**"},
+		Allow:       []string{"actions/*"},
+		Resolver:    "graphql",
+		Format:      "text",
+		Concurrency: 8,
+		Timeout:     10,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
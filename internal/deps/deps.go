@@ -0,0 +1,194 @@
+package deps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/checker"
+	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/internal/parser"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// MaxDepth limits how many levels of dependencies are followed below a reference
+const MaxDepth = 10
+
+// metadataFiles are the names GitHub looks up the metadata of an action under, in order
+var metadataFiles = []string{"action.yml", "action.yaml"}
+
+// Node is a reference together with the actions and images it runs. The dependencies of an action
+// are read from its action.yml, and those of a reusable workflow from the workflow file, at the
+// commit the reference resolves to.
+type Node struct {
+	Action types.ActionRef `json:"-"`
+	Uses   string          `json:"uses"`
+	// Line and Column locate the reference in the file it was found in, i.e. the workflow for the
+	// root of a tree and the action.yml of the parent node for dependencies
+	Line   int  `json:"line,omitempty"`
+	Column int  `json:"column,omitempty"`
+	Pinned bool `json:"pinned"`
+	// SHA is the commit the dependencies were read at
+	SHA          string  `json:"sha,omitempty"`
+	Dependencies []*Node `json:"dependencies,omitempty"`
+	// Cycle is set if the reference is one of its own ancestors; its dependencies are not repeated
+	Cycle bool `json:"cycle,omitempty"`
+	// Error tells why the dependencies could not be determined, e.g. an unresolvable tag
+	Error string `json:"error,omitempty"`
+}
+
+// Finding is a transitive dependency that is not pinned. Chain lists the references leading to it,
+// starting with the reference found in the workflow and ending with the unpinned one.
+type Finding struct {
+	Chain []types.ActionRef
+}
+
+// Unpinned returns the dependencies below the root of a tree that are not pinned. The root itself
+// is not included, as pinning it is the job of check.
+func (n *Node) Unpinned() []Finding {
+	var findings []Finding
+	var walk func(node *Node, chain []types.ActionRef)
+	walk = func(node *Node, chain []types.ActionRef) {
+		chain = append(chain[:len(chain):len(chain)], node.Action)
+		if len(chain) > 1 && !node.Pinned {
+			findings = append(findings, Finding{Chain: chain})
+		}
+		for _, dep := range node.Dependencies {
+			walk(dep, chain)
+		}
+	}
+	walk(n, nil)
+	return findings
+}
+
+// Analyzer builds dependency trees by fetching action metadata through a GitHub client
+type Analyzer struct {
+	client  ghclient.GitHubClient
+	fetcher ghclient.ContentFetcher
+	// deps caches the parsed dependencies by owner/repo/path@sha, as content at a commit never changes
+	deps map[string][]types.ActionRef
+}
+
+// NewAnalyzer creates an Analyzer resolving references and fetching files with the given client. It
+// fails if the client cannot fetch files.
+func NewAnalyzer(client ghclient.GitHubClient) (*Analyzer, error) {
+	fetcher, ok := ghclient.AsContentFetcher(client)
+	if !ok {
+		return nil, errors.New("fetching action metadata is not supported by the configured resolver")
+	}
+	return &Analyzer{client: client, fetcher: fetcher, deps: make(map[string][]types.ActionRef)}, nil
+}
+
+// Analyze builds the dependency tree of a reference. Problems with single nodes, such as a tag that
+// cannot be resolved, are recorded in the node; an error is only returned if the client cannot
+// fetch files at all or the context is done.
+func (a *Analyzer) Analyze(ctx context.Context, action types.ActionRef) (*Node, error) {
+	return a.build(ctx, action, nil)
+}
+
+// build builds the tree of a reference whose ancestors are identified by the keys in ancestors
+func (a *Analyzer) build(ctx context.Context, action types.ActionRef, ancestors []string) (*Node, error) {
+	node := &Node{
+		Action: action,
+		Uses:   action.String(),
+		Line:   action.Line,
+		Column: action.Column,
+		Pinned: checker.IsPinned(action),
+	}
+	// Images do not run further references
+	if action.IsImage() {
+		return node, nil
+	}
+
+	sha, err := a.client.ResolveActionSHA(ctx, action)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		node.Error = err.Error()
+		return node, nil
+	}
+	node.SHA = sha
+
+	key := strings.ToLower(action.Owner+"/"+action.Repo+"/"+action.Path) + "@" + sha
+	for _, ancestor := range ancestors {
+		if ancestor == key {
+			node.Cycle = true
+			return node, nil
+		}
+	}
+	if len(ancestors) >= MaxDepth {
+		node.Error = fmt.Sprintf("dependencies deeper than %d levels are not followed", MaxDepth)
+		return node, nil
+	}
+
+	deps, err := a.dependencies(ctx, action, sha, key)
+	if err != nil {
+		if errors.Is(err, ghclient.ErrNotSupported) {
+			return nil, fmt.Errorf("fetching action metadata is not supported by the configured resolver: %w", err)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		node.Error = err.Error()
+		return node, nil
+	}
+
+	ancestors = append(ancestors[:len(ancestors):len(ancestors)], key)
+	for _, dep := range deps {
+		child, err := a.build(ctx, dep, ancestors)
+		if err != nil {
+			return nil, err
+		}
+		node.Dependencies = append(node.Dependencies, child)
+	}
+	return node, nil
+}
+
+// dependencies fetches and parses the metadata of an action, or the file of a reusable workflow, at a commit
+func (a *Analyzer) dependencies(ctx context.Context, action types.ActionRef, sha, key string) ([]types.ActionRef, error) {
+	if deps, ok := a.deps[key]; ok {
+		return deps, nil
+	}
+
+	var deps []types.ActionRef
+	var err error
+	if action.Kind == types.KindReusableWorkflow {
+		var content []byte
+		content, err = a.fetcher.FetchFile(ctx, action.Owner, action.Repo, action.Path, sha)
+		if err == nil {
+			deps, err = parser.ParseWorkflowActions(content)
+		}
+	} else {
+		deps, err = a.actionDependencies(ctx, action, sha)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.deps[key] = deps
+	return deps, nil
+}
+
+// actionDependencies fetches the first metadata file of an action that exists and parses it
+func (a *Analyzer) actionDependencies(ctx context.Context, action types.ActionRef, sha string) ([]types.ActionRef, error) {
+	for _, name := range metadataFiles {
+		file := path.Join(action.Path, name)
+		content, err := a.fetcher.FetchFile(ctx, action.Owner, action.Repo, file, sha)
+		if errors.Is(err, ghclient.ErrFileNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		deps, err := parser.ParseActionDependencies(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		return deps, nil
+	}
+	return nil, fmt.Errorf("no %s found in %s at %s", strings.Join(metadataFiles, " or "), path.Join(action.Owner, action.Repo, action.Path), sha)
+}
@@ -0,0 +1,237 @@
+package deps
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+const (
+	setupSHA  = "a81bbbf8298c0fa03ea29cdc473d45769f953675"
+	toolSHA   = "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c"
+	cacheSHA  = "c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8"
+	sharedSHA = "d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0"
+)
+
+// fakeClient resolves refs and serves files from maps keyed by "owner/repo@ref" and "owner/repo/path@sha"
+type fakeClient struct {
+	refs    map[string]string
+	files   map[string]string
+	fetches map[string]int
+}
+
+func (f *fakeClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	if len(action.Ref) == 40 {
+		return action.Ref, nil
+	}
+	if sha, ok := f.refs[action.Owner+"/"+action.Repo+"@"+action.Ref]; ok {
+		return sha, nil
+	}
+	return "", errors.New("ref not found")
+}
+
+func (f *fakeClient) FetchFile(ctx context.Context, owner, repo, path, sha string) ([]byte, error) {
+	key := owner + "/" + repo + "/" + path + "@" + sha
+	f.fetches[key]++
+	content, ok := f.files[key]
+	if !ok {
+		return nil, ghclient.ErrFileNotFound
+	}
+	return []byte(content), nil
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		refs: map[string]string{
+			"other/tool@main": toolSHA,
+			"myorg/shared@v1": sharedSHA,
+		},
+		files: map[string]string{
+			"myorg/setup/action.yml@" + setupSHA: `runs:
+  using: composite
+  steps:
+    - uses: other/tool@main
+    - uses: actions/cache/save@` + cacheSHA + `
+`,
+			"other/tool/action.yaml@" + toolSHA: `runs:
+  using: docker
+  image: docker://alpine:3.20
+`,
+			"actions/cache/save/action.yml@" + cacheSHA: `runs:
+  using: node20
+  main: dist/save.js
+`,
+			"myorg/shared/.github/workflows/build.yml@" + sharedSHA: `jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: myorg/setup@` + setupSHA + `
+      - uses: myorg/broken@v9
+`,
+			"myorg/loop/action.yml@" + setupSHA: `runs:
+  using: composite
+  steps:
+    - uses: myorg/loop@` + setupSHA + `
+`,
+		},
+		fetches: make(map[string]int),
+	}
+}
+
+// render prints a tree with one line per node, indented by depth
+func render(node *Node) string {
+	var b strings.Builder
+	var walk func(node *Node, depth int)
+	walk = func(node *Node, depth int) {
+		b.WriteString(strings.Repeat("  ", depth) + node.Uses)
+		if !node.Pinned {
+			b.WriteString(" (unpinned)")
+		}
+		if node.Cycle {
+			b.WriteString(" (cycle)")
+		}
+		if node.Error != "" {
+			b.WriteString(" (error)")
+		}
+		b.WriteString("\n")
+		for _, dep := range node.Dependencies {
+			walk(dep, depth+1)
+		}
+	}
+	walk(node, 0)
+	return b.String()
+}
+
+func TestAnalyzer_Analyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   types.ActionRef
+		expected string
+		unpinned int
+	}{
+		{
+			name:   "composite action",
+			action: types.ActionRef{Owner: "myorg", Repo: "setup", Ref: setupSHA, Kind: types.KindAction},
+			expected: "myorg/setup@" + setupSHA + "\n" +
+				"  other/tool@main (unpinned)\n" +
+				"    docker://alpine:3.20 (unpinned)\n" +
+				"  actions/cache/save@" + cacheSHA + "\n",
+			unpinned: 2,
+		},
+		{
+			name:   "reusable workflow",
+			action: types.ActionRef{Owner: "myorg", Repo: "shared", Path: ".github/workflows/build.yml", Ref: "v1", Kind: types.KindReusableWorkflow},
+			expected: "myorg/shared/.github/workflows/build.yml@v1 (unpinned)\n" +
+				"  myorg/setup@" + setupSHA + "\n" +
+				"    other/tool@main (unpinned)\n" +
+				"      docker://alpine:3.20 (unpinned)\n" +
+				"    actions/cache/save@" + cacheSHA + "\n" +
+				"  myorg/broken@v9 (unpinned) (error)\n",
+			unpinned: 3,
+		},
+		{
+			name:     "cycle",
+			action:   types.ActionRef{Owner: "myorg", Repo: "loop", Ref: setupSHA, Kind: types.KindAction},
+			expected: "myorg/loop@" + setupSHA + "\n  myorg/loop@" + setupSHA + " (cycle)\n",
+		},
+		{
+			name:     "missing metadata",
+			action:   types.ActionRef{Owner: "myorg", Repo: "empty", Ref: setupSHA, Kind: types.KindAction},
+			expected: "myorg/empty@" + setupSHA + " (error)\n",
+		},
+		{
+			name:     "image",
+			action:   types.ActionRef{Image: "node", Ref: "20", Kind: types.KindContainerImage},
+			expected: "node:20 (unpinned)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer, err := NewAnalyzer(newFakeClient())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			node, err := analyzer.Analyze(context.Background(), tt.action)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := render(node); got != tt.expected {
+				t.Errorf("unexpected tree:\nexpected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+			if got := len(node.Unpinned()); got != tt.unpinned {
+				t.Errorf("expected %d unpinned dependencies, got %d", tt.unpinned, got)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_CachesPerSHA(t *testing.T) {
+	client := newFakeClient()
+	analyzer, err := NewAnalyzer(client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	action := types.ActionRef{Owner: "myorg", Repo: "setup", Ref: setupSHA, Kind: types.KindAction}
+	for range 2 {
+		if _, err := analyzer.Analyze(context.Background(), action); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// other/tool has no action.yml, so action.yaml is read after it
+	for _, key := range []string{"myorg/setup/action.yml@" + setupSHA, "other/tool/action.yml@" + toolSHA, "other/tool/action.yaml@" + toolSHA} {
+		if got := client.fetches[key]; got != 1 {
+			t.Errorf("expected 1 fetch of %s, got %d", key, got)
+		}
+	}
+}
+
+func TestNode_Unpinned(t *testing.T) {
+	client := newFakeClient()
+	analyzer, err := NewAnalyzer(client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	node, err := analyzer.Analyze(context.Background(), types.ActionRef{Owner: "myorg", Repo: "setup", Ref: setupSHA, Kind: types.KindAction})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	findings := node.Unpinned()
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %d", len(findings))
+	}
+	chain := findings[1].Chain
+	if len(chain) != 3 || chain[0].Repo != "setup" || chain[1].Repo != "tool" || chain[2].Image != "alpine" {
+		t.Errorf("unexpected chain: %+v", chain)
+	}
+}
+
+// resolveOnlyClient cannot fetch files
+type resolveOnlyClient struct{}
+
+func (resolveOnlyClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	return setupSHA, nil
+}
+
+func TestAnalyzer_NotSupported(t *testing.T) {
+	if _, err := NewAnalyzer(resolveOnlyClient{}); err == nil {
+		t.Error("expected error for a client that cannot fetch files, got nil")
+	}
+
+	analyzer, err := NewAnalyzer(ghclient.NewCachingClient(resolveOnlyClient{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = analyzer.Analyze(context.Background(), types.ActionRef{Owner: "myorg", Repo: "setup", Ref: "v1", Kind: types.KindAction})
+	if !errors.Is(err, ghclient.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change
+const contextLines = 3
+
+// opKind is the kind of a line in an edit script
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is a single line of an edit script
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns a unified diff between oldContent and newContent with the given file names
+// in the header, or an empty string if the contents are equal.
+func Unified(oldName, newName, oldContent, newContent string) string {
+	if oldContent == newContent {
+		return ""
+	}
+
+	ops := editScript(splitLines(oldContent), splitLines(newContent))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].kind == opEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk until more than twice the context separates two changes
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != opEqual {
+				end = i + 1
+			} else if i-end >= 2*contextLines {
+				break
+			}
+		}
+
+		from := max(start-contextLines, 0)
+		to := min(end+contextLines, len(ops))
+		writeHunk(&b, ops, from, to)
+		start = to
+	}
+
+	return b.String()
+}
+
+// writeHunk writes the hunk covering ops[from:to] including its header
+func writeHunk(b *strings.Builder, ops []op, from, to int) {
+	oldStart, newStart := 1, 1
+	for _, o := range ops[:from] {
+		if o.kind != opInsert {
+			oldStart++
+		}
+		if o.kind != opDelete {
+			newStart++
+		}
+	}
+
+	oldCount, newCount := 0, 0
+	for _, o := range ops[from:to] {
+		if o.kind != opInsert {
+			oldCount++
+		}
+		if o.kind != opDelete {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, o := range ops[from:to] {
+		prefix := " "
+		switch o.kind {
+		case opDelete:
+			prefix = "-"
+		case opInsert:
+			prefix = "+"
+		}
+		b.WriteString(prefix)
+		b.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk range as used in unified diff headers
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	default:
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+}
+
+// splitLines splits content into lines, keeping the line terminators
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript computes a shortest edit script turning a into b using Myers' algorithm
+func editScript(a, b []string) []op {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD
+	v := make([]int, 2*maxD+2)
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the recorded Myers trace backwards to build the edit script
+func backtrack(trace [][]int, a, b []string, offset, d int) []op {
+	x, y := len(a), len(b)
+	var ops []op
+
+	for ; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: opEqual, line: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, op{kind: opInsert, line: b[y]})
+			} else {
+				x--
+				ops = append(ops, op{kind: opDelete, line: a[x]})
+			}
+		}
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
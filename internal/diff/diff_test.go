@@ -0,0 +1,106 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+	}{
+		{
+			name:     "equal content",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "single changed line",
+			old:  "on: push\njobs:\n  test:\n    steps:\n      - uses: actions/checkout@v4\n",
+			new:  "on: push\njobs:\n  test:\n    steps:\n      - uses: actions/checkout@a81bbbf # v4\n",
+			expected: `--- a/ci.yml
++++ b/ci.yml
+@@ -2,4 +2,4 @@
+ jobs:
+   test:
+     steps:
+-      - uses: actions/checkout@v4
++      - uses: actions/checkout@a81bbbf # v4
+`,
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expected: `--- a/ci.yml
++++ b/ci.yml
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+`,
+		},
+		{
+			name: "nearby changes share a hunk",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\neight\n",
+			expected: `--- a/ci.yml
++++ b/ci.yml
+@@ -1,8 +1,8 @@
+-1
++one
+ 2
+ 3
+ 4
+ 5
+ 6
+ 7
+-8
++eight
+`,
+		},
+		{
+			name: "insertions, deletions and missing newline",
+			old:  "a\nb\nc",
+			new:  "a\nc\nd\n",
+			expected: `--- a/ci.yml
++++ b/ci.yml
+@@ -1,3 +1,3 @@
+ a
+-b
+-c
+\ No newline at end of file
++c
++d
+`,
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "a\n",
+			expected: `--- a/ci.yml
++++ b/ci.yml
+@@ -0,0 +1 @@
++a
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("a/ci.yml", "b/ci.yml", tt.old, tt.new)
+			if got != tt.expected {
+				t.Errorf("diff mismatch:\nExpected:\n%s\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package finder
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Filter selects files by glob patterns matched against their slash-separated path relative to the
+// scanned directory. Patterns use path.Match syntax, in which a "**" segment also matches any number
+// of directories. A file is selected if it matches an include pattern, or there are none, and no
+// exclude pattern.
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// Validate reports the first malformed pattern of the filter
+func (f Filter) Validate() error {
+	for _, pattern := range append(append([]string(nil), f.Include...), f.Exclude...) {
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid glob %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Match reports whether the filter selects the file
+func (f Filter) Match(file string) bool {
+	file = filepath.ToSlash(file)
+
+	included := len(f.Include) == 0
+	for _, pattern := range f.Include {
+		if matchGlob(pattern, file) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for _, pattern := range f.Exclude {
+		if matchGlob(pattern, file) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the files selected by the filter, in their original order
+func (f Filter) Apply(files []string) []string {
+	if len(f.Include) == 0 && len(f.Exclude) == 0 {
+		return files
+	}
+
+	selected := make([]string, 0, len(files))
+	for _, file := range files {
+		if f.Match(file) {
+			selected = append(selected, file)
+		}
+	}
+	return selected
+}
+
+// matchGlob reports whether a slash-separated path matches a glob pattern
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, expanding "**" to any number of segments
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
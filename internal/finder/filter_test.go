@@ -0,0 +1,64 @@
+package finder
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilter_Apply(t *testing.T) {
+	files := []string{
+		".github/workflows/ci.yml",
+		".github/workflows/legacy-deploy.yml",
+		".github/actions/setup/action.yml",
+		"tools/vendor/action/action.yml",
+		"action.yml",
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{
+			name:     "no patterns",
+			expected: files,
+		},
+		{
+			name:     "include",
+			filter:   Filter{Include: []string{".github/**"}},
+			expected: []string{".github/workflows/ci.yml", ".github/workflows/legacy-deploy.yml", ".github/actions/setup/action.yml"},
+		},
+		{
+			name:     "exclude",
+			filter:   Filter{Exclude: []string{".github/workflows/legacy-*.yml", "tools/**"}},
+			expected: []string{".github/workflows/ci.yml", ".github/actions/setup/action.yml", "action.yml"},
+		},
+		{
+			name:     "double star matches no directory",
+			filter:   Filter{Include: []string{"**/action.yml"}, Exclude: []string{"**/vendor/**"}},
+			expected: []string{".github/actions/setup/action.yml", "action.yml"},
+		},
+		{
+			name:     "single star does not cross directories",
+			filter:   Filter{Include: []string{".github/*/ci.yml", "*.yml"}},
+			expected: []string{".github/workflows/ci.yml", "action.yml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Apply(files); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	if err := (Filter{Include: []string{"**/*.yml"}, Exclude: []string{".github/workflows/[a-c]*"}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (Filter{Exclude: []string{".github/[workflows"}}).Validate(); err == nil {
+		t.Error("expected error for a malformed pattern, got nil")
+	}
+}
@@ -6,6 +6,13 @@ import (
 	"strings"
 )
 
+// skipDirs lists directories that never contain action metadata worth pinning.
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
 // FindWorkflowFiles scans the provided filesystem for GitHub Actions workflow files
 func FindWorkflowFiles(fsys fs.FS) ([]string, error) {
 	var workflowFiles []string
@@ -31,3 +38,34 @@ func FindWorkflowFiles(fsys fs.FS) ([]string, error) {
 
 	return workflowFiles, err
 }
+
+// FindActionFiles scans the provided filesystem for action metadata files (action.yml or action.yaml)
+func FindActionFiles(fsys fs.FS) ([]string, error) {
+	var actionFiles []string
+
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != "." && skipDirs[d.Name()] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if IsActionFile(path) {
+			actionFiles = append(actionFiles, path)
+		}
+		return nil
+	})
+
+	return actionFiles, err
+}
+
+// IsActionFile reports whether the given path names an action metadata file
+func IsActionFile(path string) bool {
+	base := filepath.Base(path)
+	return base == "action.yml" || base == "action.yaml"
+}
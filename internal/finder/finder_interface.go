@@ -7,3 +7,7 @@ type DefaultFinder struct{}
 func (d DefaultFinder) FindWorkflowFiles(fsys fs.FS) ([]string, error) {
 	return FindWorkflowFiles(fsys)
 }
+
+func (d DefaultFinder) FindActionFiles(fsys fs.FS) ([]string, error) {
+	return FindActionFiles(fsys)
+}
@@ -74,3 +74,63 @@ func TestFindWorkflowFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestFindActionFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		fs       fstest.MapFS
+		expected []string
+	}{
+		{
+			name: "finds action metadata anywhere in the tree",
+			fs: fstest.MapFS{
+				".github/actions/setup/action.yml":    &fstest.MapFile{},
+				".github/actions/release/action.yaml": &fstest.MapFile{},
+				"action.yml":                          &fstest.MapFile{},
+				"tools/lint/action.yml":               &fstest.MapFile{},
+			},
+			expected: []string{
+				".github/actions/release/action.yaml",
+				".github/actions/setup/action.yml",
+				"action.yml",
+				"tools/lint/action.yml",
+			},
+		},
+		{
+			name: "ignores workflows and other yaml files",
+			fs: fstest.MapFS{
+				".github/workflows/action.yml.bak": &fstest.MapFile{},
+				".github/workflows/ci.yml":         &fstest.MapFile{},
+				"actions.yml":                      &fstest.MapFile{},
+			},
+			expected: nil,
+		},
+		{
+			name: "skips dependency and git directories",
+			fs: fstest.MapFS{
+				"node_modules/some-action/action.yml": &fstest.MapFile{},
+				"vendor/other/action.yml":             &fstest.MapFile{},
+				".git/action.yml":                     &fstest.MapFile{},
+				".github/actions/build/action.yml":    &fstest.MapFile{},
+			},
+			expected: []string{".github/actions/build/action.yml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := FindActionFiles(tt.fs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(files) != len(tt.expected) {
+				t.Fatalf("expected %d action files, got %d: %v", len(tt.expected), len(files), files)
+			}
+			for i, file := range files {
+				if file != tt.expected[i] {
+					t.Errorf("file %d: expected %q, got %q", i, tt.expected[i], file)
+				}
+			}
+		})
+	}
+}
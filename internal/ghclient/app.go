@@ -0,0 +1,257 @@
+package ghclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+)
+
+const (
+	// jwtLifetime is how long an app JWT is valid; GitHub accepts at most ten minutes.
+	jwtLifetime = 9 * time.Minute
+	// jwtClockSkew backdates the issue time of an app JWT to allow for clock drift.
+	jwtClockSkew = time.Minute
+	// tokenRefreshMargin is how long before its expiry an installation token is replaced.
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// AppConfig identifies a GitHub App used to authenticate requests instead of a personal token.
+type AppConfig struct {
+	AppID int64
+	// PrivateKey is the PEM encoded private key of the app.
+	PrivateKey []byte
+	// InstallationID selects the installation whose token is used for all requests. If zero, the
+	// installation is discovered per owner, falling back to the app's first installation for owners
+	// that have not installed it, since any installation token can read public repositories.
+	InstallationID int64
+}
+
+// NewGitHubAppClient creates a GitHub client for the API at baseURL that authenticates as an installation
+// of a GitHub App. Installation tokens are minted with a JWT signed by the app's key and refreshed before they expire.
+func NewGitHubAppClient(baseURL string, cfg AppConfig) (GitHubClient, error) {
+	key, err := parsePrivateKey(cfg.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := newGitHubClient(baseURL, &jwtTransport{appID: cfg.AppID, key: key, base: http.DefaultTransport}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &installationTransport{
+		apps:           apps.client.Apps,
+		installationID: cfg.InstallationID,
+		base:           http.DefaultTransport,
+		now:            time.Now,
+		owners:         make(map[string]*cacheEntry[int64]),
+		tokens:         make(map[int64]*cacheEntry[*github.InstallationToken]),
+	}
+	client, err := newGitHubClient(baseURL, transport, true)
+	if err != nil {
+		return nil, err
+	}
+	client.ownerScoped = cfg.InstallationID == 0
+	return client, nil
+}
+
+// parsePrivateKey parses a PEM encoded RSA key in PKCS #1 (as downloaded from GitHub) or PKCS #8 form.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to parse app private key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse app private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("failed to parse app private key: not an RSA key")
+	}
+	return key, nil
+}
+
+// jwtTransport authenticates requests as the GitHub App itself, which is required to discover
+// installations and to mint installation tokens.
+type jwtTransport struct {
+	appID int64
+	key   *rsa.PrivateKey
+	base  http.RoundTripper
+}
+
+// RoundTrip sends the request with a freshly signed app JWT.
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := signJWT(t.appID, t.key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// signJWT returns an RS256 signed JWT identifying the app, as described in
+// https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+func signJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT header: %w", err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// installationTransport authenticates requests with the token of the app installation
+// responsible for the repository owner in the request path. Installations and tokens are
+// cached like the responses of cachingClient: the lock is only held to access the maps, and
+// concurrent requests needing the same installation or token share a single API call.
+type installationTransport struct {
+	apps           *github.AppsService
+	installationID int64
+	base           http.RoundTripper
+	now            func() time.Time
+
+	mu     sync.Mutex
+	owners map[string]*cacheEntry[int64]
+	tokens map[int64]*cacheEntry[*github.InstallationToken]
+}
+
+// repositoryKey is the context key of the repository a request without one in its path is made for.
+type repositoryKey struct{}
+
+// withRepository returns a context for requests made on behalf of a repository whose path does
+// not name it, such as GraphQL queries, so that they are authenticated for its owner.
+func withRepository(ctx context.Context, owner, repo string) context.Context {
+	return context.WithValue(ctx, repositoryKey{}, [2]string{owner, repo})
+}
+
+// RoundTrip sends the request with an installation token.
+func (t *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	owner, repo := repositoryFromPath(req.URL.Path)
+	if owner == "" {
+		if r, ok := req.Context().Value(repositoryKey{}).([2]string); ok {
+			owner, repo = r[0], r[1]
+		}
+	}
+	token, err := t.token(req.Context(), owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// token returns a valid installation token for the owner, minting a new one if needed.
+func (t *installationTransport) token(ctx context.Context, owner, repo string) (string, error) {
+	id, err := t.installation(ctx, owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	if entry, ok := t.tokens[id]; ok && entry.ready() && !entry.value.GetExpiresAt().After(t.now().Add(tokenRefreshMargin)) {
+		delete(t.tokens, id)
+	}
+	t.mu.Unlock()
+
+	token, err := load(ctx, &t.mu, t.tokens, id, func() (*github.InstallationToken, error) {
+		token, _, err := t.apps.CreateInstallationToken(ctx, id, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create token for installation %d: %w", id, err)
+		}
+		return token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token.GetToken(), nil
+}
+
+// installation returns the ID of the installation used for a repository.
+func (t *installationTransport) installation(ctx context.Context, owner, repo string) (int64, error) {
+	if t.installationID != 0 {
+		return t.installationID, nil
+	}
+	if owner == "" || repo == "" {
+		return t.fallbackInstallation(ctx)
+	}
+
+	return load(ctx, &t.mu, t.owners, strings.ToLower(owner), func() (int64, error) {
+		installation, resp, err := t.apps.FindRepositoryInstallation(ctx, owner, repo)
+		switch {
+		case err == nil:
+			if id := installation.GetID(); id != 0 {
+				return id, nil
+			}
+		case resp == nil || resp.StatusCode != http.StatusNotFound:
+			return 0, fmt.Errorf("failed to find app installation for %s/%s: %w", owner, repo, err)
+		}
+		return t.fallbackInstallation(ctx)
+	})
+}
+
+// fallbackInstallation returns the first installation of the app, used for owners that have not installed it.
+// It is cached under the empty owner.
+func (t *installationTransport) fallbackInstallation(ctx context.Context) (int64, error) {
+	return load(ctx, &t.mu, t.owners, "", func() (int64, error) {
+		installations, _, err := t.apps.ListInstallations(ctx, &github.ListOptions{PerPage: 1})
+		if err != nil {
+			return 0, fmt.Errorf("failed to list app installations: %w", err)
+		}
+		if len(installations) == 0 {
+			return 0, errors.New("the GitHub App has no installations")
+		}
+		return installations[0].GetID(), nil
+	})
+}
+
+// repositoryFromPath extracts the owner and repository from an API path such as
+// /repos/{owner}/{repo}/git/ref/tags/v4 or /api/v3/repos/{owner}/{repo}/tags.
+func repositoryFromPath(path string) (string, string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if segment == "repos" && i+2 < len(segments) {
+			return segments[i+1], segments[i+2]
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,401 @@
+package ghclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// fakeAppServer is a GitHub API stand-in that issues installation tokens to a single app
+type fakeAppServer struct {
+	t      *testing.T
+	appID  int64
+	key    *rsa.PublicKey
+	expiry time.Duration
+	// beforeMint is called with the installation ID before a token is minted, if set
+	beforeMint func(id string)
+
+	mu     sync.Mutex
+	minted map[string]int
+	auth   map[string]string
+}
+
+func (f *fakeAppServer) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// Endpoints authenticated as the app
+	mux.HandleFunc("POST /api/v3/app/installations/{id}/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		f.verifyJWT(r)
+		id := r.PathValue("id")
+		if f.beforeMint != nil {
+			f.beforeMint(id)
+		}
+
+		f.mu.Lock()
+		f.minted[id]++
+		token := fmt.Sprintf("installation-%s-%d", id, f.minted[id])
+		f.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"token":%q,"expires_at":%q}`, token, time.Now().Add(f.expiry).Format(time.RFC3339))
+	})
+	mux.HandleFunc("GET /api/v3/repos/corp/{repo}/installation", func(w http.ResponseWriter, r *http.Request) {
+		f.verifyJWT(r)
+		_, _ = fmt.Fprint(w, `{"id":11}`)
+	})
+	mux.HandleFunc("GET /api/v3/repos/actions/{repo}/installation", func(w http.ResponseWriter, r *http.Request) {
+		f.verifyJWT(r)
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprint(w, `{"message":"Not Found"}`)
+	})
+	mux.HandleFunc("GET /api/v3/app/installations", func(w http.ResponseWriter, r *http.Request) {
+		f.verifyJWT(r)
+		_, _ = fmt.Fprint(w, `[{"id":99}]`)
+	})
+
+	// Endpoints authenticated as an installation
+	mux.HandleFunc("GET /api/v3/repos/{owner}/{repo}/git/ref/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.auth[r.PathValue("owner")] = r.Header.Get("Authorization")
+		f.mu.Unlock()
+		_, _ = fmt.Fprint(w, `{"object":{"type":"commit","sha":"cccccccccccccccccccccccccccccccccccccccc"}}`)
+	})
+
+	mux.HandleFunc("POST /api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]string `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			f.t.Errorf("failed to decode GraphQL request: %v", err)
+		}
+
+		data := make(map[string]any)
+		f.mu.Lock()
+		for name, value := range body.Variables {
+			if index, ok := strings.CutPrefix(name, "o"); ok {
+				f.auth[value] = r.Header.Get("Authorization")
+				data["r"+index] = map[string]any{"t": map[string]any{"target": map[string]string{
+					"__typename": "Commit", "oid": "cccccccccccccccccccccccccccccccccccccccc",
+				}}}
+			}
+		}
+		f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	})
+
+	return mux
+}
+
+// verifyJWT checks that a request carries a JWT issued by the app and signed with its key
+func (f *fakeAppServer) verifyJWT(r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(token, ".")
+	if !ok || len(parts) != 3 {
+		f.t.Errorf("expected a JWT, got %q", r.Header.Get("Authorization"))
+		return
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		f.t.Errorf("failed to decode JWT signature: %v", err)
+		return
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], signature); err != nil {
+		f.t.Errorf("invalid JWT signature: %v", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		f.t.Errorf("failed to decode JWT claims: %v", err)
+		return
+	}
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		f.t.Errorf("failed to decode JWT claims: %v", err)
+	}
+	if claims.Iss != fmt.Sprint(f.appID) {
+		f.t.Errorf("expected issuer %d, got %s", f.appID, claims.Iss)
+	}
+	if lifetime := time.Duration(claims.Exp-claims.Iat) * time.Second; lifetime > 10*time.Minute {
+		f.t.Errorf("JWT lifetime %s exceeds ten minutes", lifetime)
+	}
+}
+
+func TestNewGitHubAppClient(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	tests := []struct {
+		name           string
+		installationID int64
+		expiry         time.Duration
+		wantAuth       map[string]string
+		wantMinted     map[string]int
+	}{
+		{
+			name:           "fixed installation",
+			installationID: 5,
+			expiry:         time.Hour,
+			wantAuth:       map[string]string{"corp": "Bearer installation-5-1", "actions": "Bearer installation-5-1"},
+			wantMinted:     map[string]int{"5": 1},
+		},
+		{
+			name:       "installation per owner",
+			expiry:     time.Hour,
+			wantAuth:   map[string]string{"corp": "Bearer installation-11-1", "actions": "Bearer installation-99-1"},
+			wantMinted: map[string]int{"11": 1, "99": 1},
+		},
+		{
+			name:           "tokens close to expiry are refreshed",
+			installationID: 5,
+			expiry:         time.Minute,
+			wantAuth:       map[string]string{"corp": "Bearer installation-5-2", "actions": "Bearer installation-5-4"},
+			wantMinted:     map[string]int{"5": 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeAppServer{
+				t:      t,
+				appID:  42,
+				key:    &key.PublicKey,
+				expiry: tt.expiry,
+				minted: make(map[string]int),
+				auth:   make(map[string]string),
+			}
+			server := httptest.NewServer(fake.handler())
+			t.Cleanup(server.Close)
+
+			client, err := NewGitHubAppClient(server.URL, AppConfig{AppID: 42, PrivateKey: pemKey, InstallationID: tt.installationID})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, action := range []types.ActionRef{
+				{Owner: "corp", Repo: "deploy", Ref: "v1"},
+				{Owner: "corp", Repo: "build", Ref: "v1"},
+				{Owner: "actions", Repo: "checkout", Ref: "v4"},
+				{Owner: "actions", Repo: "cache", Ref: "v4"},
+			} {
+				if _, err := client.ResolveActionSHA(context.Background(), action); err != nil {
+					t.Fatalf("unexpected error for %s: %v", action, err)
+				}
+			}
+
+			if fmt.Sprint(fake.auth) != fmt.Sprint(tt.wantAuth) {
+				t.Errorf("expected authorization %v, got %v", tt.wantAuth, fake.auth)
+			}
+			if fmt.Sprint(fake.minted) != fmt.Sprint(tt.wantMinted) {
+				t.Errorf("expected minted tokens %v, got %v", tt.wantMinted, fake.minted)
+			}
+		})
+	}
+}
+
+func TestNewGitHubAppClient_Concurrent(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	// Minting the token of installation 11 blocks until the token of installation 99 was minted,
+	// which only works if a pending token request does not block requests for other installations
+	started, released := make(chan struct{}), make(chan struct{})
+	fake := &fakeAppServer{
+		t:      t,
+		appID:  42,
+		key:    &key.PublicKey,
+		expiry: time.Hour,
+		minted: make(map[string]int),
+		auth:   make(map[string]string),
+		beforeMint: func(id string) {
+			switch id {
+			case "11":
+				close(started)
+				select {
+				case <-released:
+				case <-time.After(5 * time.Second):
+					t.Error("token request for installation 99 was blocked by installation 11")
+				}
+			case "99":
+				close(released)
+			}
+		},
+	}
+	server := httptest.NewServer(fake.handler())
+	t.Cleanup(server.Close)
+
+	client, err := NewGitHubAppClient(server.URL, AppConfig{AppID: 42, PrivateKey: pemKey})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolve := func(owner, repo string) {
+		if _, err := client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: owner, Repo: repo, Ref: "v1"}); err != nil {
+			t.Errorf("unexpected error for %s/%s: %v", owner, repo, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() { resolve("corp", "deploy") })
+	<-started
+	for _, repo := range []string{"build", "test", "release"} {
+		wg.Go(func() { resolve("corp", repo) })
+	}
+	resolve("actions", "checkout")
+	wg.Wait()
+
+	want := map[string]int{"11": 1, "99": 1}
+	if fmt.Sprint(fake.minted) != fmt.Sprint(want) {
+		t.Errorf("expected one token per installation %v, got %v", want, fake.minted)
+	}
+}
+
+func TestNewGitHubAppClient_GraphQL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	fake := &fakeAppServer{
+		t:      t,
+		appID:  42,
+		key:    &key.PublicKey,
+		expiry: time.Hour,
+		minted: make(map[string]int),
+		auth:   make(map[string]string),
+	}
+	server := httptest.NewServer(fake.handler())
+	t.Cleanup(server.Close)
+
+	rest, err := NewGitHubAppClient(server.URL, AppConfig{AppID: 42, PrivateKey: pemKey})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client, err := NewGraphQLClient(rest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Lookups of several owners joining one batch are queried with the installation of each owner
+	var wg sync.WaitGroup
+	for _, action := range []types.ActionRef{
+		{Owner: "corp", Repo: "deploy", Ref: "v1"},
+		{Owner: "corp", Repo: "build", Ref: "v1"},
+		{Owner: "actions", Repo: "checkout", Ref: "v4"},
+	} {
+		wg.Go(func() {
+			if _, err := client.ResolveActionSHA(context.Background(), action); err != nil {
+				t.Errorf("unexpected error for %s: %v", action, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	wantAuth := map[string]string{"corp": "Bearer installation-11-1", "actions": "Bearer installation-99-1"}
+	if fmt.Sprint(fake.auth) != fmt.Sprint(wantAuth) {
+		t.Errorf("expected authorization %v, got %v", wantAuth, fake.auth)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{
+			name: "pkcs1",
+			data: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+		{
+			name: "pkcs8",
+			data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		},
+		{
+			name:    "not pem",
+			data:    []byte("not a key"),
+			wantErr: true,
+		},
+		{
+			name:    "garbage",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parsePrivateKey(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !parsed.Equal(key) {
+				t.Error("parsed key does not match")
+			}
+		})
+	}
+}
+
+func TestRepositoryFromPath(t *testing.T) {
+	tests := []struct {
+		path      string
+		wantOwner string
+		wantRepo  string
+	}{
+		{path: "/repos/actions/checkout/git/ref/tags/v4", wantOwner: "actions", wantRepo: "checkout"},
+		{path: "/api/v3/repos/corp/deploy/tags", wantOwner: "corp", wantRepo: "deploy"},
+		{path: "/repos/actions", wantOwner: "", wantRepo: ""},
+		{path: "/rate_limit", wantOwner: "", wantRepo: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			owner, repo := repositoryFromPath(tt.path)
+			if owner != tt.wantOwner || repo != tt.wantRepo {
+				t.Errorf("expected %s/%s, got %s/%s", tt.wantOwner, tt.wantRepo, owner, repo)
+			}
+		})
+	}
+}
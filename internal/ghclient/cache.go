@@ -0,0 +1,144 @@
+package ghclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// ErrNotSupported is returned by decorators when the wrapped client lacks an optional capability.
+var ErrNotSupported = errors.New("operation not supported by client")
+
+// cachingClient is a GitHubClient decorator that caches resolved SHAs, tag lists and fetched files in memory.
+// Concurrent lookups of the same key share a single request to the wrapped client.
+type cachingClient struct {
+	client GitHubClient
+
+	mu    sync.Mutex
+	shas  map[string]*cacheEntry[string]
+	tags  map[string]*cacheEntry[[]Tag]
+	files map[string]*cacheEntry[[]byte]
+}
+
+// cacheEntry holds a cached value; done is closed once the value or error is available.
+type cacheEntry[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// ready reports whether the value or error of the entry is available.
+func (e *cacheEntry[T]) ready() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewCachingClient wraps a GitHub client with an in-memory cache that lives as long as the returned client.
+// Failed lookups are not cached.
+func NewCachingClient(client GitHubClient) GitHubClient {
+	return &cachingClient{
+		client: client,
+		shas:   make(map[string]*cacheEntry[string]),
+		tags:   make(map[string]*cacheEntry[[]Tag]),
+		files:  make(map[string]*cacheEntry[[]byte]),
+	}
+}
+
+// ResolveActionSHA resolves the SHA of an action reference, using the cache when possible.
+func (c *cachingClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	key := action.Owner + "/" + action.Repo + "@" + action.Ref
+	return load(ctx, &c.mu, c.shas, key, func() (string, error) {
+		return c.client.ResolveActionSHA(ctx, action)
+	})
+}
+
+// ListTags lists the tags of a repository, using the cache when possible.
+func (c *cachingClient) ListTags(ctx context.Context, owner, repo string) ([]Tag, error) {
+	lister, ok := AsTagLister(c.client)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return load(ctx, &c.mu, c.tags, owner+"/"+repo, func() ([]Tag, error) {
+		return lister.ListTags(ctx, owner, repo)
+	})
+}
+
+// FetchFile reads a file of a repository at a commit, using the cache when possible.
+func (c *cachingClient) FetchFile(ctx context.Context, owner, repo, path, sha string) ([]byte, error) {
+	fetcher, ok := AsContentFetcher(c.client)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return load(ctx, &c.mu, c.files, owner+"/"+repo+"/"+path+"@"+sha, func() ([]byte, error) {
+		return fetcher.FetchFile(ctx, owner, repo, path, sha)
+	})
+}
+
+// Unwrap returns the wrapped client.
+func (c *cachingClient) Unwrap() GitHubClient {
+	return c.client
+}
+
+// load returns the cached value for key, calling fetch if there is none. Callers asking for a key
+// that is being fetched wait for that fetch instead of starting another one.
+func load[K comparable, T any](ctx context.Context, mu *sync.Mutex, entries map[K]*cacheEntry[T], key K, fetch func() (T, error)) (T, error) {
+	mu.Lock()
+	if entry, ok := entries[key]; ok {
+		mu.Unlock()
+		select {
+		case <-entry.done:
+			return entry.value, entry.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+
+	entry := &cacheEntry[T]{done: make(chan struct{})}
+	entries[key] = entry
+	mu.Unlock()
+
+	entry.value, entry.err = fetch()
+	if entry.err != nil {
+		mu.Lock()
+		delete(entries, key)
+		mu.Unlock()
+	}
+	close(entry.done)
+
+	return entry.value, entry.err
+}
+
+// AsTagLister returns the first client in a chain of decorators that can list tags.
+func AsTagLister(client GitHubClient) (TagLister, bool) {
+	return find[TagLister](client)
+}
+
+// AsRateReporter returns the first client in a chain of decorators that tracks the API quota.
+func AsRateReporter(client GitHubClient) (RateReporter, bool) {
+	return find[RateReporter](client)
+}
+
+// find walks a chain of decorators and returns the first client implementing T.
+func find[T any](client GitHubClient) (T, bool) {
+	for client != nil {
+		if capability, ok := client.(T); ok {
+			return capability, true
+		}
+		wrapper, ok := client.(interface{ Unwrap() GitHubClient })
+		if !ok {
+			break
+		}
+		client = wrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,138 @@
+package ghclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// countingClient counts the calls made to it and can be slowed down to provoke concurrent lookups
+type countingClient struct {
+	calls atomic.Int32
+	delay time.Duration
+	err   error
+}
+
+func (c *countingClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	c.calls.Add(1)
+	time.Sleep(c.delay)
+	return "sha-" + action.Ref, c.err
+}
+
+// countingTagLister additionally lists tags
+type countingTagLister struct {
+	countingClient
+	tagCalls atomic.Int32
+}
+
+func (c *countingTagLister) ListTags(ctx context.Context, owner, repo string) ([]Tag, error) {
+	c.tagCalls.Add(1)
+	return []Tag{{Name: "v1.0.0", SHA: "sha-v1"}}, nil
+}
+
+// countingFetcher additionally fetches files, returning "owner/repo/path@sha" as their content
+type countingFetcher struct {
+	countingClient
+	fileCalls atomic.Int32
+}
+
+func (c *countingFetcher) FetchFile(ctx context.Context, owner, repo, path, sha string) ([]byte, error) {
+	c.fileCalls.Add(1)
+	if path == "missing.yml" {
+		return nil, ErrFileNotFound
+	}
+	return []byte(owner + "/" + repo + "/" + path + "@" + sha), nil
+}
+
+func TestCachingClient_ResolveActionSHA(t *testing.T) {
+	inner := &countingClient{delay: 20 * time.Millisecond}
+	client := NewCachingClient(inner)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sha, err := client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"})
+			if err != nil || sha != "sha-v4" {
+				t.Errorf("unexpected result: %q, %v", sha, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A different path in the same repository resolves to the same SHA
+	_, _ = client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "actions", Repo: "checkout", Path: "sub", Ref: "v4"})
+	if got := inner.calls.Load(); got != 1 {
+		t.Errorf("expected 1 call to the wrapped client, got %d", got)
+	}
+
+	_, _ = client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v5"})
+	if got := inner.calls.Load(); got != 2 {
+		t.Errorf("expected 2 calls to the wrapped client, got %d", got)
+	}
+}
+
+func TestCachingClient_ErrorsAreNotCached(t *testing.T) {
+	inner := &countingClient{err: errors.New("boom")}
+	client := NewCachingClient(inner)
+	action := types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"}
+
+	for range 2 {
+		if _, err := client.ResolveActionSHA(context.Background(), action); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	}
+	if got := inner.calls.Load(); got != 2 {
+		t.Errorf("expected 2 calls to the wrapped client, got %d", got)
+	}
+}
+
+func TestCachingClient_ListTags(t *testing.T) {
+	inner := &countingTagLister{}
+	client := NewCachingClient(inner)
+
+	lister, ok := AsTagLister(client)
+	if !ok {
+		t.Fatal("expected caching client to list tags")
+	}
+	for range 3 {
+		tags, err := lister.ListTags(context.Background(), "actions", "checkout")
+		if err != nil || len(tags) != 1 {
+			t.Fatalf("unexpected result: %v, %v", tags, err)
+		}
+	}
+	if got := inner.tagCalls.Load(); got != 1 {
+		t.Errorf("expected 1 call to the wrapped client, got %d", got)
+	}
+
+	// Without a tag lister underneath, the decorator reports the capability as unsupported
+	lister, _ = AsTagLister(NewCachingClient(&countingClient{}))
+	if _, err := lister.ListTags(context.Background(), "actions", "checkout"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestCachingClient_FetchFile(t *testing.T) {
+	inner := &countingFetcher{}
+	fetcher, ok := AsContentFetcher(NewCachingClient(inner))
+	if !ok {
+		t.Fatal("expected caching client to fetch files")
+	}
+
+	for range 3 {
+		content, err := fetcher.FetchFile(context.Background(), "actions", "cache", "save/action.yml", "sha-1")
+		if err != nil || string(content) != "actions/cache/save/action.yml@sha-1" {
+			t.Fatalf("unexpected result: %q, %v", content, err)
+		}
+	}
+	_, _ = fetcher.FetchFile(context.Background(), "actions", "cache", "save/action.yml", "sha-2")
+	if got := inner.fileCalls.Load(); got != 2 {
+		t.Errorf("expected 2 calls to the wrapped client, got %d", got)
+	}
+}
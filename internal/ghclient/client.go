@@ -3,38 +3,133 @@ package ghclient
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/google/go-github/v75/github"
 	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
 	"golang.org/x/oauth2"
 )
 
+// maxTagDepth limits how many nested annotated tags are followed when resolving a ref.
+const maxTagDepth = 10
+
 type GitHubClient interface {
 	ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error)
 }
 
+// HostReporter is implemented by clients that know the GitHub server they resolve the references
+// of an owner against, e.g. "github.com" or "ghes.example.com".
+type HostReporter interface {
+	Host(owner string) string
+}
+
+// Tag is a repository tag and the commit it points to.
+type Tag struct {
+	Name string
+	SHA  string
+}
+
+// TagLister is implemented by clients that can list the tags of a repository.
+type TagLister interface {
+	ListTags(ctx context.Context, owner, repo string) ([]Tag, error)
+}
+
 // githubClient is a wrapper around the GitHub client.
 type githubClient struct {
-	client *github.Client
+	client    *github.Client
+	transport *retryTransport
+	// ownerScoped is set if requests are authenticated per repository owner, so requests without
+	// a repository in their path, such as GraphQL queries, must be made for a single owner
+	ownerScoped bool
 }
 
-// NewGitHubClient creates a new GitHub client.
+// NewGitHubClient creates a new GitHub client for github.com.
 func NewGitHubClient() GitHubClient {
-	token := os.Getenv("GITHUB_TOKEN")
+	client, _ := NewGitHubClientForURL("")
+	return client
+}
 
-	var client *github.Client
+// NewGitHubClientForURL creates a GitHub client for the API at baseURL, e.g. https://ghes.example.com/api/v3.
+// An empty baseURL or https://api.github.com selects github.com, authenticated with GITHUB_TOKEN; GitHub
+// Enterprise Server instances are authenticated with GH_ENTERPRISE_TOKEN, falling back to GITHUB_TOKEN.
+func NewGitHubClientForURL(baseURL string) (GitHubClient, error) {
+	enterprise, err := isEnterpriseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	token := tokenFromEnv(enterprise)
+
+	var transport http.RoundTripper = http.DefaultTransport
 	if token != "" {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
-		)
-		tc := oauth2.NewClient(context.Background(), ts)
-		client = github.NewClient(tc)
-	} else {
-		client = github.NewClient(nil)
+		transport = &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+			Base:   transport,
+		}
+	}
+
+	return newGitHubClient(baseURL, transport, token != "")
+}
+
+// newGitHubClient creates a GitHub client for the API at baseURL that sends its requests through transport.
+func newGitHubClient(baseURL string, transport http.RoundTripper, authenticated bool) (*githubClient, error) {
+	enterprise, err := isEnterpriseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := newRetryTransport(transport, authenticated)
+	client := github.NewClient(&http.Client{Transport: retry})
+
+	if enterprise {
+		client, err = client.WithEnterpriseURLs(baseURL, enterpriseUploadURL(baseURL))
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure GitHub API URL %s: %w", baseURL, err)
+		}
+	}
+
+	// Rate limits are waited out by the retry transport instead of failing requests up front
+	client.DisableRateLimitCheck = true
+
+	return &githubClient{client: client, transport: retry}, nil
+}
+
+// tokenFromEnv returns the token for github.com or a GitHub Enterprise Server from the environment.
+func tokenFromEnv(enterprise bool) string {
+	if enterprise && os.Getenv("GH_ENTERPRISE_TOKEN") != "" {
+		return os.Getenv("GH_ENTERPRISE_TOKEN")
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
+
+// isEnterpriseURL reports whether baseURL points to an API other than github.com's.
+func isEnterpriseURL(baseURL string) (bool, error) {
+	if baseURL == "" {
+		return false, nil
 	}
 
-	return &githubClient{client: client}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false, fmt.Errorf("invalid GitHub API URL %s: %w", baseURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false, fmt.Errorf("invalid GitHub API URL %s: expected an http or https URL", baseURL)
+	}
+
+	return !strings.EqualFold(u.Host, "api.github.com"), nil
+}
+
+// enterpriseUploadURL derives the upload URL of a GitHub Enterprise Server from its API URL,
+// e.g. https://ghes.example.com/api/uploads/ for https://ghes.example.com/api/v3.
+func enterpriseUploadURL(baseURL string) string {
+	trimmed := strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(trimmed, "/api/v3") {
+		return strings.TrimSuffix(trimmed, "v3") + "uploads/"
+	}
+	return baseURL
 }
 
 // ResolveActionSHA resolves the SHA of a GitHub Action reference.
@@ -51,7 +146,65 @@ func (g *githubClient) ResolveActionSHA(ctx context.Context, action types.Action
 		}
 	}
 
-	return ref.GetObject().GetSHA(), nil
+	return g.peelTag(ctx, action, ref.GetObject())
+}
+
+// peelTag follows annotated tag objects (which may point to further tags) until it
+// reaches the commit they reference, and returns the commit SHA.
+func (g *githubClient) peelTag(ctx context.Context, action types.ActionRef, object *github.GitObject) (string, error) {
+	for range maxTagDepth {
+		if object.GetType() != "tag" {
+			return object.GetSHA(), nil
+		}
+
+		tag, _, err := g.client.Git.GetTag(ctx, action.Owner, action.Repo, object.GetSHA())
+		if err != nil {
+			return "", fmt.Errorf("failed to dereference tag object %s for ref %s: %w", object.GetSHA(), action.Ref, err)
+		}
+		object = tag.GetObject()
+	}
+
+	return "", fmt.Errorf("failed to dereference ref %s: more than %d nested tags", action.Ref, maxTagDepth)
+}
+
+// ListTags lists all tags of a repository together with the commit SHA they point to.
+func (g *githubClient) ListTags(ctx context.Context, owner, repo string) ([]Tag, error) {
+	opts := &github.ListOptions{PerPage: 100}
+
+	var tags []Tag
+	for {
+		page, resp, err := g.client.Repositories.ListTags(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s/%s: %w", owner, repo, err)
+		}
+
+		for _, tag := range page {
+			tags = append(tags, Tag{Name: tag.GetName(), SHA: tag.GetCommit().GetSHA()})
+		}
+
+		if resp.NextPage == 0 {
+			return tags, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// Host returns the host of the GitHub server the API belongs to. The API of github.com is served
+// from api.github.com, which is reported as github.com like by the git client.
+func (g *githubClient) Host(owner string) string {
+	host := g.client.BaseURL.Hostname()
+	if host == "api.github.com" {
+		return "github.com"
+	}
+	return host
+}
+
+// RateLimit returns the API quota reported by the last response.
+func (g *githubClient) RateLimit() (Rate, bool) {
+	if g.transport == nil {
+		return Rate{}, false
+	}
+	return g.transport.RateLimit()
 }
 
 func isSHA(ref string) bool {
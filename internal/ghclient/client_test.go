@@ -3,8 +3,13 @@ package ghclient
 import (
 	"context"
 	"fmt"
-	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
 )
 
 type mockGitHubClient struct {
@@ -71,3 +76,205 @@ func TestResolveActionSHA(t *testing.T) {
 		})
 	}
 }
+
+// newTestClient returns a githubClient talking to a local fake API server served by mux.
+func newTestClient(t *testing.T, mux *http.ServeMux) *githubClient {
+	t.Helper()
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("failed to parse server URL: %v", err)
+	}
+	client.BaseURL = baseURL
+
+	return &githubClient{client: client}
+}
+
+func TestListTags(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/actions/checkout/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = fmt.Fprint(w, `[{"name":"v4","commit":{"sha":"1111111111111111111111111111111111111111"}}]`)
+			return
+		}
+		w.Header().Set("Link", `<`+r.URL.Path+`?page=2>; rel="next"`)
+		_, _ = fmt.Fprint(w, `[{"name":"v4.2.1","commit":{"sha":"1111111111111111111111111111111111111111"}},`+
+			`{"name":"v4.2.0","commit":{"sha":"2222222222222222222222222222222222222222"}}]`)
+	})
+
+	client := newTestClient(t, mux)
+	tags, err := client.ListTags(context.Background(), "actions", "checkout")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Tag{
+		{Name: "v4.2.1", SHA: "1111111111111111111111111111111111111111"},
+		{Name: "v4.2.0", SHA: "2222222222222222222222222222222222222222"},
+		{Name: "v4", SHA: "1111111111111111111111111111111111111111"},
+	}
+	if len(tags) != len(expected) {
+		t.Fatalf("expected %d tags, got %d", len(expected), len(tags))
+	}
+	for i, tag := range tags {
+		if tag != expected[i] {
+			t.Errorf("tag %d: expected %+v, got %+v", i, expected[i], tag)
+		}
+	}
+}
+
+func TestResolveActionSHAWithAPI(t *testing.T) {
+	const commitSHA = "a81bbbf8298c0fa03ea29cdc473d45769f953675"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/actions/checkout/git/ref/tags/v4", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"ref":"refs/tags/v4","object":{"type":"commit","sha":%q}}`, commitSHA)
+	})
+	mux.HandleFunc("/repos/actions/checkout/git/ref/tags/v4.2.1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"ref":"refs/tags/v4.2.1","object":{"type":"tag","sha":"1111111111111111111111111111111111111111"}}`)
+	})
+	mux.HandleFunc("/repos/actions/checkout/git/ref/tags/v4.2.0", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"ref":"refs/tags/v4.2.0","object":{"type":"tag","sha":"2222222222222222222222222222222222222222"}}`)
+	})
+	mux.HandleFunc("/repos/actions/checkout/git/ref/tags/loop", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"ref":"refs/tags/loop","object":{"type":"tag","sha":"3333333333333333333333333333333333333333"}}`)
+	})
+	mux.HandleFunc("/repos/actions/checkout/git/ref/heads/main", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"ref":"refs/heads/main","object":{"type":"commit","sha":%q}}`, commitSHA)
+	})
+	mux.HandleFunc("/repos/actions/checkout/git/tags/1111111111111111111111111111111111111111", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"tag":"v4.2.1","object":{"type":"commit","sha":%q}}`, commitSHA)
+	})
+	mux.HandleFunc("/repos/actions/checkout/git/tags/2222222222222222222222222222222222222222", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"tag":"v4.2.0","object":{"type":"tag","sha":"1111111111111111111111111111111111111111"}}`)
+	})
+	mux.HandleFunc("/repos/actions/checkout/git/tags/3333333333333333333333333333333333333333", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"tag":"loop","object":{"type":"tag","sha":"3333333333333333333333333333333333333333"}}`)
+	})
+
+	tests := []struct {
+		name    string
+		ref     string
+		wantSHA string
+		wantErr bool
+	}{
+		{name: "lightweight tag", ref: "v4", wantSHA: commitSHA},
+		{name: "annotated tag", ref: "v4.2.1", wantSHA: commitSHA},
+		{name: "nested annotated tag", ref: "v4.2.0", wantSHA: commitSHA},
+		{name: "branch", ref: "main", wantSHA: commitSHA},
+		{name: "already a SHA", ref: commitSHA, wantSHA: commitSHA},
+		{name: "tag cycle", ref: "loop", wantErr: true},
+		{name: "unknown ref", ref: "v999", wantErr: true},
+	}
+
+	client := newTestClient(t, mux)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := types.ActionRef{Owner: "actions", Repo: "checkout", Ref: tt.ref}
+			sha, err := client.ResolveActionSHA(context.Background(), action)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if sha != tt.wantSHA {
+				t.Errorf("expected SHA %q, got %q", tt.wantSHA, sha)
+			}
+		})
+	}
+}
+
+func TestNewGitHubClientForURL(t *testing.T) {
+	const commitSHA = "cccccccccccccccccccccccccccccccccccccccc"
+
+	// The fake Enterprise Server only answers below /api/v3 and records the token it receives
+	var gotAuth string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/corp/deploy/git/ref/tags/v1", func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = fmt.Fprintf(w, `{"ref":"refs/tags/v1","object":{"type":"commit","sha":%q}}`, commitSHA)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name            string
+		baseURL         string
+		enterpriseToken string
+		wantAuth        string
+		wantErr         bool
+	}{
+		{name: "host only", baseURL: server.URL, wantAuth: "Bearer github-token"},
+		{name: "api path", baseURL: server.URL + "/api/v3", wantAuth: "Bearer github-token"},
+		{name: "api path with slash", baseURL: server.URL + "/api/v3/", wantAuth: "Bearer github-token"},
+		{name: "enterprise token", baseURL: server.URL + "/api/v3", enterpriseToken: "ghes-token", wantAuth: "Bearer ghes-token"},
+		{name: "not a URL", baseURL: "ghes.example.com", wantErr: true},
+		{name: "unsupported scheme", baseURL: "ftp://ghes.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_TOKEN", "github-token")
+			t.Setenv("GH_ENTERPRISE_TOKEN", tt.enterpriseToken)
+			gotAuth = ""
+
+			client, err := NewGitHubClientForURL(tt.baseURL)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sha, err := client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "corp", Repo: "deploy", Ref: "v1"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sha != commitSHA {
+				t.Errorf("expected SHA %q, got %q", commitSHA, sha)
+			}
+			if gotAuth != tt.wantAuth {
+				t.Errorf("expected authorization %q, got %q", tt.wantAuth, gotAuth)
+			}
+		})
+	}
+}
+
+func TestNewGitHubClientForURL_GitHubCom(t *testing.T) {
+	for _, baseURL := range []string{"", "https://api.github.com", "https://api.github.com/"} {
+		client, err := NewGitHubClientForURL(baseURL)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", baseURL, err)
+		}
+		if got := client.(*githubClient).client.BaseURL.String(); got != "https://api.github.com/" {
+			t.Errorf("expected github.com API for %q, got %s", baseURL, got)
+		}
+	}
+}
+
+func TestEnterpriseUploadURL(t *testing.T) {
+	tests := map[string]string{
+		"https://ghes.example.com/api/v3":  "https://ghes.example.com/api/uploads/",
+		"https://ghes.example.com/api/v3/": "https://ghes.example.com/api/uploads/",
+		"https://ghes.example.com":         "https://ghes.example.com",
+	}
+	for baseURL, want := range tests {
+		if got := enterpriseUploadURL(baseURL); got != want {
+			t.Errorf("enterpriseUploadURL(%q) = %q, want %q", baseURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package ghclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// ErrFileNotFound is returned by content fetchers when a repository has no such file at the commit.
+var ErrFileNotFound = errors.New("file not found")
+
+// ContentFetcher is implemented by clients that can read a file of a repository at a commit.
+// Content at a commit SHA never changes, so fetched files can be cached without expiry.
+type ContentFetcher interface {
+	FetchFile(ctx context.Context, owner, repo, path, sha string) ([]byte, error)
+}
+
+// AsContentFetcher returns the first client in a chain of decorators that can fetch files.
+func AsContentFetcher(client GitHubClient) (ContentFetcher, bool) {
+	return find[ContentFetcher](client)
+}
+
+// FetchFile reads a file of a repository at a commit with the contents API. A missing file or
+// a path naming a directory is reported as ErrFileNotFound.
+func (g *githubClient) FetchFile(ctx context.Context, owner, repo, path, sha string) ([]byte, error) {
+	file, _, resp, err := g.client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: sha})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s/%s/%s@%s: %w", owner, repo, path, sha, ErrFileNotFound)
+		}
+		return nil, fmt.Errorf("failed to fetch %s from %s/%s@%s: %w", path, owner, repo, sha, err)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%s/%s/%s@%s: %w", owner, repo, path, sha, ErrFileNotFound)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s from %s/%s@%s: %w", path, owner, repo, sha, err)
+	}
+	return []byte(content), nil
+}
@@ -0,0 +1,88 @@
+package ghclient
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubClient_FetchFile(t *testing.T) {
+	const actionYAML = "runs:\n  using: composite\n"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/actions/cache/contents/save/action.yml", func(w http.ResponseWriter, r *http.Request) {
+		if ref := r.URL.Query().Get("ref"); ref != mainHeadSHA {
+			t.Errorf("expected ref %s, got %s", mainHeadSHA, ref)
+		}
+		_, _ = fmt.Fprintf(w, `{"type":"file","encoding":"base64","content":%q}`, base64.StdEncoding.EncodeToString([]byte(actionYAML)))
+	})
+	mux.HandleFunc("GET /api/v3/repos/actions/cache/contents/save", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[{"type":"file","name":"action.yml"}]`)
+	})
+	mux.HandleFunc("GET /api/v3/repos/actions/broken/contents/action.yml", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, `{"message":"Forbidden"}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := NewGitHubClientForURL(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fetcher, ok := AsContentFetcher(NewCachingClient(client))
+	if !ok {
+		t.Fatal("expected the REST client to fetch files")
+	}
+
+	content, err := fetcher.FetchFile(context.Background(), "actions", "cache", "save/action.yml", mainHeadSHA)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(content) != actionYAML {
+		t.Errorf("unexpected content: %q", content)
+	}
+
+	tests := []struct {
+		name    string
+		repo    string
+		path    string
+		wantErr error
+	}{
+		{name: "missing file", repo: "cache", path: "action.yaml", wantErr: ErrFileNotFound},
+		{name: "directory", repo: "cache", path: "save", wantErr: ErrFileNotFound},
+		{name: "API error", repo: "broken", path: "action.yml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fetcher.FetchFile(context.Background(), "actions", tt.repo, tt.path, mainHeadSHA)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && errors.Is(err, ErrFileNotFound) {
+				t.Errorf("expected an API error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAsContentFetcher(t *testing.T) {
+	git, err := NewGitClientForURL("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fetcher, ok := AsContentFetcher(NewCachingClient(git))
+	if !ok {
+		t.Fatal("expected the caching client to fetch files")
+	}
+	if _, err := fetcher.FetchFile(context.Background(), "actions", "checkout", "action.yml", mainHeadSHA); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
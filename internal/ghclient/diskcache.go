@@ -0,0 +1,206 @@
+package ghclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// cacheDirName is the directory created below the user cache directory.
+const cacheDirName = "github-actions-digest-pinner"
+
+// DefaultCacheTTL is how long resolved references are kept on disk unless configured otherwise.
+const DefaultCacheTTL = 24 * time.Hour
+
+// diskCacheEntry is the JSON document stored for each resolved reference or fetched file.
+type diskCacheEntry struct {
+	Key        string    `json:"key"`
+	SHA        string    `json:"sha"`
+	ResolvedAt time.Time `json:"resolved_at"`
+	Content    []byte    `json:"content,omitempty"`
+}
+
+// diskCachingClient is a GitHubClient decorator that persists resolved SHAs in a directory,
+// one file per host/owner/repo@ref, so that they survive across runs. Files fetched at a commit are
+// persisted as well, one entry per host/owner/repo/path@sha.
+type diskCachingClient struct {
+	client GitHubClient
+	dir    string
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewDiskCachingClient wraps a GitHub client with a cache stored in dir. Entries older than ttl
+// are resolved again; a ttl of zero or less keeps entries until the cache is cleaned.
+func NewDiskCachingClient(client GitHubClient, dir string, ttl time.Duration) GitHubClient {
+	return &diskCachingClient{
+		client: client,
+		dir:    dir,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// DefaultCacheDir returns the cache directory below $XDG_CACHE_HOME, or the platform's user cache directory.
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user cache directory: %w", err)
+	}
+	return filepath.Join(base, cacheDirName), nil
+}
+
+// CleanCache removes the cache directory and all entries in it.
+func CleanCache(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove cache directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// ResolveActionSHA resolves the SHA of an action reference, using the on-disk cache when an entry is fresh.
+// Failing to read or write the cache is not fatal; the reference is then resolved by the wrapped client.
+func (c *diskCachingClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	if isSHA(action.Ref) {
+		return c.client.ResolveActionSHA(ctx, action)
+	}
+
+	key := c.host(action.Owner) + "/" + action.Owner + "/" + action.Repo + "@" + action.Ref
+	file := c.path(key)
+
+	if entry, err := c.read(file); err == nil && entry.Key == key && c.fresh(entry) {
+		return entry.SHA, nil
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Warning: ignoring cache entry for %s: %v", key, err)
+	}
+
+	sha, err := c.client.ResolveActionSHA(ctx, action)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.write(file, diskCacheEntry{Key: key, SHA: sha, ResolvedAt: c.now().UTC()}); err != nil {
+		log.Printf("Warning: failed to cache %s: %v", key, err)
+	}
+	return sha, nil
+}
+
+// ListTags lists the tags of a repository using the wrapped client; tag lists are not cached on disk.
+func (c *diskCachingClient) ListTags(ctx context.Context, owner, repo string) ([]Tag, error) {
+	lister, ok := AsTagLister(c.client)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return lister.ListTags(ctx, owner, repo)
+}
+
+// FetchFile reads a file of a repository at a commit, using the on-disk cache when possible. As content
+// at a commit never changes, file entries do not expire. Missing files are not cached.
+func (c *diskCachingClient) FetchFile(ctx context.Context, owner, repo, path, sha string) ([]byte, error) {
+	fetcher, ok := AsContentFetcher(c.client)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	// The prefix keeps file keys apart from the keys of resolved references
+	key := "file:" + c.host(owner) + "/" + owner + "/" + repo + "/" + path + "@" + sha
+	file := c.path(key)
+
+	if entry, err := c.read(file); err == nil && entry.Key == key {
+		return entry.Content, nil
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Warning: ignoring cache entry for %s: %v", key, err)
+	}
+
+	content, err := fetcher.FetchFile(ctx, owner, repo, path, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.write(file, diskCacheEntry{Key: key, SHA: sha, ResolvedAt: c.now().UTC(), Content: content}); err != nil {
+		log.Printf("Warning: failed to cache %s: %v", key, err)
+	}
+	return content, nil
+}
+
+// Unwrap returns the wrapped client.
+func (c *diskCachingClient) Unwrap() GitHubClient {
+	return c.client
+}
+
+// host returns the server the references of owner are resolved against. It is part of every key, as
+// the cache directory is shared by runs against github.com and GitHub Enterprise Servers, where the
+// same owner/repo@ref may point to a different commit.
+func (c *diskCachingClient) host(owner string) string {
+	if reporter, ok := find[HostReporter](c.client); ok {
+		return reporter.Host(owner)
+	}
+	return ""
+}
+
+// path returns the file of a cache key. Keys are hashed since refs may contain slashes.
+func (c *diskCachingClient) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// fresh reports whether an entry is younger than the TTL.
+func (c *diskCachingClient) fresh(entry diskCacheEntry) bool {
+	return c.ttl <= 0 || c.now().Sub(entry.ResolvedAt) < c.ttl
+}
+
+// read loads a cache entry from file.
+func (c *diskCachingClient) read(file string) (diskCacheEntry, error) {
+	var entry diskCacheEntry
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, fmt.Errorf("failed to decode %s: %w", file, err)
+	}
+	return entry, nil
+}
+
+// write stores a cache entry. The entry is written to a temporary file and renamed, so that
+// concurrent runs never observe a partially written entry.
+func (c *diskCachingClient) write(file string, entry diskCacheEntry) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	return nil
+}
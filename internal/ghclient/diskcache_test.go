@@ -0,0 +1,316 @@
+package ghclient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+func TestDiskCachingClient_ResolveActionSHA(t *testing.T) {
+	action := types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		age       time.Duration
+		wantCalls int32
+	}{
+		{
+			name:      "fresh entry is reused",
+			ttl:       time.Hour,
+			age:       30 * time.Minute,
+			wantCalls: 1,
+		},
+		{
+			name:      "expired entry is resolved again",
+			ttl:       time.Hour,
+			age:       2 * time.Hour,
+			wantCalls: 2,
+		},
+		{
+			name:      "zero ttl never expires",
+			ttl:       0,
+			age:       365 * 24 * time.Hour,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			inner := &countingClient{}
+
+			// A first client populates the cache, a second one started later reads it
+			first := NewDiskCachingClient(inner, dir, tt.ttl).(*diskCachingClient)
+			first.now = func() time.Time { return now }
+			if _, err := first.ResolveActionSHA(context.Background(), action); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			second := NewDiskCachingClient(inner, dir, tt.ttl).(*diskCachingClient)
+			second.now = func() time.Time { return now.Add(tt.age) }
+			sha, err := second.ResolveActionSHA(context.Background(), action)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sha != "sha-v4" {
+				t.Errorf("expected sha-v4, got %s", sha)
+			}
+			if got := inner.calls.Load(); got != tt.wantCalls {
+				t.Errorf("expected %d calls to the wrapped client, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestDiskCachingClient_Keys(t *testing.T) {
+	dir := t.TempDir()
+	inner := &countingClient{}
+	client := NewDiskCachingClient(inner, dir, time.Hour)
+
+	refs := []types.ActionRef{
+		{Owner: "actions", Repo: "checkout", Ref: "v4"},
+		{Owner: "actions", Repo: "checkout", Ref: "release/v4"},
+		{Owner: "actions", Repo: "cache", Path: "save", Ref: "v4"},
+		{Owner: "actions", Repo: "cache", Path: "restore", Ref: "v4"},
+		{Owner: "actions", Repo: "checkout", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675"},
+	}
+	for _, ref := range refs {
+		if _, err := client.ResolveActionSHA(context.Background(), ref); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read cache directory: %v", err)
+	}
+	// Paths share an entry and SHAs are never stored
+	if len(entries) != 3 {
+		t.Errorf("expected 3 cache entries, got %d", len(entries))
+	}
+	if got := inner.calls.Load(); got != 4 {
+		t.Errorf("expected 4 calls to the wrapped client, got %d", got)
+	}
+}
+
+// hostedClient resolves references of a fixed server to SHAs naming that server
+type hostedClient struct {
+	countingClient
+	host string
+}
+
+func (c *hostedClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	c.calls.Add(1)
+	return c.host + "-" + action.Ref, nil
+}
+
+func (c *hostedClient) Host(owner string) string {
+	return c.host
+}
+
+func TestDiskCachingClient_Hosts(t *testing.T) {
+	dir := t.TempDir()
+	action := types.ActionRef{Owner: "myorg", Repo: "deploy", Ref: "v1"}
+	cloud := &hostedClient{host: "github.com"}
+	ghes := &hostedClient{host: "ghes.example.com"}
+
+	// A run against github.com must not serve its SHAs to a run against a GitHub Enterprise Server
+	for range 2 {
+		for _, inner := range []*hostedClient{cloud, ghes} {
+			sha, err := NewDiskCachingClient(inner, dir, time.Hour).ResolveActionSHA(context.Background(), action)
+			if err != nil || sha != inner.host+"-v1" {
+				t.Fatalf("unexpected result for %s: %q, %v", inner.host, sha, err)
+			}
+		}
+	}
+	if cloud.calls.Load() != 1 || ghes.calls.Load() != 1 {
+		t.Errorf("expected 1 call to each server, got %d and %d", cloud.calls.Load(), ghes.calls.Load())
+	}
+
+	// Routed owners are cached under the host of their route
+	routed := NewDiskCachingClient(NewRoutingClient(cloud, map[string]GitHubClient{"myorg": ghes}), dir, time.Hour)
+	if sha, err := routed.ResolveActionSHA(context.Background(), action); err != nil || sha != "ghes.example.com-v1" {
+		t.Errorf("unexpected result: %q, %v", sha, err)
+	}
+	if ghes.calls.Load() != 1 {
+		t.Errorf("expected the routed owner to be served from the cache, got %d calls", ghes.calls.Load())
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		newFunc  func(string) (GitHubClient, error)
+		apiURL   string
+		expected string
+	}{
+		{name: "api github.com", newFunc: NewGitHubClientForURL, apiURL: "", expected: "github.com"},
+		{name: "api enterprise", newFunc: NewGitHubClientForURL, apiURL: "https://ghes.example.com/api/v3", expected: "ghes.example.com"},
+		{name: "git github.com", newFunc: NewGitClientForURL, apiURL: "", expected: "github.com"},
+		{name: "git enterprise", newFunc: NewGitClientForURL, apiURL: "https://ghes.example.com/api/v3", expected: "ghes.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := tt.newFunc(tt.apiURL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			reporter, ok := find[HostReporter](NewCachingClient(client))
+			if !ok {
+				t.Fatal("expected a host reporter")
+			}
+			if got := reporter.Host("actions"); got != tt.expected {
+				t.Errorf("expected host %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDiskCachingClient_Errors(t *testing.T) {
+	action := types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"}
+
+	t.Run("failed lookups are not cached", func(t *testing.T) {
+		dir := t.TempDir()
+		inner := &countingClient{err: errors.New("boom")}
+		client := NewDiskCachingClient(inner, dir, time.Hour)
+
+		for range 2 {
+			if _, err := client.ResolveActionSHA(context.Background(), action); err == nil {
+				t.Fatal("expected an error")
+			}
+		}
+		if got := inner.calls.Load(); got != 2 {
+			t.Errorf("expected 2 calls to the wrapped client, got %d", got)
+		}
+	})
+
+	t.Run("corrupt entries are ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		inner := &countingClient{}
+		client := NewDiskCachingClient(inner, dir, time.Hour).(*diskCachingClient)
+
+		if err := os.WriteFile(client.path("actions/checkout@v4"), []byte("{not json"), 0o644); err != nil {
+			t.Fatalf("failed to write entry: %v", err)
+		}
+
+		sha, err := client.ResolveActionSHA(context.Background(), action)
+		if err != nil || sha != "sha-v4" {
+			t.Fatalf("unexpected result: %q, %v", sha, err)
+		}
+		if got := inner.calls.Load(); got != 1 {
+			t.Errorf("expected 1 call to the wrapped client, got %d", got)
+		}
+
+		// The corrupt entry has been replaced
+		if _, err := client.ResolveActionSHA(context.Background(), action); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := inner.calls.Load(); got != 1 {
+			t.Errorf("expected the repaired entry to be used, got %d calls", got)
+		}
+	})
+
+	t.Run("unwritable cache directory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		client := NewDiskCachingClient(&countingClient{}, filepath.Join(file, "cache"), time.Hour)
+
+		sha, err := client.ResolveActionSHA(context.Background(), action)
+		if err != nil || sha != "sha-v4" {
+			t.Fatalf("unexpected result: %q, %v", sha, err)
+		}
+	})
+}
+
+func TestDiskCachingClient_ListTags(t *testing.T) {
+	client := NewDiskCachingClient(&countingClient{}, t.TempDir(), time.Hour)
+	if _, err := client.(TagLister).ListTags(context.Background(), "actions", "checkout"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+
+	inner := &countingTagLister{}
+	client = NewCachingClient(NewDiskCachingClient(inner, t.TempDir(), time.Hour))
+	lister, ok := AsTagLister(client)
+	if !ok {
+		t.Fatal("expected a tag lister")
+	}
+	tags, err := lister.ListTags(context.Background(), "actions", "checkout")
+	if err != nil || len(tags) != 1 {
+		t.Fatalf("unexpected result: %v, %v", tags, err)
+	}
+}
+
+func TestDiskCachingClient_FetchFile(t *testing.T) {
+	dir := t.TempDir()
+	inner := &countingFetcher{}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	first := NewDiskCachingClient(inner, dir, time.Hour).(*diskCachingClient)
+	first.now = func() time.Time { return now }
+	if _, err := first.FetchFile(context.Background(), "actions", "cache", "action.yml", "sha-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := first.FetchFile(context.Background(), "actions", "cache", "missing.yml", "sha-1"); !errors.Is(err, ErrFileNotFound) {
+		t.Fatalf("expected ErrFileNotFound, got %v", err)
+	}
+
+	// File entries outlive the TTL, and missing files are fetched again
+	second := NewDiskCachingClient(inner, dir, time.Hour).(*diskCachingClient)
+	second.now = func() time.Time { return now.Add(365 * 24 * time.Hour) }
+	content, err := second.FetchFile(context.Background(), "actions", "cache", "action.yml", "sha-1")
+	if err != nil || string(content) != "actions/cache/action.yml@sha-1" {
+		t.Fatalf("unexpected result: %q, %v", content, err)
+	}
+	_, _ = second.FetchFile(context.Background(), "actions", "cache", "missing.yml", "sha-1")
+	if got := inner.fileCalls.Load(); got != 3 {
+		t.Errorf("expected 3 calls to the wrapped client, got %d", got)
+	}
+
+	client := NewDiskCachingClient(&countingClient{}, t.TempDir(), time.Hour)
+	if _, err := client.(ContentFetcher).FetchFile(context.Background(), "actions", "cache", "action.yml", "sha-1"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestCleanCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	client := NewDiskCachingClient(&countingClient{}, dir, time.Hour)
+	if _, err := client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := CleanCache(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected cache directory to be removed, got %v", err)
+	}
+
+	// Cleaning a missing cache is not an error
+	if err := CleanCache(dir); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDefaultCacheDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/tmp/xdg")
+	t.Setenv("HOME", "/tmp/home")
+
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Base(dir) != cacheDirName {
+		t.Errorf("expected cache directory to end in %s, got %s", cacheDirName, dir)
+	}
+}
@@ -0,0 +1,221 @@
+package ghclient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+const (
+	// gitHubWebURL is where github.com serves git repositories.
+	gitHubWebURL = "https://github.com"
+	// uploadPackAdvertisement is the content type of a smart-HTTP ref advertisement.
+	uploadPackAdvertisement = "application/x-git-upload-pack-advertisement"
+	// peeledSuffix marks the commit an annotated tag points to in a ref advertisement.
+	peeledSuffix = "^{}"
+)
+
+// gitClient resolves references with git's smart-HTTP protocol, like git ls-remote. A single
+// request returns all tags and branches of a repository and does not count against the API quota.
+type gitClient struct {
+	baseURL string
+	client  *http.Client
+	token   string
+
+	mu   sync.Mutex
+	refs map[string]*cacheEntry[map[string]string]
+}
+
+// NewGitClientForURL creates a client that resolves references over git's smart-HTTP protocol
+// from the server of the GitHub API at apiURL, e.g. https://github.com for https://api.github.com.
+// Requests are authenticated with the same token as NewGitHubClientForURL, if any.
+func NewGitClientForURL(apiURL string) (GitHubClient, error) {
+	enterprise, err := isEnterpriseURL(apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := gitHubWebURL
+	if enterprise {
+		baseURL = strings.TrimSuffix(strings.TrimSuffix(apiURL, "/"), "/api/v3")
+	}
+
+	token := tokenFromEnv(enterprise)
+	return &gitClient{
+		baseURL: baseURL,
+		client:  &http.Client{Transport: newRetryTransport(http.DefaultTransport, token != "")},
+		token:   token,
+		refs:    make(map[string]*cacheEntry[map[string]string]),
+	}, nil
+}
+
+// Host returns the host of the git server.
+func (g *gitClient) Host(owner string) string {
+	u, err := url.Parse(g.baseURL)
+	if err != nil {
+		return g.baseURL
+	}
+	return u.Hostname()
+}
+
+// ResolveActionSHA resolves the commit SHA of a tag or branch. Annotated tags resolve to
+// the commit they point to, like the REST API client.
+func (g *gitClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	if isSHA(action.Ref) {
+		return action.Ref, nil
+	}
+
+	refs, err := g.lsRemote(ctx, action.Owner, action.Repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve ref %s: %w", action.Ref, err)
+	}
+
+	for _, name := range []string{"refs/tags/" + action.Ref + peeledSuffix, "refs/tags/" + action.Ref, "refs/heads/" + action.Ref} {
+		if sha, ok := refs[name]; ok {
+			return sha, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to resolve ref %s: not found in %s/%s", action.Ref, action.Owner, action.Repo)
+}
+
+// ListTags lists all tags of a repository together with the commit SHA they point to.
+func (g *gitClient) ListTags(ctx context.Context, owner, repo string) ([]Tag, error) {
+	refs, err := g.lsRemote(ctx, owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s/%s: %w", owner, repo, err)
+	}
+
+	var tags []Tag
+	for name, sha := range refs {
+		tag, ok := strings.CutPrefix(name, "refs/tags/")
+		if !ok || strings.HasSuffix(tag, peeledSuffix) {
+			continue
+		}
+		if peeled, ok := refs[name+peeledSuffix]; ok {
+			sha = peeled
+		}
+		tags = append(tags, Tag{Name: tag, SHA: sha})
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// lsRemote returns the refs advertised for a repository, fetching them once per repository.
+func (g *gitClient) lsRemote(ctx context.Context, owner, repo string) (map[string]string, error) {
+	return load(ctx, &g.mu, g.refs, owner+"/"+repo, func() (map[string]string, error) {
+		return g.fetchRefs(ctx, owner, repo)
+	})
+}
+
+// fetchRefs requests the ref advertisement of git-upload-pack for a repository.
+func (g *gitClient) fetchRefs(ctx context.Context, owner, repo string) (map[string]string, error) {
+	url := fmt.Sprintf("%s/%s/%s.git/info/refs?service=git-upload-pack", g.baseURL, owner, repo)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if g.token != "" {
+		req.SetBasicAuth("x-access-token", g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch refs of %s/%s: %w", owner, repo, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch refs of %s/%s: %s", owner, repo, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, uploadPackAdvertisement) {
+		return nil, fmt.Errorf("failed to fetch refs of %s/%s: unexpected content type %q", owner, repo, contentType)
+	}
+
+	refs, err := parseAdvertisement(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse refs of %s/%s: %w", owner, repo, err)
+	}
+	return refs, nil
+}
+
+// parseAdvertisement parses a smart-HTTP ref advertisement: a "# service=git-upload-pack" packet and
+// a flush packet, followed by one "<sha> <ref>" packet per ref, the first of which carries the server
+// capabilities after a NUL byte, and a final flush packet.
+func parseAdvertisement(r io.Reader) (map[string]string, error) {
+	reader := bufio.NewReader(r)
+
+	service, err := readPacket(reader)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(service, "\n") != "# service=git-upload-pack" {
+		return nil, fmt.Errorf("unexpected service announcement %q", service)
+	}
+	if flush, err := readPacket(reader); err != nil || flush != "" {
+		return nil, errors.New("missing flush packet after service announcement")
+	}
+
+	refs := make(map[string]string)
+	for {
+		line, err := readPacket(reader)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			return refs, nil
+		}
+
+		line, _, _ = strings.Cut(strings.TrimSuffix(line, "\n"), "\x00")
+		sha, name, ok := strings.Cut(line, " ")
+		if !ok || !isSHA(sha) {
+			return nil, fmt.Errorf("invalid ref line %q", line)
+		}
+		// An empty repository advertises its capabilities on a placeholder ref
+		if name == "capabilities"+peeledSuffix {
+			continue
+		}
+		refs[name] = sha
+	}
+}
+
+// readPacket reads a pkt-line. A flush packet is returned as an empty string.
+func readPacket(r *bufio.Reader) (string, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", fmt.Errorf("failed to read packet length: %w", err)
+	}
+
+	length, err := strconv.ParseUint(string(header[:]), 16, 16)
+	if err != nil {
+		return "", fmt.Errorf("invalid packet length %q", header)
+	}
+	if length == 0 {
+		return "", nil
+	}
+	if length < 4 {
+		return "", fmt.Errorf("invalid packet length %d", length)
+	}
+
+	data := make([]byte, length-4)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", fmt.Errorf("failed to read packet: %w", err)
+	}
+	if msg, ok := strings.CutPrefix(string(data), "ERR "); ok {
+		return "", fmt.Errorf("server error: %s", strings.TrimSpace(msg))
+	}
+	return string(data), nil
+}
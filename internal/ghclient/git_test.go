@@ -0,0 +1,241 @@
+package ghclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// pktLine encodes data as a git pkt-line
+func pktLine(data string) string {
+	return fmt.Sprintf("%04x%s", len(data)+4, data)
+}
+
+// advertisement builds a smart-HTTP ref advertisement of the given "<sha> <ref>" lines
+func advertisement(lines ...string) string {
+	var b strings.Builder
+	b.WriteString(pktLine("# service=git-upload-pack\n"))
+	b.WriteString("0000")
+	for i, line := range lines {
+		if i == 0 {
+			line += "\x00multi_ack thin-pack side-band symref=HEAD:refs/heads/main"
+		}
+		b.WriteString(pktLine(line + "\n"))
+	}
+	b.WriteString("0000")
+	return b.String()
+}
+
+const (
+	gitCommitSHA = "cccccccccccccccccccccccccccccccccccccccc"
+	gitTagSHA    = "dddddddddddddddddddddddddddddddddddddddd"
+	gitBranchSHA = "eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+)
+
+// newGitTestServer serves the ref advertisement of actions/checkout and counts the requests
+func newGitTestServer(t *testing.T, wantAuth string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/actions/checkout.git/info/refs" || r.URL.Query().Get("service") != "git-upload-pack" {
+			http.NotFound(w, r)
+			return
+		}
+		if user, pass, _ := r.BasicAuth(); wantAuth != "" && user+":"+pass != wantAuth {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		_, _ = fmt.Fprint(w, advertisement(
+			gitBranchSHA+" HEAD",
+			gitBranchSHA+" refs/heads/main",
+			gitCommitSHA+" refs/heads/v1",
+			gitCommitSHA+" refs/tags/v4",
+			gitTagSHA+" refs/tags/v4.2.0",
+			gitCommitSHA+" refs/tags/v4.2.0^{}",
+		))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestGitClient_ResolveActionSHA(t *testing.T) {
+	server, requests := newGitTestServer(t, "")
+	client, err := NewGitClientForURL(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		repo    string
+		ref     string
+		wantSHA string
+		wantErr bool
+	}{
+		{name: "lightweight tag", repo: "checkout", ref: "v4", wantSHA: gitCommitSHA},
+		{name: "annotated tag is peeled", repo: "checkout", ref: "v4.2.0", wantSHA: gitCommitSHA},
+		{name: "branch", repo: "checkout", ref: "main", wantSHA: gitBranchSHA},
+		{name: "tags win over branches", repo: "checkout", ref: "v4", wantSHA: gitCommitSHA},
+		{name: "already a SHA", repo: "checkout", ref: gitTagSHA, wantSHA: gitTagSHA},
+		{name: "unknown ref", repo: "checkout", ref: "v999", wantErr: true},
+		{name: "unknown repository", repo: "missing", ref: "v1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sha, err := client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "actions", Repo: tt.repo, Ref: tt.ref})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sha != tt.wantSHA {
+				t.Errorf("expected SHA %q, got %q", tt.wantSHA, sha)
+			}
+		})
+	}
+
+	// All refs of a repository are fetched with a single request
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected 2 requests, got %d", got)
+	}
+}
+
+func TestGitClient_ListTags(t *testing.T) {
+	server, _ := newGitTestServer(t, "")
+	client, err := NewGitClientForURL(server.URL + "/api/v3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tags, err := client.(TagLister).ListTags(context.Background(), "actions", "checkout")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Tag{{Name: "v4", SHA: gitCommitSHA}, {Name: "v4.2.0", SHA: gitCommitSHA}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("expected tags %v, got %v", want, tags)
+	}
+}
+
+func TestGitClient_Authentication(t *testing.T) {
+	server, _ := newGitTestServer(t, "x-access-token:secret")
+	action := types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "")
+	client, err := NewGitClientForURL(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.ResolveActionSHA(context.Background(), action); err == nil {
+		t.Error("expected an error without token")
+	}
+
+	t.Setenv("GITHUB_TOKEN", "secret")
+	client, err = NewGitClientForURL(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.ResolveActionSHA(context.Background(), action); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewGitClientForURL(t *testing.T) {
+	tests := map[string]string{
+		"":                                 "https://github.com",
+		"https://api.github.com":           "https://github.com",
+		"https://ghes.example.com/api/v3":  "https://ghes.example.com",
+		"https://ghes.example.com/api/v3/": "https://ghes.example.com",
+		"https://ghes.example.com":         "https://ghes.example.com",
+	}
+	for apiURL, want := range tests {
+		client, err := NewGitClientForURL(apiURL)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", apiURL, err)
+		}
+		if got := client.(*gitClient).baseURL; got != want {
+			t.Errorf("NewGitClientForURL(%q) uses %s, want %s", apiURL, got, want)
+		}
+	}
+
+	if _, err := NewGitClientForURL("ghes.example.com"); err == nil {
+		t.Error("expected error for an invalid URL")
+	}
+}
+
+func TestParseAdvertisement(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "refs",
+			body: advertisement(gitCommitSHA+" refs/tags/v1", gitTagSHA+" refs/tags/v2", gitCommitSHA+" refs/tags/v2^{}"),
+			want: map[string]string{"refs/tags/v1": gitCommitSHA, "refs/tags/v2": gitTagSHA, "refs/tags/v2^{}": gitCommitSHA},
+		},
+		{
+			name: "empty repository",
+			body: advertisement("0000000000000000000000000000000000000000 capabilities^{}"),
+			want: map[string]string{},
+		},
+		{
+			name:    "missing service announcement",
+			body:    pktLine(gitCommitSHA+" refs/tags/v1\n") + "0000",
+			wantErr: true,
+		},
+		{
+			name:    "server error",
+			body:    pktLine("# service=git-upload-pack\n") + "0000" + pktLine("ERR access denied\n"),
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			body:    pktLine("# service=git-upload-pack\n") + "0000" + "0040abc",
+			wantErr: true,
+		},
+		{
+			name:    "invalid line",
+			body:    advertisement("not-a-sha refs/tags/v1"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := parseAdvertisement(strings.NewReader(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(refs, tt.want) {
+				t.Errorf("expected refs %v, got %v", tt.want, refs)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package ghclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+const (
+	// graphqlMaxBatch is the maximum number of references resolved by a single query.
+	graphqlMaxBatch = 100
+	// graphqlBatchWindow is how long a lookup waits for others to join its batch.
+	graphqlBatchWindow = 10 * time.Millisecond
+)
+
+// graphqlRefFragment selects the commit a ref points to, peeling one level of annotated tags.
+const graphqlRefFragment = `fragment target on Ref { target { __typename oid ... on Tag { target { __typename oid } } } }`
+
+// graphqlClient resolves references through the GraphQL API. Lookups made at about the same time,
+// e.g. by the updater's workers, are combined into a single aliased query. References the query
+// cannot resolve are resolved with the REST API client it wraps, which also lists tags.
+type graphqlClient struct {
+	rest     *githubClient
+	endpoint string
+	window   time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	pending []*graphqlLookup
+}
+
+// graphqlLookup is a reference waiting to be resolved by a batch.
+type graphqlLookup struct {
+	action types.ActionRef
+	done   chan struct{}
+	sha    string
+	err    error
+}
+
+// NewGraphQLClient creates a client that resolves references in batches through the GraphQL API
+// of the GitHub server rest talks to, reusing its authentication. rest must be a client created by
+// NewGitHubClientForURL or NewGitHubAppClient; it is used for everything the GraphQL API cannot resolve.
+func NewGraphQLClient(rest GitHubClient) (GitHubClient, error) {
+	client, ok := rest.(*githubClient)
+	if !ok {
+		return nil, errors.New("the GraphQL resolver requires a REST API client")
+	}
+
+	// https://api.github.com/graphql on github.com, https://<host>/api/graphql on Enterprise Server
+	endpoint := client.client.BaseURL.String()
+	if base, ok := strings.CutSuffix(endpoint, "/v3/"); ok {
+		endpoint = base + "/graphql"
+	} else {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/graphql"
+	}
+
+	return &graphqlClient{
+		rest:     client,
+		endpoint: endpoint,
+		window:   graphqlBatchWindow,
+		maxBatch: graphqlMaxBatch,
+	}, nil
+}
+
+// ResolveActionSHA resolves the commit SHA of a tag or branch as part of the next batch.
+func (g *graphqlClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	if isSHA(action.Ref) {
+		return action.Ref, nil
+	}
+
+	lookup := &graphqlLookup{action: action, done: make(chan struct{})}
+
+	g.mu.Lock()
+	g.pending = append(g.pending, lookup)
+	switch {
+	case len(g.pending) >= g.maxBatch:
+		batch := g.pending
+		g.pending = nil
+		go g.resolveBatch(ctx, batch)
+	case len(g.pending) == 1:
+		time.AfterFunc(g.window, func() {
+			g.mu.Lock()
+			batch := g.pending
+			g.pending = nil
+			g.mu.Unlock()
+			g.resolveBatch(ctx, batch)
+		})
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-lookup.done:
+		return lookup.sha, lookup.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// detach returns a context for a batch started by the lookup with ctx. It keeps the deadline
+// of ctx but is not canceled with it, since other lookups may have joined the batch.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return context.WithCancel(detached)
+}
+
+// ListTags lists the tags of a repository using the REST API.
+func (g *graphqlClient) ListTags(ctx context.Context, owner, repo string) ([]Tag, error) {
+	return g.rest.ListTags(ctx, owner, repo)
+}
+
+// Unwrap returns the REST API client.
+func (g *graphqlClient) Unwrap() GitHubClient {
+	return g.rest
+}
+
+// graphqlResponse is the response to a batched query; each alias holds a repository or null.
+type graphqlResponse struct {
+	Data   map[string]*graphqlRepository `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+		Path    []any  `json:"path"`
+	} `json:"errors"`
+}
+
+// graphqlRepository holds the tag and branch of the requested name.
+type graphqlRepository struct {
+	Tag    *graphqlRef `json:"t"`
+	Branch *graphqlRef `json:"h"`
+}
+
+// graphqlRef is a ref and the object it points to.
+type graphqlRef struct {
+	Target graphqlObject `json:"target"`
+}
+
+// graphqlObject is a git object; for annotated tags, Target is the tagged object.
+type graphqlObject struct {
+	Typename string         `json:"__typename"`
+	OID      string         `json:"oid"`
+	Target   *graphqlObject `json:"target"`
+}
+
+// resolveBatch resolves a batch of lookups with a single query and completes them. Lookups
+// the query fails to resolve fall back to the REST API. ctx is the context of the lookup
+// that started the batch. If the REST client authenticates per owner, e.g. as a GitHub App
+// with several installations, one query is sent per owner with that owner's credentials.
+func (g *graphqlClient) resolveBatch(ctx context.Context, batch []*graphqlLookup) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := detach(ctx)
+	defer cancel()
+
+	if !g.rest.ownerScoped {
+		g.resolve(ctx, batch)
+		return
+	}
+
+	var owners []string
+	groups := make(map[string][]*graphqlLookup)
+	for _, lookup := range batch {
+		owner := strings.ToLower(lookup.action.Owner)
+		if _, ok := groups[owner]; !ok {
+			owners = append(owners, owner)
+		}
+		groups[owner] = append(groups[owner], lookup)
+	}
+
+	var wg sync.WaitGroup
+	for _, owner := range owners {
+		group := groups[owner]
+		wg.Go(func() {
+			g.resolve(withRepository(ctx, group[0].action.Owner, group[0].action.Repo), group)
+		})
+	}
+	wg.Wait()
+}
+
+// resolve resolves lookups with a single query and completes them, falling back to the REST API
+// for the ones the query fails to resolve.
+func (g *graphqlClient) resolve(ctx context.Context, batch []*graphqlLookup) {
+	resp, err := g.query(ctx, batch)
+	if err != nil {
+		log.Printf("Warning: GraphQL query for %d references failed, falling back to the REST API: %v", len(batch), err)
+	}
+
+	aliasErrors := make(map[string]string)
+	if resp != nil {
+		for _, e := range resp.Errors {
+			if len(e.Path) > 0 {
+				if alias, ok := e.Path[0].(string); ok {
+					aliasErrors[alias] = e.Message
+				}
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, lookup := range batch {
+		alias := fmt.Sprintf("r%d", i)
+		if resp != nil {
+			if sha, ok := commitSHA(resp.Data[alias]); ok {
+				lookup.sha = sha
+				close(lookup.done)
+				continue
+			}
+		}
+		if msg, ok := aliasErrors[alias]; ok {
+			log.Printf("GraphQL could not resolve %s (%s), falling back to the REST API", lookup.action, msg)
+		}
+
+		wg.Go(func() {
+			lookup.sha, lookup.err = g.rest.ResolveActionSHA(ctx, lookup.action)
+			close(lookup.done)
+		})
+	}
+	wg.Wait()
+}
+
+// commitSHA returns the commit a repository's tag, or else its branch, points to. Nested
+// annotated tags are not peeled by the query and are left to the REST API.
+func commitSHA(repo *graphqlRepository) (string, bool) {
+	if repo == nil {
+		return "", false
+	}
+
+	ref := repo.Tag
+	if ref == nil {
+		ref = repo.Branch
+	}
+	if ref == nil {
+		return "", false
+	}
+
+	object := ref.Target
+	if object.Typename == "Tag" {
+		if object.Target == nil {
+			return "", false
+		}
+		object = *object.Target
+	}
+	if object.Typename != "Commit" || !isSHA(object.OID) {
+		return "", false
+	}
+	return object.OID, true
+}
+
+// query sends one aliased query for all lookups of a batch. Values are passed as variables.
+func (g *graphqlClient) query(ctx context.Context, batch []*graphqlLookup) (*graphqlResponse, error) {
+	var params, fields []string
+	variables := make(map[string]string, 4*len(batch))
+	for i, lookup := range batch {
+		params = append(params, fmt.Sprintf("$o%[1]d: String!, $n%[1]d: String!, $t%[1]d: String!, $h%[1]d: String!", i))
+		fields = append(fields, fmt.Sprintf("r%[1]d: repository(owner: $o%[1]d, name: $n%[1]d) { t: ref(qualifiedName: $t%[1]d) { ...target } h: ref(qualifiedName: $h%[1]d) { ...target } }", i))
+		variables[fmt.Sprintf("o%d", i)] = lookup.action.Owner
+		variables[fmt.Sprintf("n%d", i)] = lookup.action.Repo
+		variables[fmt.Sprintf("t%d", i)] = "refs/tags/" + lookup.action.Ref
+		variables[fmt.Sprintf("h%d", i)] = "refs/heads/" + lookup.action.Ref
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"query":     fmt.Sprintf("query(%s) { %s } %s", strings.Join(params, ", "), strings.Join(fields, " "), graphqlRefFragment),
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := g.rest.client.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send query: %w", err)
+	}
+	defer func() {
+		_ = httpResp.Body.Close()
+	}()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", httpResp.Status)
+	}
+
+	var resp graphqlResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.Data == nil && len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("query failed: %s", resp.Errors[0].Message)
+	}
+	return &resp, nil
+}
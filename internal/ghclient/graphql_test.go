@@ -0,0 +1,234 @@
+package ghclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+const (
+	graphqlCommitSHA = "cccccccccccccccccccccccccccccccccccccccc"
+	graphqlRESTSHA   = "ffffffffffffffffffffffffffffffffffffffff"
+)
+
+// graphqlRefs are the refs known to the fake GraphQL API, as JSON ref objects by owner/repo@qualifiedName
+var graphqlRefs = map[string]string{
+	"actions/checkout@refs/tags/v4":     `{"target":{"__typename":"Commit","oid":"` + graphqlCommitSHA + `"}}`,
+	"actions/checkout@refs/tags/v4.2.0": `{"target":{"__typename":"Tag","oid":"dddddddddddddddddddddddddddddddddddddddd","target":{"__typename":"Commit","oid":"` + graphqlCommitSHA + `"}}}`,
+	"actions/checkout@refs/heads/main":  `{"target":{"__typename":"Commit","oid":"` + graphqlCommitSHA + `"}}`,
+	"actions/checkout@refs/tags/nested": `{"target":{"__typename":"Tag","oid":"dddddddddddddddddddddddddddddddddddddddd","target":{"__typename":"Tag","oid":"eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"}}}`,
+}
+
+// fakeGraphQLServer answers batched ref queries from graphqlRefs and REST ref lookups with graphqlRESTSHA
+type fakeGraphQLServer struct {
+	queries  atomic.Int32
+	rest     atomic.Int32
+	failures bool
+}
+
+func (f *fakeGraphQLServer) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		f.queries.Add(1)
+		if f.failures {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode query: %v", err)
+			return
+		}
+		if !strings.Contains(req.Query, "fragment target on Ref") {
+			t.Errorf("unexpected query %q", req.Query)
+		}
+
+		var data, errs []string
+		for i := 0; ; i++ {
+			owner, ok := req.Variables[fmt.Sprintf("o%d", i)]
+			if !ok {
+				break
+			}
+			repo := owner + "/" + req.Variables[fmt.Sprintf("n%d", i)]
+			if repo == "actions/missing" {
+				data = append(data, fmt.Sprintf(`"r%d":null`, i))
+				errs = append(errs, fmt.Sprintf(`{"type":"NOT_FOUND","path":["r%d"],"message":"Could not resolve to a Repository"}`, i))
+				continue
+			}
+
+			tag, branch := "null", "null"
+			if ref, ok := graphqlRefs[repo+"@"+req.Variables[fmt.Sprintf("t%d", i)]]; ok {
+				tag = ref
+			}
+			if ref, ok := graphqlRefs[repo+"@"+req.Variables[fmt.Sprintf("h%d", i)]]; ok {
+				branch = ref
+			}
+			data = append(data, fmt.Sprintf(`"r%d":{"t":%s,"h":%s}`, i, tag, branch))
+		}
+
+		_, _ = fmt.Fprintf(w, `{"data":{%s},"errors":[%s]}`, strings.Join(data, ","), strings.Join(errs, ","))
+	})
+	mux.HandleFunc("GET /api/v3/repos/actions/{repo}/git/ref/{kind}/{ref}", func(w http.ResponseWriter, r *http.Request) {
+		f.rest.Add(1)
+		if r.PathValue("repo") == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"object":{"type":"commit","sha":%q}}`, graphqlRESTSHA)
+	})
+	return mux
+}
+
+// newGraphQLTestClient returns a GraphQL client talking to a fake Enterprise Server
+func newGraphQLTestClient(t *testing.T, fake *fakeGraphQLServer) *graphqlClient {
+	t.Helper()
+
+	server := httptest.NewServer(fake.handler(t))
+	t.Cleanup(server.Close)
+
+	rest, err := NewGitHubClientForURL(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client, err := NewGraphQLClient(rest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return client.(*graphqlClient)
+}
+
+func TestGraphQLClient_ResolveActionSHA(t *testing.T) {
+	tests := []struct {
+		name     string
+		repo     string
+		ref      string
+		wantSHA  string
+		wantREST int32
+		wantErr  bool
+	}{
+		{name: "lightweight tag", repo: "checkout", ref: "v4", wantSHA: graphqlCommitSHA},
+		{name: "annotated tag", repo: "checkout", ref: "v4.2.0", wantSHA: graphqlCommitSHA},
+		{name: "branch", repo: "checkout", ref: "main", wantSHA: graphqlCommitSHA},
+		{name: "already a SHA", repo: "checkout", ref: graphqlRESTSHA, wantSHA: graphqlRESTSHA},
+		{name: "nested annotated tag falls back to REST", repo: "checkout", ref: "nested", wantSHA: graphqlRESTSHA, wantREST: 1},
+		{name: "unknown ref falls back to REST", repo: "checkout", ref: "v999", wantSHA: graphqlRESTSHA, wantREST: 1},
+		{name: "alias error falls back to REST", repo: "missing", ref: "v1", wantREST: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeGraphQLServer{}
+			client := newGraphQLTestClient(t, fake)
+
+			sha, err := client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "actions", Repo: tt.repo, Ref: tt.ref})
+			if got := fake.rest.Load(); got != tt.wantREST {
+				t.Errorf("expected %d REST requests, got %d", tt.wantREST, got)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sha != tt.wantSHA {
+				t.Errorf("expected SHA %q, got %q", tt.wantSHA, sha)
+			}
+		})
+	}
+}
+
+func TestGraphQLClient_Batching(t *testing.T) {
+	refs := []string{"v4", "v4.2.0", "main", "nested", "v999"}
+
+	tests := []struct {
+		name        string
+		maxBatch    int
+		failures    bool
+		wantQueries int32
+		wantREST    int32
+	}{
+		{name: "single batch", maxBatch: 100, wantQueries: 1, wantREST: 2},
+		{name: "batches are limited in size", maxBatch: 2, wantQueries: 3, wantREST: 2},
+		{name: "failed query falls back to REST", maxBatch: 100, failures: true, wantQueries: 1, wantREST: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeGraphQLServer{failures: tt.failures}
+			client := newGraphQLTestClient(t, fake)
+			client.maxBatch = tt.maxBatch
+			client.window = 100 * time.Millisecond
+
+			var wg sync.WaitGroup
+			for _, ref := range refs {
+				wg.Go(func() {
+					if _, err := client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "actions", Repo: "checkout", Ref: ref}); err != nil {
+						t.Errorf("unexpected error for %s: %v", ref, err)
+					}
+				})
+			}
+			wg.Wait()
+
+			if got := fake.queries.Load(); got != tt.wantQueries {
+				t.Errorf("expected %d queries, got %d", tt.wantQueries, got)
+			}
+			if got := fake.rest.Load(); got != tt.wantREST {
+				t.Errorf("expected %d REST requests, got %d", tt.wantREST, got)
+			}
+		})
+	}
+}
+
+func TestGraphQLClient_ContextCanceled(t *testing.T) {
+	client := newGraphQLTestClient(t, &fakeGraphQLServer{})
+	client.window = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ResolveActionSHA(ctx, types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"}); err == nil {
+		t.Error("expected an error for a canceled context")
+	}
+}
+
+func TestNewGraphQLClient(t *testing.T) {
+	tests := map[string]string{
+		"":                                "https://api.github.com/graphql",
+		"https://ghes.example.com/api/v3": "https://ghes.example.com/api/graphql",
+	}
+	for apiURL, want := range tests {
+		rest, err := NewGitHubClientForURL(apiURL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		client, err := NewGraphQLClient(rest)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := client.(*graphqlClient).endpoint; got != want {
+			t.Errorf("expected endpoint %s for %q, got %s", want, apiURL, got)
+		}
+		if _, ok := AsRateReporter(client); !ok {
+			t.Error("expected the GraphQL client to report the rate limit of its REST client")
+		}
+	}
+
+	if _, err := NewGraphQLClient(&countingClient{}); err == nil {
+		t.Error("expected an error for a client without REST API")
+	}
+}
@@ -0,0 +1,206 @@
+package ghclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxRetries is how often a request is retried after a server error or rate limit.
+	defaultMaxRetries = 5
+	// defaultBaseDelay is the first backoff delay after a server error; it doubles with every retry.
+	defaultBaseDelay = time.Second
+	// defaultMaxDelay caps the backoff delay after server errors.
+	defaultMaxDelay = 30 * time.Second
+	// secondaryRateLimitDelay is the wait GitHub recommends after a secondary rate limit without Retry-After.
+	secondaryRateLimitDelay = time.Minute
+	// maxErrorBodySize limits how much of an error response is read to detect rate limits.
+	maxErrorBodySize = 64 << 10
+)
+
+// Rate is the API quota reported by GitHub in the last response.
+type Rate struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateReporter is implemented by clients that track the API quota reported by GitHub.
+type RateReporter interface {
+	RateLimit() (Rate, bool)
+}
+
+// retryTransport is an http.RoundTripper that retries requests failing with a server error
+// using exponential backoff with jitter, and waits out primary and secondary rate limits as
+// long as the request's context deadline allows.
+type retryTransport struct {
+	base          http.RoundTripper
+	authenticated bool
+	maxRetries    int
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+	now           func() time.Time
+	sleep         func(ctx context.Context, d time.Duration) error
+
+	mu      sync.Mutex
+	rate    Rate
+	hasRate bool
+}
+
+// newRetryTransport wraps base with retries using the default delays.
+func newRetryTransport(base http.RoundTripper, authenticated bool) *retryTransport {
+	return &retryTransport{
+		base:          base,
+		authenticated: authenticated,
+		maxRetries:    defaultMaxRetries,
+		baseDelay:     defaultBaseDelay,
+		maxDelay:      defaultMaxDelay,
+		now:           time.Now,
+		sleep:         sleep,
+	}
+}
+
+// RoundTrip sends the request, retrying it while the response asks for it.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		t.recordRate(resp)
+
+		wait, reason, retry := t.retryDelay(resp, attempt)
+		if !retry || attempt >= t.maxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		if deadline, ok := req.Context().Deadline(); ok && t.now().Add(wait).After(deadline) {
+			log.Printf("Warning: %s, not retrying %s since waiting %s would exceed the timeout", reason, req.URL.Path, wait.Round(time.Second))
+			return resp, nil
+		}
+
+		log.Printf("%s, retrying %s in %s", reason, req.URL.Path, wait.Round(time.Millisecond))
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryDelay reports whether a response should be retried, how long to wait before doing so and why.
+func (t *retryTransport) retryDelay(resp *http.Response, attempt int) (time.Duration, string, bool) {
+	switch {
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return t.backoff(attempt), fmt.Sprintf("server error %d", resp.StatusCode), true
+	case resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests:
+		return 0, "", false
+	}
+
+	if wait, ok := t.parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		return wait, "secondary rate limit exceeded", true
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reason := "primary rate limit exceeded"
+		if !t.authenticated {
+			reason += " (unauthenticated requests are limited to 60 per hour, set GITHUB_TOKEN to raise the limit)"
+		}
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// The reset time has a resolution of seconds, so wait one more to be on the safe side
+			return max(time.Unix(reset, 0).Sub(t.now()), 0) + time.Second, reason, true
+		}
+		return secondaryRateLimitDelay, reason, true
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || isSecondaryRateLimit(resp) {
+		return secondaryRateLimitDelay << attempt, "secondary rate limit exceeded", true
+	}
+
+	return 0, "", false
+}
+
+// backoff returns the exponential backoff delay of an attempt with equal jitter.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.maxDelay
+	if attempt < 32 && t.baseDelay<<attempt < t.maxDelay {
+		d = t.baseDelay << attempt
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func (t *retryTransport) parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(t.now()), 0), true
+	}
+	return 0, false
+}
+
+// recordRate stores the quota reported by a response.
+func (t *retryTransport) recordRate(resp *http.Response) {
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rate = Rate{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}
+	t.hasRate = true
+}
+
+// RateLimit returns the quota reported by the last response, if any.
+func (t *retryTransport) RateLimit() (Rate, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rate, t.hasRate
+}
+
+// isSecondaryRateLimit reports whether a 403 response is a secondary rate limit without headers,
+// which GitHub only signals in the error message. The body is restored for the caller.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
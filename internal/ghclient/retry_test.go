@@ -0,0 +1,238 @@
+package ghclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// response is a canned reply of the fake API server
+type response struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+func TestRetryTransport(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	reset := strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)
+	ok := response{status: http.StatusOK, body: "ok"}
+
+	tests := []struct {
+		name          string
+		responses     []response
+		authenticated bool
+		timeout       time.Duration
+		wantStatus    int
+		wantRequests  int
+		wantWaits     []time.Duration
+	}{
+		{
+			name:         "success",
+			responses:    []response{ok},
+			wantStatus:   http.StatusOK,
+			wantRequests: 1,
+		},
+		{
+			name:         "transient server errors",
+			responses:    []response{{status: http.StatusBadGateway}, {status: http.StatusServiceUnavailable}, ok},
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+		},
+		{
+			name: "persistent server errors",
+			responses: []response{
+				{status: 500}, {status: 500}, {status: 500}, {status: 500}, {status: 500}, {status: 500}, {status: 500},
+			},
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 6,
+		},
+		{
+			name:         "not found is not retried",
+			responses:    []response{{status: http.StatusNotFound}, ok},
+			wantStatus:   http.StatusNotFound,
+			wantRequests: 1,
+		},
+		{
+			name:         "forbidden is not retried",
+			responses:    []response{{status: http.StatusForbidden, body: `{"message":"Resource not accessible by integration"}`}, ok},
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+		{
+			name: "retry after seconds",
+			responses: []response{
+				{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "7"}},
+				ok,
+			},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantWaits:    []time.Duration{7 * time.Second},
+		},
+		{
+			name: "primary rate limit waits for reset",
+			responses: []response{
+				{status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}},
+				ok,
+			},
+			authenticated: true,
+			wantStatus:    http.StatusOK,
+			wantRequests:  2,
+			wantWaits:     []time.Duration{31 * time.Second},
+		},
+		{
+			name: "secondary rate limit without headers",
+			responses: []response{
+				{status: http.StatusForbidden, body: `{"message":"You have exceeded a secondary rate limit."}`},
+				{status: http.StatusForbidden, body: `{"message":"You have exceeded a secondary rate limit."}`},
+				ok,
+			},
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+			wantWaits:    []time.Duration{time.Minute, 2 * time.Minute},
+		},
+		{
+			name: "wait beyond the timeout",
+			responses: []response{
+				{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "120"}},
+				ok,
+			},
+			timeout:      time.Minute,
+			wantStatus:   http.StatusTooManyRequests,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				resp := tt.responses[requests]
+				requests++
+				for k, v := range resp.headers {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(resp.status)
+				_, _ = fmt.Fprint(w, resp.body)
+			}))
+			t.Cleanup(server.Close)
+
+			var waits []time.Duration
+			transport := newRetryTransport(http.DefaultTransport, tt.authenticated)
+			transport.now = func() time.Time { return now }
+			transport.sleep = func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, now.Add(tt.timeout))
+				defer cancel()
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("expected %d requests, got %d", tt.wantRequests, requests)
+			}
+			if tt.wantWaits != nil && fmt.Sprint(waits) != fmt.Sprint(tt.wantWaits) {
+				t.Errorf("expected waits %v, got %v", tt.wantWaits, waits)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_Backoff(t *testing.T) {
+	transport := newRetryTransport(http.DefaultTransport, false)
+
+	for attempt := range 10 {
+		want := min(transport.baseDelay<<attempt, transport.maxDelay)
+		for range 20 {
+			got := transport.backoff(attempt)
+			if got < want/2 || got > want {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", attempt, got, want/2, want)
+			}
+		}
+	}
+}
+
+func TestRetryTransport_ContextCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := newRetryTransport(http.DefaultTransport, false)
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return sleep(ctx, d)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestGitHubClient_RateLimit(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/actions/checkout/git/ref/tags/v4", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4321")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		_, _ = fmt.Fprint(w, `{"object":{"type":"commit","sha":"cccccccccccccccccccccccccccccccccccccccc"}}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := NewGitHubClientForURL(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client = NewCachingClient(client)
+
+	reporter, ok := AsRateReporter(client)
+	if !ok {
+		t.Fatal("expected a rate reporter")
+	}
+	if _, ok := reporter.RateLimit(); ok {
+		t.Error("expected no rate before the first request")
+	}
+
+	if _, err := client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rate, ok := reporter.RateLimit()
+	if !ok {
+		t.Fatal("expected a rate after the first request")
+	}
+	want := Rate{Limit: 5000, Remaining: 4321, Reset: time.Unix(1700000000, 0)}
+	if rate != want {
+		t.Errorf("expected rate %+v, got %+v", want, rate)
+	}
+}
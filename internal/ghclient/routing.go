@@ -0,0 +1,82 @@
+package ghclient
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// routingClient resolves references with a client chosen by the owner of the repository,
+// so that some owners can be resolved against a GitHub Enterprise Server and others against github.com.
+type routingClient struct {
+	fallback GitHubClient
+	routes   map[string]GitHubClient
+}
+
+// NewRoutingClient returns a client that resolves the references of the owners in routes with their
+// client and all other references with fallback. Owners are matched case-insensitively.
+func NewRoutingClient(fallback GitHubClient, routes map[string]GitHubClient) GitHubClient {
+	normalized := make(map[string]GitHubClient, len(routes))
+	for owner, client := range routes {
+		normalized[strings.ToLower(owner)] = client
+	}
+
+	return &routingClient{
+		fallback: fallback,
+		routes:   normalized,
+	}
+}
+
+// ResolveActionSHA resolves the SHA of an action reference with the client of its owner.
+func (r *routingClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	return r.route(action.Owner).ResolveActionSHA(ctx, action)
+}
+
+// ListTags lists the tags of a repository with the client of its owner.
+func (r *routingClient) ListTags(ctx context.Context, owner, repo string) ([]Tag, error) {
+	lister, ok := AsTagLister(r.route(owner))
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return lister.ListTags(ctx, owner, repo)
+}
+
+// IsReachable verifies a commit with the client of the repository's owner.
+func (r *routingClient) IsReachable(ctx context.Context, owner, repo, sha, version string) (bool, error) {
+	verifier, ok := AsCommitVerifier(r.route(owner))
+	if !ok {
+		return false, ErrNotSupported
+	}
+	return verifier.IsReachable(ctx, owner, repo, sha, version)
+}
+
+// FetchFile reads a file with the client of the repository's owner.
+func (r *routingClient) FetchFile(ctx context.Context, owner, repo, path, sha string) ([]byte, error) {
+	fetcher, ok := AsContentFetcher(r.route(owner))
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return fetcher.FetchFile(ctx, owner, repo, path, sha)
+}
+
+// Host returns the host of the server the references of owner are resolved against.
+func (r *routingClient) Host(owner string) string {
+	if reporter, ok := find[HostReporter](r.route(owner)); ok {
+		return reporter.Host(owner)
+	}
+	return ""
+}
+
+// Unwrap returns the client used for owners without a route.
+func (r *routingClient) Unwrap() GitHubClient {
+	return r.fallback
+}
+
+// route returns the client responsible for owner.
+func (r *routingClient) route(owner string) GitHubClient {
+	if client, ok := r.routes[strings.ToLower(owner)]; ok {
+		return client
+	}
+	return r.fallback
+}
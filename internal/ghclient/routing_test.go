@@ -0,0 +1,79 @@
+package ghclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+func TestRoutingClient_ResolveActionSHA(t *testing.T) {
+	githubCom := &countingClient{}
+	ghes := &countingClient{}
+	client := NewRoutingClient(githubCom, map[string]GitHubClient{"Corp": ghes})
+
+	tests := []struct {
+		owner     string
+		wantGHES  int32
+		wantCloud int32
+	}{
+		{owner: "corp", wantGHES: 1},
+		{owner: "CORP", wantGHES: 2},
+		{owner: "actions", wantGHES: 2, wantCloud: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.owner, func(t *testing.T) {
+			if _, err := client.ResolveActionSHA(context.Background(), types.ActionRef{Owner: tt.owner, Repo: "repo", Ref: "v1"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := ghes.calls.Load(); got != tt.wantGHES {
+				t.Errorf("expected %d calls to GHES, got %d", tt.wantGHES, got)
+			}
+			if got := githubCom.calls.Load(); got != tt.wantCloud {
+				t.Errorf("expected %d calls to github.com, got %d", tt.wantCloud, got)
+			}
+		})
+	}
+}
+
+func TestRoutingClient_ListTags(t *testing.T) {
+	ghes := &countingTagLister{}
+	client := NewRoutingClient(&countingClient{}, map[string]GitHubClient{"corp": NewCachingClient(ghes)})
+
+	lister, ok := AsTagLister(client)
+	if !ok {
+		t.Fatal("expected a tag lister")
+	}
+
+	if _, err := lister.ListTags(context.Background(), "corp", "deploy"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ghes.tagCalls.Load(); got != 1 {
+		t.Errorf("expected 1 call to GHES, got %d", got)
+	}
+
+	if _, err := lister.ListTags(context.Background(), "actions", "checkout"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestRoutingClient_FetchFile(t *testing.T) {
+	ghes := &countingFetcher{}
+	fetcher, ok := AsContentFetcher(NewRoutingClient(&countingClient{}, map[string]GitHubClient{"corp": ghes}))
+	if !ok {
+		t.Fatal("expected a content fetcher")
+	}
+
+	if _, err := fetcher.FetchFile(context.Background(), "corp", "deploy", "action.yml", "sha-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ghes.fileCalls.Load(); got != 1 {
+		t.Errorf("expected 1 call to GHES, got %d", got)
+	}
+
+	if _, err := fetcher.FetchFile(context.Background(), "actions", "checkout", "action.yml", "sha-1"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package ghclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// ErrUnverified is returned by IsReachable when a commit was not found in the refs compared so far
+// and comparing more of them would exceed the comparison limit.
+var ErrUnverified = errors.New("commit could not be verified within the comparison limit")
+
+// maxComparisons limits how many refs besides the default branch and the version tag are compared
+// with a commit, as each comparison costs one request.
+var maxComparisons = 10
+
+// CommitVerifier is implemented by clients that can tell whether a commit belongs to a repository.
+// GitHub serves the commits of all repositories in a fork network from each of them, so a commit
+// that can be fetched from a repository may still come from a fork (an impostor commit).
+type CommitVerifier interface {
+	// IsReachable reports whether sha is reachable from a branch or tag of the repository. version is
+	// the tag the commit is expected to be released under, or empty if it is not known. An error
+	// wrapping ErrUnverified is returned if the answer would take too many requests.
+	IsReachable(ctx context.Context, owner, repo, sha, version string) (bool, error)
+}
+
+// AsCommitVerifier returns the first client in a chain of decorators that can verify commits.
+func AsCommitVerifier(client GitHubClient) (CommitVerifier, bool) {
+	return find[CommitVerifier](client)
+}
+
+// IsReachable reports whether sha is the head of a branch or tag of the repository, or an ancestor
+// of one. sha is compared with the default branch first and then with the version tag, if given;
+// this settles almost every legitimate pin in one or two requests. Otherwise, e.g. if the version
+// tag was moved, the heads of the other branches and tags are checked, and at most maxComparisons
+// of them are compared with sha. If none of those contain it but more refs are left, the commit is
+// neither reported reachable nor an impostor but ErrUnverified is returned.
+func (g *githubClient) IsReachable(ctx context.Context, owner, repo, sha, version string) (bool, error) {
+	repository, _, err := g.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return false, fmt.Errorf("failed to get repository %s/%s: %w", owner, repo, err)
+	}
+	defaultBranch := repository.GetDefaultBranch()
+
+	contained, err := g.contains(ctx, owner, repo, defaultBranch, sha)
+	if err != nil || contained {
+		return contained, err
+	}
+	if version != "" {
+		contained, err := g.contains(ctx, owner, repo, version, sha)
+		if err != nil || contained {
+			return contained, err
+		}
+	}
+
+	branches, err := g.listBranches(ctx, owner, repo)
+	if err != nil {
+		return false, err
+	}
+	tags, err := g.ListTags(ctx, owner, repo)
+	if err != nil {
+		return false, err
+	}
+
+	var bases []string
+	for _, branch := range branches {
+		if strings.EqualFold(branch.SHA, sha) {
+			return true, nil
+		}
+		if branch.Name != defaultBranch {
+			bases = append(bases, branch.Name)
+		}
+	}
+	for _, tag := range tags {
+		if strings.EqualFold(tag.SHA, sha) {
+			return true, nil
+		}
+		// The version tag was compared above
+		if tag.Name != version {
+			bases = append(bases, tag.Name)
+		}
+	}
+
+	for i, base := range bases {
+		if i == maxComparisons {
+			return false, fmt.Errorf("%w: %s is not reachable from the default branch or %d of %d other refs of %s/%s",
+				ErrUnverified, sha, maxComparisons, len(bases), owner, repo)
+		}
+		contained, err := g.contains(ctx, owner, repo, base, sha)
+		if err != nil || contained {
+			return contained, err
+		}
+	}
+	return false, nil
+}
+
+// listBranches lists all branches of a repository together with their head commit. Branch heads
+// use the Tag type, as both are names pointing to a commit.
+func (g *githubClient) listBranches(ctx context.Context, owner, repo string) ([]Tag, error) {
+	opts := &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	var branches []Tag
+	for {
+		page, resp, err := g.client.Repositories.ListBranches(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list branches of %s/%s: %w", owner, repo, err)
+		}
+
+		for _, branch := range page {
+			branches = append(branches, Tag{Name: branch.GetName(), SHA: branch.GetCommit().GetSHA()})
+		}
+
+		if resp.NextPage == 0 {
+			return branches, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// contains reports whether sha is base or one of its ancestors. A commit GitHub cannot compare
+// with base at all, e.g. because it does not exist, is not contained.
+func (g *githubClient) contains(ctx context.Context, owner, repo, base, sha string) (bool, error) {
+	comparison, resp, err := g.client.Repositories.CompareCommits(ctx, owner, repo, base, sha, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to compare %s with %s in %s/%s: %w", sha, base, owner, repo, err)
+	}
+
+	switch comparison.GetStatus() {
+	case "identical", "behind":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,164 @@
+package ghclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	mainHeadSHA  = "1111111111111111111111111111111111111111"
+	mainOldSHA   = "2222222222222222222222222222222222222222"
+	releaseSHA   = "3333333333333333333333333333333333333333"
+	tagOnlySHA   = "4444444444444444444444444444444444444444"
+	forkSHA      = "5555555555555555555555555555555555555555"
+	missingSHA   = "6666666666666666666666666666666666666666"
+	releaseHead  = "7777777777777777777777777777777777777777"
+	branchOldSHA = "8888888888888888888888888888888888888888"
+)
+
+// fakeHistory is a repository whose comparisons report which commits are ancestors of which refs
+type fakeHistory struct {
+	compares atomic.Int32
+}
+
+func (f *fakeHistory) handler() http.Handler {
+	// ancestors lists the commits reachable from each branch and tag
+	ancestors := map[string][]string{
+		"main":       {mainHeadSHA, mainOldSHA},
+		"release/v1": {releaseHead, branchOldSHA},
+		"v1.0.0":     {releaseSHA},
+		"v0.9.0":     {tagOnlySHA},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/actions/checkout", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"name":"checkout","default_branch":"main"}`)
+	})
+	mux.HandleFunc("GET /api/v3/repos/actions/checkout/branches", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `[{"name":"release/v1","commit":{"sha":%q}},{"name":"main","commit":{"sha":%q}}]`, releaseHead, mainHeadSHA)
+	})
+	mux.HandleFunc("GET /api/v3/repos/actions/checkout/tags", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `[{"name":"v1.0.0","commit":{"sha":%q}},{"name":"v0.9.0","commit":{"sha":"9999999999999999999999999999999999999999"}}]`, releaseSHA)
+	})
+	mux.HandleFunc("GET /api/v3/repos/actions/checkout/compare/{basehead...}", func(w http.ResponseWriter, r *http.Request) {
+		f.compares.Add(1)
+		base, head, _ := strings.Cut(r.PathValue("basehead"), "...")
+		if head == missingSHA {
+			http.NotFound(w, r)
+			return
+		}
+
+		status := "diverged"
+		for _, sha := range ancestors[base] {
+			if sha == head {
+				status = "behind"
+			}
+		}
+		_, _ = fmt.Fprintf(w, `{"status":%q}`, status)
+	})
+	mux.HandleFunc("GET /api/v3/repos/actions/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, `{"message":"Forbidden"}`)
+	})
+	return mux
+}
+
+func TestGitHubClient_IsReachable(t *testing.T) {
+	tests := []struct {
+		name         string
+		repo         string
+		sha          string
+		version      string
+		limit        int
+		want         bool
+		wantCompares int32
+		wantErr      bool
+		// unverified expects the error to be ErrUnverified
+		unverified bool
+	}{
+		{name: "head of the default branch", repo: "checkout", sha: mainHeadSHA, want: true, wantCompares: 1},
+		{name: "head of another branch", repo: "checkout", sha: releaseHead, want: true, wantCompares: 1},
+		{name: "tag", repo: "checkout", sha: releaseSHA, want: true, wantCompares: 1},
+		{name: "version tag", repo: "checkout", sha: releaseSHA, version: "v1.0.0", want: true, wantCompares: 2},
+		{name: "ancestor of the default branch", repo: "checkout", sha: mainOldSHA, version: "v1.0.0", want: true, wantCompares: 1},
+		{name: "ancestor of another branch", repo: "checkout", sha: branchOldSHA, want: true, wantCompares: 2},
+		{name: "ancestor of a tag", repo: "checkout", sha: tagOnlySHA, want: true, wantCompares: 4},
+		{name: "ancestor of the version tag", repo: "checkout", sha: tagOnlySHA, version: "v0.9.0", want: true, wantCompares: 2},
+		{name: "fork commit", repo: "checkout", sha: forkSHA, want: false, wantCompares: 4},
+		{name: "ancestor of a tag other than the version tag", repo: "checkout", sha: tagOnlySHA, version: "v1.0.0", want: true, wantCompares: 4},
+		{name: "fork commit with version", repo: "checkout", sha: forkSHA, version: "v1.0.0", want: false, wantCompares: 4},
+		{name: "unknown commit", repo: "checkout", sha: missingSHA, want: false, wantCompares: 4},
+		{name: "comparison limit", repo: "checkout", sha: forkSHA, limit: 2, wantCompares: 3, wantErr: true, unverified: true},
+		{name: "within comparison limit", repo: "checkout", sha: forkSHA, version: "v1.0.0", limit: 2, want: false, wantCompares: 4},
+		{name: "API error", repo: "broken", sha: forkSHA, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeHistory{}
+			server := httptest.NewServer(fake.handler())
+			t.Cleanup(server.Close)
+
+			client, err := NewGitHubClientForURL(server.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			verifier, ok := AsCommitVerifier(NewCachingClient(client))
+			if !ok {
+				t.Fatal("expected the REST client to verify commits")
+			}
+
+			if tt.limit > 0 {
+				defer func(limit int) { maxComparisons = limit }(maxComparisons)
+				maxComparisons = tt.limit
+			}
+
+			got, err := verifier.IsReachable(context.Background(), "actions", tt.repo, tt.sha, tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if tt.unverified && !errors.Is(err, ErrUnverified) {
+					t.Errorf("expected ErrUnverified, got %v", err)
+				}
+				if n := fake.compares.Load(); n != tt.wantCompares {
+					t.Errorf("expected %d comparisons, got %d", tt.wantCompares, n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected reachable %v, got %v", tt.want, got)
+			}
+			if n := fake.compares.Load(); n != tt.wantCompares {
+				t.Errorf("expected %d comparisons, got %d", tt.wantCompares, n)
+			}
+		})
+	}
+}
+
+func TestAsCommitVerifier(t *testing.T) {
+	git, err := NewGitClientForURL("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := AsCommitVerifier(NewCachingClient(git)); ok {
+		t.Error("expected the git client not to verify commits")
+	}
+
+	verifier, ok := AsCommitVerifier(NewRoutingClient(git, nil))
+	if !ok {
+		t.Fatal("expected the routing client to verify commits")
+	}
+	if _, err := verifier.IsReachable(context.Background(), "actions", "checkout", forkSHA, ""); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultPath is where the lockfile is kept, relative to the repository root.
+const DefaultPath = ".github/actions.lock.json"
+
+// formatVersion is the version of the lockfile format written by Save.
+const formatVersion = 1
+
+// Entry records what a reference resolved to.
+type Entry struct {
+	// SHA is the commit a reference resolved to, or the manifest digest of an image.
+	SHA string `json:"sha"`
+	// Version is the tag recorded next to the pinned SHA, e.g. v4.2.1 for a reference to v4.
+	Version    string    `json:"version"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// Lockfile maps owner/repo[/path]@ref references to the commit they resolved to, and
+// [docker://]image[:tag] references to their digest.
+type Lockfile struct {
+	Version int              `json:"version"`
+	Actions map[string]Entry `json:"actions"`
+	changed bool
+}
+
+// New creates an empty lockfile.
+func New() *Lockfile {
+	return &Lockfile{Version: formatVersion, Actions: make(map[string]Entry)}
+}
+
+// Load reads the lockfile at path. A missing file yields an empty lockfile.
+func Load(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile %s: %w", path, err)
+	}
+
+	lock := New()
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if lock.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported lockfile version %d in %s, expected %d", lock.Version, path, formatVersion)
+	}
+	if lock.Actions == nil {
+		lock.Actions = make(map[string]Entry)
+	}
+	return lock, nil
+}
+
+// Lookup returns the entry of a reference.
+func (l *Lockfile) Lookup(ref string) (Entry, bool) {
+	entry, ok := l.Actions[ref]
+	return entry, ok
+}
+
+// Record stores the resolution of a reference. An existing entry with the same SHA and version
+// is kept with its original resolution time, so that re-resolving unchanged tags does not
+// change the file.
+func (l *Lockfile) Record(ref string, entry Entry) {
+	if old, ok := l.Actions[ref]; ok && old.SHA == entry.SHA && old.Version == entry.Version {
+		return
+	}
+	l.Actions[ref] = entry
+	l.changed = true
+}
+
+// Changed reports whether entries were recorded since the lockfile was created or loaded.
+func (l *Lockfile) Changed() bool {
+	return l.changed
+}
+
+// Save writes the lockfile to path with entries sorted by reference, creating its directory if needed.
+func (l *Lockfile) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create lockfile directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write lockfile %s: %w", path, err)
+	}
+
+	l.changed = false
+	return nil
+}
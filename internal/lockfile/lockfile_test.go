@@ -0,0 +1,164 @@
+package lockfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantActions int
+		wantErr     bool
+	}{
+		{
+			name: "valid lockfile",
+			content: `{
+  "version": 1,
+  "actions": {
+    "actions/checkout@v4": {"sha": "a81bbbf8298c0fa03ea29cdc473d45769f953675", "version": "v4.2.1", "resolved_at": "2025-01-01T00:00:00Z"}
+  }
+}`,
+			wantActions: 1,
+		},
+		{
+			name:    "no actions",
+			content: `{"version": 1}`,
+		},
+		{
+			name:    "unsupported version",
+			content: `{"version": 2, "actions": {}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			content: `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "actions.lock.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("failed to write lockfile: %v", err)
+			}
+
+			lock, err := Load(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(lock.Actions) != tt.wantActions {
+				t.Errorf("expected %d actions, got %d", tt.wantActions, len(lock.Actions))
+			}
+			if lock.Changed() {
+				t.Error("expected a loaded lockfile to be unchanged")
+			}
+		})
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	lock, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lock.Actions) != 0 {
+		t.Errorf("expected an empty lockfile, got %v", lock.Actions)
+	}
+}
+
+func TestLockfile_Record(t *testing.T) {
+	first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := first.Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		entry       Entry
+		wantEntry   Entry
+		wantChanged bool
+	}{
+		{
+			name:      "unchanged resolution keeps the original time",
+			entry:     Entry{SHA: "aaaa", Version: "v4.2.1", ResolvedAt: later},
+			wantEntry: Entry{SHA: "aaaa", Version: "v4.2.1", ResolvedAt: first},
+		},
+		{
+			name:        "moved tag is recorded",
+			entry:       Entry{SHA: "bbbb", Version: "v4.2.1", ResolvedAt: later},
+			wantEntry:   Entry{SHA: "bbbb", Version: "v4.2.1", ResolvedAt: later},
+			wantChanged: true,
+		},
+		{
+			name:        "new release is recorded",
+			entry:       Entry{SHA: "aaaa", Version: "v4.3.0", ResolvedAt: later},
+			wantEntry:   Entry{SHA: "aaaa", Version: "v4.3.0", ResolvedAt: later},
+			wantChanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lock := New()
+			lock.Actions["actions/checkout@v4"] = Entry{SHA: "aaaa", Version: "v4.2.1", ResolvedAt: first}
+
+			lock.Record("actions/checkout@v4", tt.entry)
+			entry, ok := lock.Lookup("actions/checkout@v4")
+			if !ok {
+				t.Fatal("expected entry to exist")
+			}
+			if entry != tt.wantEntry {
+				t.Errorf("expected entry %+v, got %+v", tt.wantEntry, entry)
+			}
+			if lock.Changed() != tt.wantChanged {
+				t.Errorf("expected changed %v, got %v", tt.wantChanged, lock.Changed())
+			}
+		})
+	}
+}
+
+func TestLockfile_Save(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".github", "actions.lock.json")
+	resolvedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	lock := New()
+	lock.Record("actions/setup-go@v5", Entry{SHA: "bbbb", Version: "v5.1.0", ResolvedAt: resolvedAt})
+	lock.Record("actions/checkout@v4", Entry{SHA: "aaaa", Version: "v4.2.1", ResolvedAt: resolvedAt})
+
+	if err := lock.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lock.Changed() {
+		t.Error("expected a saved lockfile to be unchanged")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read lockfile: %v", err)
+	}
+	content := string(data)
+	if strings.Index(content, "actions/checkout@v4") > strings.Index(content, "actions/setup-go@v5") {
+		t.Errorf("expected entries sorted by reference, got:\n%s", content)
+	}
+	if !strings.HasSuffix(content, "}\n") {
+		t.Errorf("expected a trailing newline, got:\n%s", content)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry, _ := loaded.Lookup("actions/checkout@v4"); entry != (Entry{SHA: "aaaa", Version: "v4.2.1", ResolvedAt: resolvedAt}) {
+		t.Errorf("unexpected entry after reload: %+v", entry)
+	}
+}
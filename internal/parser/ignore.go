@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// directiveIgnore suppresses the reference on the same line or the line below
+	directiveIgnore = "ignore"
+	// directiveIgnoreFile suppresses every reference of the file
+	directiveIgnoreFile = "ignore-file"
+)
+
+// directiveRegex matches a "# digest-pinner: ignore[-file] [reason]" comment. The reason may be
+// separated from the directive by "--" or ":".
+var directiveRegex = regexp.MustCompile(`#\s*digest-pinner:\s*(ignore-file|ignore)(?:[\s:]+(.*))?$`)
+
+// directive is an ignore directive found in a line
+type directive struct {
+	name   string
+	reason string
+	// commentOnly is set if the line holds nothing but the directive comment
+	commentOnly bool
+}
+
+// parseDirective returns the ignore directive of a line, if any
+func parseDirective(line string) (directive, bool) {
+	loc := directiveRegex.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return directive{}, false
+	}
+
+	d := directive{
+		name:        line[loc[2]:loc[3]],
+		commentOnly: strings.TrimSpace(line[:loc[0]]) == "",
+	}
+	if loc[4] >= 0 {
+		d.reason = strings.TrimSpace(strings.TrimLeft(line[loc[4]:loc[5]], "-: "))
+	}
+	if d.reason == "" {
+		d.reason = "digest-pinner: " + d.name
+	}
+	return d, true
+}
+
+// markIgnored marks the references suppressed by the ignore directives of content. A reference is
+// ignored by an ignore directive on its own line or on a comment line directly above it, and every
+// reference is ignored by an ignore-file directive on a comment line anywhere in the file. Only
+// YAML comments count: a directive inside a block scalar, such as a run script, is shell text.
+func markIgnored(content []byte, actions []types.ActionRef) {
+	lines := strings.Split(string(content), "\n")
+	comments := commentLines(content)
+
+	for _, line := range lines {
+		if d, ok := parseDirective(line); ok && d.name == directiveIgnoreFile && comments[strings.TrimSpace(line)] {
+			for i := range actions {
+				actions[i].Ignored = true
+				actions[i].IgnoreReason = d.reason
+			}
+			return
+		}
+	}
+
+	for i, action := range actions {
+		if action.Line < 1 || action.Line > len(lines) {
+			continue
+		}
+
+		d, ok := parseDirective(lines[action.Line-1])
+		if !ok && action.Line > 1 {
+			above := lines[action.Line-2]
+			d, ok = parseDirective(above)
+			ok = ok && d.commentOnly && comments[strings.TrimSpace(above)]
+		}
+		if !ok || d.name != directiveIgnore {
+			continue
+		}
+
+		actions[i].Ignored = true
+		actions[i].IgnoreReason = d.reason
+		actions[i].Comment = stripDirective(action.Comment)
+	}
+}
+
+// commentLines returns the set of comment lines yaml.v3 attaches to the nodes of content, each
+// trimmed of surrounding whitespace. Text inside scalars that merely looks like a comment is not
+// among them.
+func commentLines(content []byte) map[string]bool {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil
+	}
+
+	comments := make(map[string]bool)
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		for _, comment := range []string{node.HeadComment, node.LineComment, node.FootComment} {
+			for line := range strings.SplitSeq(comment, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					comments[line] = true
+				}
+			}
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(&doc)
+	return comments
+}
+
+// stripDirective removes an ignore directive from a trailing comment, so that a version comment
+// such as "v4.2.1 # digest-pinner: ignore" is still recognized
+func stripDirective(comment string) string {
+	i := strings.Index(comment, "digest-pinner:")
+	if i < 0 {
+		return comment
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(comment[:i]), "#"))
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+func TestMarkIgnored(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []types.ActionRef
+	}{
+		{
+			name: "line and line above",
+			content: `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: myorg/internal@main # digest-pinner: ignore -- under development
+      # digest-pinner: ignore
+      - uses: myorg/other@v1
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v5.0.2 # digest-pinner: ignore
+`,
+			expected: []types.ActionRef{
+				{Owner: "myorg", Repo: "internal", Ref: "main", Kind: types.KindAction, Job: "test", Step: 1, Line: 6, Column: 15,
+					Ignored: true, IgnoreReason: "under development"},
+				{Owner: "myorg", Repo: "other", Ref: "v1", Kind: types.KindAction, Job: "test", Step: 2, Line: 8, Column: 15,
+					Ignored: true, IgnoreReason: "digest-pinner: ignore"},
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 3, Line: 9, Column: 15},
+				{Owner: "actions", Repo: "setup-go", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675", Kind: types.KindAction, Job: "test", Step: 4, Line: 10, Column: 15,
+					Comment: "v5.0.2", Ignored: true, IgnoreReason: "digest-pinner: ignore"},
+			},
+		},
+		{
+			name: "directive after a step does not apply to the next line",
+			content: `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo hi # digest-pinner: ignore
+      - uses: actions/checkout@v4
+`,
+			expected: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 2, Line: 7, Column: 15},
+			},
+		},
+		{
+			name: "whole file",
+			content: `# digest-pinner: ignore-file: generated from a template
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    container: node:20
+    steps:
+      - uses: actions/checkout@v4
+`,
+			expected: []types.ActionRef{
+				{Image: "node", Ref: "20", Kind: types.KindContainerImage, Job: "test", Line: 6, Column: 16,
+					Ignored: true, IgnoreReason: "generated from a template"},
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 1, Line: 8, Column: 15,
+					Ignored: true, IgnoreReason: "generated from a template"},
+			},
+		},
+		{
+			name: "directives inside a run script",
+			content: `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: |
+          echo "# digest-pinner: ignore-file"
+          # digest-pinner: ignore-file
+          # digest-pinner: ignore
+      - uses: actions/checkout@v4
+`,
+			expected: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 2, Line: 10, Column: 15},
+			},
+		},
+		{
+			name: "whole file after the first key",
+			content: `on: push
+# digest-pinner: ignore-file
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+`,
+			expected: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 1, Line: 7, Column: 15,
+					Ignored: true, IgnoreReason: "digest-pinner: ignore-file"},
+			},
+		},
+		{
+			name: "unknown directive",
+			content: `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4 # digest-pinner: ignored
+`,
+			expected: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 1, Line: 6, Column: 15,
+					Comment: "digest-pinner: ignored"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := ParseWorkflowActions([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(actions) != len(tt.expected) {
+				t.Fatalf("expected %d actions, got %d: %+v", len(tt.expected), len(actions), actions)
+			}
+			for i, action := range actions {
+				if action != tt.expected[i] {
+					t.Errorf("action %d: expected %+v, got %+v", i, tt.expected[i], action)
+				}
+			}
+		})
+	}
+}
@@ -5,45 +5,268 @@ import (
 	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
 	"strings"
 
-	"sigs.k8s.io/yaml"
+	"gopkg.in/yaml.v3"
 )
 
 // ParseWorkflowActions parses a GitHub Actions workflow file and extracts action references.
+// Step-level actions and docker images, job-level reusable workflow calls and the images of job
+// and service containers are returned in document order, each carrying the line and column of its value.
+// References suppressed by an ignore directive comment are marked as ignored.
 func ParseWorkflowActions(content []byte) ([]types.ActionRef, error) {
-	var workflow struct {
-		Jobs map[string]struct {
-			Steps []struct {
-				Uses string `yaml:"uses"`
-			} `yaml:"steps"`
-		} `yaml:"jobs"`
+	root, err := parseDocument(content)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := yaml.Unmarshal(content, &workflow); err != nil {
+	c := collector{seen: make(map[*yaml.Node]bool)}
+	jobs := mappingValue(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		jobID := jobs.Content[i].Value
+		job := resolveAlias(jobs.Content[i+1])
+
+		workflowRef := types.ActionRef{Kind: types.KindReusableWorkflow, Job: jobID}
+		if err := c.add(mappingValue(job, "uses"), workflowRef); err != nil {
+			return nil, fmt.Errorf("invalid reusable workflow reference: %w", err)
+		}
+
+		if err := c.addContainers(job, jobID); err != nil {
+			return nil, err
+		}
+
+		if err := c.addSteps(mappingValue(job, "steps"), jobID); err != nil {
+			return nil, err
+		}
+	}
+
+	markIgnored(content, c.actions)
+	return c.actions, nil
+}
+
+// ParseCompositeActions parses an action metadata file (action.yml) and extracts the action
+// references used by its steps. Only composite actions (runs.using: composite) have steps;
+// JavaScript and Docker actions yield no references. Ignore directives are honored as in workflows.
+func ParseCompositeActions(content []byte) ([]types.ActionRef, error) {
+	root, err := parseDocument(content)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := mappingValue(root, "runs")
+	if using := mappingValue(runs, "using"); using == nil || using.Value != "composite" {
+		return nil, nil
+	}
+
+	c := collector{seen: make(map[*yaml.Node]bool)}
+	if err := c.addSteps(mappingValue(runs, "steps"), ""); err != nil {
+		return nil, err
+	}
+
+	markIgnored(content, c.actions)
+	return c.actions, nil
+}
+
+// ParseActionDependencies parses an action metadata file (action.yml) and extracts everything the
+// action runs: the references used by the steps of a composite action, or the image of a Docker
+// action running a prebuilt image (runs.image: docker://...). Docker actions built from a
+// Dockerfile and JavaScript actions have no dependencies.
+func ParseActionDependencies(content []byte) ([]types.ActionRef, error) {
+	root, err := parseDocument(content)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := mappingValue(root, "runs")
+	using := mappingValue(runs, "using")
+	if using == nil {
+		return nil, nil
+	}
+
+	c := collector{seen: make(map[*yaml.Node]bool)}
+	switch using.Value {
+	case "composite":
+		if err := c.addSteps(mappingValue(runs, "steps"), ""); err != nil {
+			return nil, err
+		}
+	case "docker":
+		image := resolveAlias(mappingValue(runs, "image"))
+		if image == nil || !strings.HasPrefix(image.Value, dockerPrefix) {
+			return nil, nil
+		}
+		if err := c.add(image, types.ActionRef{Kind: types.KindDockerImage}); err != nil {
+			return nil, fmt.Errorf("invalid action image: %w", err)
+		}
+	}
+
+	markIgnored(content, c.actions)
+	return c.actions, nil
+}
+
+// collector accumulates action references from uses nodes. Nodes reached through
+// aliases are only reported once, at the position of their anchor.
+type collector struct {
+	actions []types.ActionRef
+	seen    map[*yaml.Node]bool
+}
+
+// addSteps adds the uses value of every step in a steps sequence of the given job.
+func (c *collector) addSteps(steps *yaml.Node, jobID string) error {
+	if steps == nil || steps.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	for i, step := range steps.Content {
+		stepRef := types.ActionRef{Kind: types.KindAction, Job: jobID, Step: i + 1}
+		if name := mappingValue(step, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			stepRef.StepName = name.Value
+		}
+
+		if err := c.add(mappingValue(step, "uses"), stepRef); err != nil {
+			return fmt.Errorf("invalid action reference: %w", err)
+		}
+	}
+	return nil
+}
+
+// addContainers adds the images of the job container and the service containers of a job. Both
+// may be given as an image string or as a mapping with an image key.
+func (c *collector) addContainers(job *yaml.Node, jobID string) error {
+	ctx := types.ActionRef{Kind: types.KindContainerImage, Job: jobID}
+	if err := c.add(containerImage(mappingValue(job, "container")), ctx); err != nil {
+		return fmt.Errorf("invalid container image: %w", err)
+	}
+
+	services := resolveAlias(mappingValue(job, "services"))
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 1; i < len(services.Content); i += 2 {
+		if err := c.add(containerImage(services.Content[i]), ctx); err != nil {
+			return fmt.Errorf("invalid service image: %w", err)
+		}
+	}
+	return nil
+}
+
+// containerImage returns the image node of a container definition.
+func containerImage(container *yaml.Node) *yaml.Node {
+	container = resolveAlias(container)
+	if container != nil && container.Kind == yaml.MappingNode {
+		return mappingValue(container, "image")
+	}
+	return container
+}
+
+// add parses a uses or image value node and records it with the kind and job context of ctx.
+// Step-level docker:// references are recorded as docker images.
+func (c *collector) add(node *yaml.Node, ctx types.ActionRef) error {
+	node = resolveAlias(node)
+	if node == nil || c.seen[node] {
+		return nil
+	}
+	c.seen[node] = true
+
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("value at line %d is not a string", node.Line)
+	}
+
+	if node.Value == "" || isLocalReference(node.Value) {
+		return nil
+	}
+
+	var action *types.ActionRef
+	var err error
+	switch {
+	case ctx.Kind == types.KindContainerImage || strings.HasPrefix(node.Value, dockerPrefix):
+		// Images chosen by an expression, e.g. from a matrix, are only known at run time
+		if strings.Contains(node.Value, "${{") {
+			return nil
+		}
+		if ctx.Kind == types.KindAction {
+			ctx.Kind = types.KindDockerImage
+		}
+		action, err = parseImageString(strings.TrimPrefix(node.Value, dockerPrefix))
+	default:
+		action, err = parseActionString(node.Value)
+	}
+	if err != nil {
+		return fmt.Errorf("%q at line %d: %w", node.Value, node.Line, err)
+	}
+	action.Kind = ctx.Kind
+	action.Job = ctx.Job
+	action.Step = ctx.Step
+	action.StepName = ctx.StepName
+	action.Line = node.Line
+	action.Column = node.Column
+	action.Comment = strings.TrimSpace(strings.TrimPrefix(node.LineComment, "#"))
+	c.actions = append(c.actions, *action)
+	return nil
+}
+
+// parseDocument parses YAML content into its root node, which is nil for an empty document.
+func parseDocument(content []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	var actions []types.ActionRef
-	for _, job := range workflow.Jobs {
-		for _, step := range job.Steps {
-			if step.Uses == "" {
-				continue
-			}
-
-			if strings.HasPrefix(step.Uses, "./") ||
-				strings.HasPrefix(step.Uses, "../") ||
-				strings.HasPrefix(step.Uses, "docker://") {
-				continue
-			}
-
-			action, err := parseActionString(step.Uses)
-			if err != nil {
-				return nil, fmt.Errorf("invalid action reference %q: %w", step.Uses, err)
-			}
-			actions = append(actions, *action)
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return resolveAlias(doc.Content[0]), nil
+}
+
+// mappingValue returns the value stored under key in a mapping node, or nil if the node is
+// not a mapping or has no such key.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
 		}
 	}
+	return nil
+}
+
+// resolveAlias returns the node an alias points to, or the node itself.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// dockerPrefix starts uses values that run a container image
+const dockerPrefix = "docker://"
+
+// isLocalReference reports whether a uses value points to a local path rather than a
+// repository on GitHub or an image in a registry.
+func isLocalReference(uses string) bool {
+	return strings.HasPrefix(uses, "./") ||
+		strings.HasPrefix(uses, "../")
+}
+
+// parseImageString parses an image in the "name[:tag][@digest]" form into an ActionRef with the
+// image name, the tag as Ref and the digest.
+func parseImageString(image string) (*types.ActionRef, error) {
+	name, digest, hasDigest := strings.Cut(image, "@")
+	tag, hasTag := "", false
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag, hasTag = name[:i], name[i+1:], true
+	}
+
+	if name == "" || (hasTag && tag == "") || (hasDigest && digest == "") {
+		return nil, fmt.Errorf("empty component in image reference")
+	}
 
-	return actions, nil
+	return &types.ActionRef{Image: name, Ref: tag, Digest: digest}, nil
 }
 
 // parseActionString parses a string in the format "owner/repo/path@ref" into an ActionRef struct.
@@ -79,5 +302,6 @@ func parseActionString(actionStr string) (*types.ActionRef, error) {
 		Repo:  repo,
 		Path:  path,
 		Ref:   ref,
+		Kind:  types.KindAction,
 	}, nil
 }
@@ -9,3 +9,7 @@ type DefaultParser struct{}
 func (d DefaultParser) ParseWorkflowActions(content []byte) ([]types.ActionRef, error) {
 	return ParseWorkflowActions(content)
 }
+
+func (d DefaultParser) ParseCompositeActions(content []byte) ([]types.ActionRef, error) {
+	return ParseCompositeActions(content)
+}
@@ -27,10 +27,10 @@ jobs:
       - uses: actions/setup-node@v4.3.0
 `,
 			expected: []types.ActionRef{
-				{Owner: "actions", Repo: "checkout", Ref: "v3"},
-				{Owner: "actions", Repo: "setup-go", Ref: "v4"},
-				{Owner: "actions", Repo: "setup-java", Ref: "v4.7"},
-				{Owner: "actions", Repo: "setup-node", Ref: "v4.3.0"},
+				{Owner: "actions", Repo: "checkout", Ref: "v3", Kind: types.KindAction, Job: "test", Step: 1, Line: 8, Column: 15},
+				{Owner: "actions", Repo: "setup-go", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 2, Line: 9, Column: 15},
+				{Owner: "actions", Repo: "setup-java", Ref: "v4.7", Kind: types.KindAction, Job: "test", Step: 3, Line: 10, Column: 15},
+				{Owner: "actions", Repo: "setup-node", Ref: "v4.3.0", Kind: types.KindAction, Job: "test", Step: 4, Line: 11, Column: 15},
 			},
 		},
 		{
@@ -78,8 +78,22 @@ jobs:
           GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }}
 `,
 			expected: []types.ActionRef{
-				{Owner: "actions", Repo: "checkout", Ref: "v4"},
-				{Owner: "super-linter", Repo: "super-linter", Ref: "v6.7.0"},
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "build", Step: 1, StepName: "Checkout code", Line: 31, Column: 15},
+				{Owner: "super-linter", Repo: "super-linter", Ref: "v6.7.0", Kind: types.KindAction, Job: "build", Step: 2, StepName: "Super-linter", Line: 38, Column: 15, Comment: "x-release-please-version"},
+			},
+		},
+		{
+			name: "version comment of pinned reference",
+			content: `
+jobs:
+  test:
+    steps:
+      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2
+      - uses: actions/setup-go@v5 #
+`,
+			expected: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "11bd71901bbe5b1630ceea73d27597364c9af683", Kind: types.KindAction, Job: "test", Step: 1, Line: 5, Column: 15, Comment: "v4.2.2"},
+				{Owner: "actions", Repo: "setup-go", Ref: "v5", Kind: types.KindAction, Job: "test", Step: 2, Line: 6, Column: 15},
 			},
 		},
 		{
@@ -127,10 +141,57 @@ jobs:
       - uses: actions/setup-java@v4.0.1
 `,
 			expected: []types.ActionRef{
-				{Owner: "actions", Repo: "checkout", Ref: "v4"},
-				{Owner: "actions", Repo: "setup-java", Ref: "v4.0.1"},
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 1, Line: 7, Column: 15},
+				{Image: "alpine", Ref: "3.14", Kind: types.KindDockerImage, Job: "test", Step: 4, Line: 16, Column: 15},
+				{Owner: "actions", Repo: "setup-java", Ref: "v4.0.1", Kind: types.KindAction, Job: "test", Step: 5, Line: 17, Column: 15},
+			},
+		},
+		{
+			name: "container and service images",
+			content: `
+jobs:
+  test:
+    container: node:20
+    services:
+      redis:
+        image: redis:7@sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1 # 7.2.4
+      postgres:
+        image: ghcr.io/myorg/postgres
+      matrix:
+        image: ${{ matrix.image }}
+    steps:
+      - uses: docker://localhost:5000/tools/lint:v1
+  build:
+    container:
+      image: "mcr.microsoft.com/dotnet/sdk:8.0"
+      options: --cpus 1
+`,
+			expected: []types.ActionRef{
+				{Image: "node", Ref: "20", Kind: types.KindContainerImage, Job: "test", Line: 4, Column: 16},
+				{
+					Image:   "redis",
+					Ref:     "7",
+					Digest:  "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1",
+					Kind:    types.KindContainerImage,
+					Job:     "test",
+					Line:    7,
+					Column:  16,
+					Comment: "7.2.4",
+				},
+				{Image: "ghcr.io/myorg/postgres", Kind: types.KindContainerImage, Job: "test", Line: 9, Column: 16},
+				{Image: "localhost:5000/tools/lint", Ref: "v1", Kind: types.KindDockerImage, Job: "test", Step: 1, Line: 13, Column: 15},
+				{Image: "mcr.microsoft.com/dotnet/sdk", Ref: "8.0", Kind: types.KindContainerImage, Job: "build", Line: 16, Column: 14},
 			},
 		},
+		{
+			name: "invalid container image",
+			content: `
+jobs:
+  test:
+    container: "node:"
+`,
+			wantErr: true,
+		},
 		{
 			name: "path-based action reference",
 			content: `
@@ -141,13 +202,76 @@ jobs:
 `,
 			expected: []types.ActionRef{
 				{
-					Owner: "myorg",
-					Repo:  "actions-maven-setup",
-					Path:  ".github/actions/maven-setup",
-					Ref:   "v1.0.1",
+					Owner:  "myorg",
+					Repo:   "actions-maven-setup",
+					Path:   ".github/actions/maven-setup",
+					Ref:    "v1.0.1",
+					Kind:   types.KindAction,
+					Job:    "test",
+					Step:   1,
+					Line:   5,
+					Column: 15,
 				},
 			},
 		},
+		{
+			name: "reusable workflow calls",
+			content: `
+on: push
+jobs:
+  build:
+    uses: myorg/shared/.github/workflows/build.yml@v2
+    with:
+      go-version: '1.25'
+  local:
+    uses: ./.github/workflows/local.yml
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+`,
+			expected: []types.ActionRef{
+				{
+					Owner:  "myorg",
+					Repo:   "shared",
+					Path:   ".github/workflows/build.yml",
+					Ref:    "v2",
+					Kind:   types.KindReusableWorkflow,
+					Job:    "build",
+					Line:   5,
+					Column: 11,
+				},
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "test", Step: 1, Line: 13, Column: 15},
+			},
+		},
+		{
+			name: "positions of quoted, anchored and repeated references",
+			content: `
+jobs:
+  first:
+    steps:
+      - uses: &checkout actions/checkout@v4
+      - uses: "actions/setup-go@v5"
+  second:
+    steps:
+      - uses: *checkout
+      - uses: actions/setup-go@v5
+`,
+			expected: []types.ActionRef{
+				{Owner: "actions", Repo: "checkout", Ref: "v4", Kind: types.KindAction, Job: "first", Step: 1, Line: 5, Column: 15},
+				{Owner: "actions", Repo: "setup-go", Ref: "v5", Kind: types.KindAction, Job: "first", Step: 2, Line: 6, Column: 15},
+				{Owner: "actions", Repo: "setup-go", Ref: "v5", Kind: types.KindAction, Job: "second", Step: 2, Line: 10, Column: 15},
+			},
+		},
+		{
+			name: "invalid reusable workflow reference",
+			content: `
+jobs:
+  build:
+    uses: myorg/shared/.github/workflows/build.yml
+`,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -171,10 +295,184 @@ jobs:
 
 			for i, action := range actions {
 				if action != tt.expected[i] {
-					t.Errorf("action %d mismatch:\nexpected: %+v\ngot:      %+v",
+					t.Errorf("action %d mismatch:\nexpected: %#v\ngot:      %#v",
 						i, tt.expected[i], action)
 				}
 			}
 		})
 	}
 }
+
+func TestParseCompositeActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []types.ActionRef
+		wantErr  bool
+	}{
+		{
+			name: "composite action steps",
+			content: `
+name: Setup
+description: Set up the toolchain
+runs:
+  using: composite
+  steps:
+    - uses: actions/setup-go@v5
+      with:
+        go-version: '1.25'
+    - run: go version
+      shell: bash
+    - uses: ./.github/actions/cache
+    - uses: myorg/tools/lint@v1.2.0
+`,
+			expected: []types.ActionRef{
+				{Owner: "actions", Repo: "setup-go", Ref: "v5", Kind: types.KindAction, Step: 1, Line: 7, Column: 13},
+				{Owner: "myorg", Repo: "tools", Path: "lint", Ref: "v1.2.0", Kind: types.KindAction, Step: 4, Line: 13, Column: 13},
+			},
+		},
+		{
+			name: "javascript action has no references",
+			content: `
+name: Hello
+runs:
+  using: node20
+  main: dist/index.js
+`,
+			expected: nil,
+		},
+		{
+			name: "docker action has no references",
+			content: `
+name: Container
+runs:
+  using: docker
+  image: Dockerfile
+`,
+			expected: nil,
+		},
+		{
+			name: "invalid action reference",
+			content: `
+runs:
+  using: composite
+  steps:
+    - uses: actions/checkout
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := ParseCompositeActions([]byte(tt.content))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(actions) != len(tt.expected) {
+				t.Fatalf("expected %d actions, got %d", len(tt.expected), len(actions))
+			}
+
+			for i, action := range actions {
+				if action != tt.expected[i] {
+					t.Errorf("action %d mismatch:\nexpected: %#v\ngot:      %#v",
+						i, tt.expected[i], action)
+				}
+			}
+		})
+	}
+}
+
+func TestParseActionDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []types.ActionRef
+		wantErr  bool
+	}{
+		{
+			name: "composite action steps",
+			content: `
+runs:
+  using: composite
+  steps:
+    - uses: actions/setup-node@v4
+    - uses: ./local
+`,
+			expected: []types.ActionRef{
+				{Owner: "actions", Repo: "setup-node", Ref: "v4", Kind: types.KindAction, Step: 1, Line: 5, Column: 13},
+			},
+		},
+		{
+			name: "docker action with prebuilt image",
+			content: `
+runs:
+  using: docker
+  image: docker://ghcr.io/myorg/linter:1.4 # latest
+`,
+			expected: []types.ActionRef{
+				{Image: "ghcr.io/myorg/linter", Ref: "1.4", Kind: types.KindDockerImage, Line: 4, Column: 10, Comment: "latest"},
+			},
+		},
+		{
+			name: "docker action built from a Dockerfile",
+			content: `
+runs:
+  using: docker
+  image: Dockerfile
+`,
+			expected: nil,
+		},
+		{
+			name: "javascript action",
+			content: `
+runs:
+  using: node20
+  main: dist/index.js
+`,
+			expected: nil,
+		},
+		{
+			name: "invalid image",
+			content: `
+runs:
+  using: docker
+  image: "docker://alpine:"
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := ParseActionDependencies([]byte(tt.content))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(actions) != len(tt.expected) {
+				t.Fatalf("expected %d actions, got %d: %#v", len(tt.expected), len(actions), actions)
+			}
+			for i, action := range actions {
+				if action != tt.expected[i] {
+					t.Errorf("action %d mismatch:\nexpected: %#v\ngot:      %#v", i, tt.expected[i], action)
+				}
+			}
+		})
+	}
+}
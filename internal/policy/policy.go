@@ -0,0 +1,259 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/checker"
+	"github.com/zisuu/github-actions-digest-pinner/internal/semver"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is where the policy file is looked up, relative to the scanned directory
+const DefaultPath = ".github/digest-pinner-policy.yml"
+
+// Effect is what a rule does with the references it matches
+type Effect string
+
+const (
+	// EffectAllow accepts the reference as it is
+	EffectAllow Effect = "allow"
+	// EffectDeny rejects the reference
+	EffectDeny Effect = "deny"
+	// EffectMustPin rejects the reference unless it is pinned to a commit SHA or image digest
+	EffectMustPin Effect = "must-pin"
+)
+
+// Severity is the severity of a violation. Only errors fail a policy check.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+)
+
+// RefType classifies the ref of a reference
+type RefType string
+
+const (
+	// RefSHA is a full commit SHA, or the digest of an image
+	RefSHA RefType = "sha"
+	// RefTag is a version tag such as v4 or v4.2.1, or the tag of an image
+	RefTag RefType = "tag"
+	// RefBranch is any other ref. Refs are not looked up, so tags that are not versions count as branches.
+	RefBranch RefType = "branch"
+)
+
+// Patterns is a list of owner/repo patterns. In the policy file it may also be a single string.
+type Patterns []string
+
+// UnmarshalYAML accepts a single pattern as well as a list
+func (p *Patterns) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*p = Patterns{node.Value}
+		return nil
+	}
+	var patterns []string
+	if err := node.Decode(&patterns); err != nil {
+		return err
+	}
+	*p = patterns
+	return nil
+}
+
+// Rule matches references by repository or image name and ref type, and decides what happens to them
+type Rule struct {
+	Name string `yaml:"name,omitempty"`
+	// Match lists owners (e.g. "myorg"), owner/repo patterns using path.Match syntax (e.g.
+	// "actions/*") or image names (e.g. "ghcr.io/myorg/*"). "*" matches every reference.
+	Match Patterns `yaml:"match"`
+	// Refs restricts the rule to these ref types; empty matches all of them
+	Refs     []RefType `yaml:"refs,omitempty"`
+	Effect   Effect    `yaml:"effect"`
+	Severity Severity  `yaml:"severity,omitempty"`
+	// Message replaces the default description of violations of the rule
+	Message string `yaml:"message,omitempty"`
+}
+
+// Policy is an ordered list of rules. Each reference is decided by the first rule matching it;
+// references no rule matches are allowed.
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Violation is a reference rejected by a rule
+type Violation struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Uses     string   `json:"uses"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Action is the rejected reference
+	Action types.ActionRef `json:"-"`
+}
+
+// Load reads and validates a policy file
+func Load(file string) (*Policy, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy %s: %w", file, err)
+	}
+
+	p, err := Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", file, err)
+	}
+	return p, nil
+}
+
+// Parse parses and validates a policy. Unknown keys are rejected.
+func Parse(content []byte) (*Policy, error) {
+	var p Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate reports every invalid rule
+func (p *Policy) Validate() error {
+	var errs []error
+	for i, rule := range p.Rules {
+		name := rule.name(i)
+		if len(rule.Match) == 0 {
+			errs = append(errs, fmt.Errorf("%s: match must not be empty", name))
+		}
+		for _, pattern := range rule.Match {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid pattern %q: %w", name, pattern, err))
+			}
+		}
+		for _, ref := range rule.Refs {
+			if !slices.Contains([]RefType{RefSHA, RefTag, RefBranch}, ref) {
+				errs = append(errs, fmt.Errorf("%s: unsupported ref type %q, expected sha, tag or branch", name, ref))
+			}
+		}
+		if !slices.Contains([]Effect{EffectAllow, EffectDeny, EffectMustPin}, rule.Effect) {
+			errs = append(errs, fmt.Errorf("%s: unsupported effect %q, expected allow, deny or must-pin", name, rule.Effect))
+		}
+		if rule.Severity != "" && !slices.Contains([]Severity{SeverityError, SeverityWarning, SeverityNote}, rule.Severity) {
+			errs = append(errs, fmt.Errorf("%s: unsupported severity %q, expected error, warning or note", name, rule.Severity))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Evaluate returns the violation of the first rule matching the reference, if that rule rejects it
+func (p *Policy) Evaluate(file string, action types.ActionRef) (Violation, bool) {
+	refType := ClassifyRef(action)
+	for i, rule := range p.Rules {
+		if !rule.matches(action, refType) {
+			continue
+		}
+
+		var message string
+		switch rule.Effect {
+		case EffectDeny:
+			message = fmt.Sprintf("%s is denied", action)
+		case EffectMustPin:
+			if refType == RefSHA {
+				return Violation{}, false
+			}
+			message = fmt.Sprintf("%s is not pinned to %s", action, checker.PinTarget(action))
+		default:
+			return Violation{}, false
+		}
+		if rule.Message != "" {
+			message = fmt.Sprintf("%s: %s", action, rule.Message)
+		}
+
+		severity := rule.Severity
+		if severity == "" {
+			severity = SeverityError
+		}
+		return Violation{
+			File:     file,
+			Line:     action.Line,
+			Column:   action.Column,
+			Uses:     action.String(),
+			Rule:     rule.name(i),
+			Severity: severity,
+			Message:  message,
+			Action:   action,
+		}, true
+	}
+	return Violation{}, false
+}
+
+// Check evaluates every reference of a file and returns the violations in order
+func (p *Policy) Check(file string, actions []types.ActionRef) []Violation {
+	var violations []Violation
+	for _, action := range actions {
+		if v, ok := p.Evaluate(file, action); ok {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// ClassifyRef returns the ref type of a reference. Refs are not resolved: refs that parse as a
+// version are tags, image tags are tags, and everything else that is not pinned is a branch.
+func ClassifyRef(action types.ActionRef) RefType {
+	switch {
+	case checker.IsPinned(action):
+		return RefSHA
+	case action.IsImage() || semver.IsVersion(action.Ref):
+		return RefTag
+	default:
+		return RefBranch
+	}
+}
+
+// name returns the name of the rule, or its 1-based position if it has none
+func (r Rule) name(index int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("rule %d", index+1)
+}
+
+// matches reports whether the rule applies to a reference with the given ref type
+func (r Rule) matches(action types.ActionRef, refType RefType) bool {
+	if len(r.Refs) > 0 && !slices.Contains(r.Refs, refType) {
+		return false
+	}
+
+	// GitHub owner and repository names are case-insensitive
+	name := strings.ToLower(action.Owner + "/" + action.Repo)
+	if action.IsImage() {
+		name = strings.ToLower(action.Image)
+	}
+	for _, pattern := range r.Match {
+		if pattern == "*" {
+			return true
+		}
+		if !action.IsImage() && !strings.Contains(pattern, "/") {
+			pattern += "/*"
+		}
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
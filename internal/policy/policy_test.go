@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+const testPolicy = `rules:
+  - name: no-branches
+    match: "*"
+    refs: [branch]
+    effect: deny
+    message: branch refs are forbidden
+  - name: untrusted-owner
+    match: [some-owner]
+    effect: deny
+  - name: github-actions-on-tags
+    match: actions/*
+    refs: [sha, tag]
+    effect: allow
+  - name: images
+    match: ghcr.io/myorg/*
+    effect: must-pin
+    severity: warning
+  - match: "*"
+    effect: must-pin
+`
+
+func TestPolicy_Evaluate(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		action   types.ActionRef
+		expected *Violation
+	}{
+		{
+			name:   "branch",
+			action: types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "main", Line: 7, Column: 15},
+			expected: &Violation{Line: 7, Column: 15, Uses: "actions/checkout@main", Rule: "no-branches", Severity: SeverityError,
+				Message: "actions/checkout@main: branch refs are forbidden"},
+		},
+		{
+			name:   "denied owner",
+			action: types.ActionRef{Owner: "Some-Owner", Repo: "tool", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675"},
+			expected: &Violation{Uses: "Some-Owner/tool@a81bbbf8298c0fa03ea29cdc473d45769f953675", Rule: "untrusted-owner", Severity: SeverityError,
+				Message: "Some-Owner/tool@a81bbbf8298c0fa03ea29cdc473d45769f953675 is denied"},
+		},
+		{
+			name:   "allowed on tag",
+			action: types.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"},
+		},
+		{
+			name:   "unpinned image",
+			action: types.ActionRef{Image: "ghcr.io/myorg/tool", Ref: "1.2", Kind: types.KindContainerImage},
+			expected: &Violation{Uses: "ghcr.io/myorg/tool:1.2", Rule: "images", Severity: SeverityWarning,
+				Message: "ghcr.io/myorg/tool:1.2 is not pinned to a digest"},
+		},
+		{
+			name:   "must pin",
+			action: types.ActionRef{Owner: "myorg", Repo: "shared", Path: ".github/workflows/build.yml", Ref: "v1", Kind: types.KindReusableWorkflow},
+			expected: &Violation{Uses: "myorg/shared/.github/workflows/build.yml@v1", Rule: "rule 5", Severity: SeverityError,
+				Message: "myorg/shared/.github/workflows/build.yml@v1 is not pinned to a commit SHA"},
+		},
+		{
+			name:   "pinned",
+			action: types.ActionRef{Owner: "myorg", Repo: "shared", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, ok := p.Evaluate("", tt.action)
+			if tt.expected == nil {
+				if ok {
+					t.Errorf("expected no violation, got %+v", v)
+				}
+				return
+			}
+
+			tt.expected.Action = tt.action
+			if !ok || v != *tt.expected {
+				t.Errorf("expected %+v, got %+v (%v)", *tt.expected, v, ok)
+			}
+		})
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	p := &Policy{Rules: []Rule{{Match: Patterns{"*"}, Effect: EffectMustPin}}}
+	actions := []types.ActionRef{
+		{Owner: "actions", Repo: "checkout", Ref: "v4", Line: 7},
+		{Owner: "actions", Repo: "setup-go", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675", Line: 8},
+		{Image: "alpine", Kind: types.KindDockerImage, Line: 9},
+	}
+
+	violations := p.Check("ci.yml", actions)
+	if len(violations) != 2 || violations[0].Line != 7 || violations[1].Line != 9 || violations[1].File != "ci.yml" {
+		t.Errorf("unexpected violations: %+v", violations)
+	}
+
+	if violations := (&Policy{}).Check("ci.yml", actions); len(violations) != 0 {
+		t.Errorf("expected an empty policy to allow everything, got %+v", violations)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown key", content: "rules:\n  - match: '*'\n    efect: deny\n", wantErr: "field efect not found"},
+		{name: "missing match", content: "rules:\n  - effect: deny\n", wantErr: "rule 1: match must not be empty"},
+		{name: "unknown effect", content: "rules:\n  - name: r\n    match: '*'\n    effect: block\n", wantErr: `r: unsupported effect "block"`},
+		{name: "unknown ref type", content: "rules:\n  - match: '*'\n    refs: [release]\n    effect: deny\n", wantErr: `unsupported ref type "release"`},
+		{name: "unknown severity", content: "rules:\n  - match: '*'\n    effect: deny\n    severity: fatal\n", wantErr: `unsupported severity "fatal"`},
+		{name: "malformed pattern", content: "rules:\n  - match: '[a'\n    effect: deny\n", wantErr: `invalid pattern "[a"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.yml")
+	if err := os.WriteFile(file, []byte(testPolicy), 0o644); err != nil {
+		t.Fatalf("failed to write policy: %v", err)
+	}
+
+	p, err := Load(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Rules) != 5 || p.Rules[1].Match[0] != "some-owner" {
+		t.Errorf("unexpected rules: %+v", p.Rules)
+	}
+
+	if _, err := Load(file + ".missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestClassifyRef(t *testing.T) {
+	tests := []struct {
+		action   types.ActionRef
+		expected RefType
+	}{
+		{types.ActionRef{Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675"}, RefSHA},
+		{types.ActionRef{Ref: "v4"}, RefTag},
+		{types.ActionRef{Ref: "v4.2.1-rc.1"}, RefTag},
+		{types.ActionRef{Ref: "main"}, RefBranch},
+		{types.ActionRef{Ref: "release/v4"}, RefBranch},
+		{types.ActionRef{Image: "alpine", Ref: "3.19", Kind: types.KindDockerImage}, RefTag},
+		{types.ActionRef{Image: "alpine", Digest: "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1", Kind: types.KindDockerImage}, RefSHA},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyRef(tt.action); got != tt.expected {
+			t.Errorf("ClassifyRef(%s) = %s, expected %s", tt.action, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// manifestMediaTypes are the manifest formats accepted when resolving a tag. Multi-platform images
+// resolve to their index, so that the pinned digest covers every platform.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+var digestRegex = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// Resolver resolves image tags to manifest digests.
+type Resolver interface {
+	ResolveDigest(ctx context.Context, image, tag string) (string, error)
+}
+
+// Credentials returns the username and password for a registry host, or false for anonymous access.
+type Credentials func(host string) (username, password string, ok bool)
+
+// Client resolves image tags through the registry HTTP API (v2), authenticating with bearer
+// tokens or basic auth as requested by the registry.
+type Client struct {
+	httpClient  *http.Client
+	credentials Credentials
+}
+
+// NewClient creates a registry client. A nil httpClient selects http.DefaultClient and nil
+// credentials access all registries anonymously.
+func NewClient(httpClient *http.Client, credentials Credentials) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if credentials == nil {
+		credentials = func(string) (string, string, bool) { return "", "", false }
+	}
+	return &Client{httpClient: httpClient, credentials: credentials}
+}
+
+// EnvCredentials authenticates to ghcr.io with GITHUB_TOKEN and to Docker Hub with DOCKERHUB_USERNAME
+// and DOCKERHUB_TOKEN, if they are set.
+func EnvCredentials(host string) (string, string, bool) {
+	switch host {
+	case "ghcr.io":
+		token := os.Getenv("GITHUB_TOKEN")
+		return "token", token, token != ""
+	case dockerHubHost:
+		username, token := os.Getenv("DOCKERHUB_USERNAME"), os.Getenv("DOCKERHUB_TOKEN")
+		return username, token, username != "" && token != ""
+	default:
+		return "", "", false
+	}
+}
+
+// ResolveDigest returns the digest of the manifest an image tag points to, e.g. sha256:4bcff6...
+func (c *Client) ResolveDigest(ctx context.Context, image, tag string) (string, error) {
+	ref, err := ParseReference(image, tag)
+	if err != nil {
+		return "", err
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Host, ref.Repository, url.PathEscape(ref.Tag))
+
+	resp, err := c.fetchManifest(ctx, http.MethodHead, manifestURL, ref)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+	digest := resp.Header.Get("Docker-Content-Digest")
+
+	// Not every registry reports the digest of HEAD requests; it is then computed from the manifest
+	if digest == "" {
+		resp, err := c.fetchManifest(ctx, http.MethodGet, manifestURL, ref)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, resp.Body); err != nil {
+			return "", fmt.Errorf("failed to read manifest of %s: %w", ref, err)
+		}
+		digest = "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	}
+
+	if !digestRegex.MatchString(digest) {
+		return "", fmt.Errorf("unsupported digest %q for %s", digest, ref)
+	}
+	return digest, nil
+}
+
+// fetchManifest requests a manifest, authenticating when the registry responds with a challenge
+func (c *Client) fetchManifest(ctx context.Context, method, manifestURL string, ref Reference) (*http.Response, error) {
+	resp, err := c.do(ctx, method, manifestURL, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		_ = resp.Body.Close()
+		authorization, err := c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), ref)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(ctx, method, manifestURL, authorization); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("failed to get manifest of %s: registry responded %s", ref, resp.Status)
+	}
+	return resp, nil
+}
+
+// do sends a manifest request with the given Authorization header value
+func (c *Client) do(ctx context.Context, method, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request %s: %w", manifestURL, err)
+	}
+	return resp, nil
+}
+
+// authorize returns the Authorization header value answering a WWW-Authenticate challenge
+func (c *Client) authorize(ctx context.Context, challenge string, ref Reference) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	username, password, hasCredentials := c.credentials(ref.Host)
+
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		token, err := c.fetchToken(ctx, params, ref)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	case "basic":
+		if !hasCredentials {
+			return "", fmt.Errorf("registry %s requires credentials", ref.Host)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge %q from registry %s", challenge, ref.Host)
+	}
+}
+
+// fetchToken requests a pull token from the authorization service named by a bearer challenge,
+// authenticated with the credentials of the registry if there are any
+func (c *Client) fetchToken(ctx context.Context, params map[string]string, ref Reference) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme == "" || realm.Host == "" {
+		return "", fmt.Errorf("invalid token realm %q from registry %s", params["realm"], ref.Host)
+	}
+
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	if username, password, ok := c.credentials(ref.Host); ok {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request token for %s: %w", ref, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request token for %s: token service responded %s", ref, resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token for %s: %w", ref, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("token service returned no token for %s", ref)
+}
+
+// parseChallenge splits a WWW-Authenticate header such as `Bearer realm="https://auth.docker.io/token",
+// service="registry.docker.io"` into its scheme and parameters. Quoted values may contain commas.
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+
+	for rest != "" {
+		var key string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		if key != "" {
+			params[key] = strings.TrimSpace(value)
+		}
+	}
+	return scheme, params
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const manifest = `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`
+
+// fakeRegistry is a local stand-in for a registry serving a single tagged manifest of org/app
+type fakeRegistry struct {
+	// auth is the challenge scheme the registry requires: "bearer", "basic" or "" for anonymous access
+	auth string
+	// digestHeader reports the digest in the Docker-Content-Digest header of HEAD responses
+	digestHeader bool
+}
+
+func (f *fakeRegistry) start(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("service") != "fake" || r.URL.Query().Get("scope") != "repository:org/app:pull" {
+			http.Error(w, "bad scope", http.StatusBadRequest)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"access_token":"pull-token"}`)
+	})
+	mux.HandleFunc("/v2/org/app/manifests/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		switch f.auth {
+		case "bearer":
+			if r.Header.Get("Authorization") != "Bearer pull-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake",scope="repository:org/app:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "basic":
+			if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="fake"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if r.PathValue("tag") != "1.0" {
+			http.NotFound(w, r)
+			return
+		}
+		if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+			http.Error(w, "unsupported media type", http.StatusNotAcceptable)
+			return
+		}
+		if f.digestHeader {
+			w.Header().Set("Docker-Content-Digest", manifestDigest())
+		}
+		if r.Method == http.MethodGet {
+			_, _ = fmt.Fprint(w, manifest)
+		}
+	})
+
+	server = httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func manifestDigest() string {
+	sum := sha256.Sum256([]byte(manifest))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestClient_ResolveDigest(t *testing.T) {
+	credentials := func(host string) (string, string, bool) { return "user", "secret", true }
+
+	tests := []struct {
+		name        string
+		registry    fakeRegistry
+		tag         string
+		credentials Credentials
+		wantErr     bool
+	}{
+		{name: "anonymous", registry: fakeRegistry{digestHeader: true}, tag: "1.0"},
+		{name: "bearer token", registry: fakeRegistry{auth: "bearer", digestHeader: true}, tag: "1.0"},
+		{name: "bearer token with credentials", registry: fakeRegistry{auth: "bearer", digestHeader: true}, tag: "1.0", credentials: credentials},
+		{name: "digest computed from manifest", registry: fakeRegistry{auth: "bearer"}, tag: "1.0"},
+		{name: "basic auth", registry: fakeRegistry{auth: "basic", digestHeader: true}, tag: "1.0", credentials: credentials},
+		{name: "basic auth without credentials", registry: fakeRegistry{auth: "basic", digestHeader: true}, tag: "1.0", wantErr: true},
+		{name: "unknown tag", registry: fakeRegistry{auth: "bearer", digestHeader: true}, tag: "2.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tt.registry.start(t)
+			client := NewClient(server.Client(), tt.credentials)
+
+			image := strings.TrimPrefix(server.URL, "https://") + "/org/app"
+			digest, err := client.ResolveDigest(context.Background(), image, tt.tag)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got digest %s", digest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if digest != manifestDigest() {
+				t.Errorf("expected digest %s, got %s", manifestDigest(), digest)
+			}
+		})
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull,push"`)
+	if scheme != "Bearer" {
+		t.Errorf("expected scheme Bearer, got %q", scheme)
+	}
+
+	expected := map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/alpine:pull,push",
+	}
+	if len(params) != len(expected) {
+		t.Errorf("expected %d parameters, got %v", len(expected), params)
+	}
+	for key, value := range expected {
+		if params[key] != value {
+			t.Errorf("expected %s=%q, got %q", key, value, params[key])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// dockerHubDomain is the registry assumed for image names without one
+	dockerHubDomain = "docker.io"
+	// dockerHubHost serves the registry API of Docker Hub
+	dockerHubHost = "registry-1.docker.io"
+	// defaultTag is resolved for image names without a tag
+	defaultTag = "latest"
+)
+
+// Reference identifies a tagged manifest in a registry.
+type Reference struct {
+	// Host is the host serving the registry API, e.g. registry-1.docker.io or ghcr.io
+	Host string
+	// Repository is the repository within the registry, e.g. library/alpine
+	Repository string
+	Tag        string
+}
+
+// String returns the reference in the host/repository:tag form.
+func (r Reference) String() string {
+	return r.Host + "/" + r.Repository + ":" + r.Tag
+}
+
+// ParseReference splits an image name such as "alpine", "ghcr.io/owner/image" or "localhost:5000/image"
+// into the registry host and repository. As with the docker CLI, the first component names the registry
+// if it contains a dot or a port or is localhost; other images are on Docker Hub, where single-component
+// names are official images under library/. An empty tag selects latest.
+func ParseReference(image, tag string) (Reference, error) {
+	if image == "" || strings.ContainsAny(image, " \t@") {
+		return Reference{}, fmt.Errorf("invalid image name %q", image)
+	}
+
+	domain, repository := dockerHubDomain, image
+	if first, rest, ok := strings.Cut(image, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		domain, repository = first, rest
+	}
+
+	for _, component := range strings.Split(repository, "/") {
+		if component == "" {
+			return Reference{}, fmt.Errorf("invalid image name %q", image)
+		}
+	}
+
+	host := domain
+	if domain == dockerHubDomain || domain == "index.docker.io" {
+		host = dockerHubHost
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+
+	if tag == "" {
+		tag = defaultTag
+	}
+	return Reference{Host: host, Repository: strings.ToLower(repository), Tag: tag}, nil
+}
@@ -0,0 +1,42 @@
+package registry
+
+import "testing"
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image   string
+		tag     string
+		want    Reference
+		wantErr bool
+	}{
+		{image: "alpine", tag: "3.19", want: Reference{Host: "registry-1.docker.io", Repository: "library/alpine", Tag: "3.19"}},
+		{image: "alpine", want: Reference{Host: "registry-1.docker.io", Repository: "library/alpine", Tag: "latest"}},
+		{image: "bitnami/redis", tag: "7", want: Reference{Host: "registry-1.docker.io", Repository: "bitnami/redis", Tag: "7"}},
+		{image: "docker.io/node", tag: "20", want: Reference{Host: "registry-1.docker.io", Repository: "library/node", Tag: "20"}},
+		{image: "index.docker.io/library/node", tag: "20", want: Reference{Host: "registry-1.docker.io", Repository: "library/node", Tag: "20"}},
+		{image: "ghcr.io/Owner/image", tag: "v1", want: Reference{Host: "ghcr.io", Repository: "owner/image", Tag: "v1"}},
+		{image: "localhost:5000/app", tag: "dev", want: Reference{Host: "localhost:5000", Repository: "app", Tag: "dev"}},
+		{image: "localhost/app", tag: "dev", want: Reference{Host: "localhost", Repository: "app", Tag: "dev"}},
+		{image: "", wantErr: true},
+		{image: "ghcr.io//image", wantErr: true},
+		{image: "alpine@sha256", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := ParseReference(tt.image, tt.tag)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/checker"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// Format is an output format for scan and check results
+type Format string
+
+const (
+	FormatText  Format = "text"
+	FormatJSON  Format = "json"
+	FormatSARIF Format = "sarif"
+	FormatCSV   Format = "csv"
+)
+
+// ParseFormat validates an output format name
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case FormatText, FormatJSON, FormatSARIF, FormatCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported output format %q, expected text, json, sarif or csv", name)
+	}
+}
+
+// Record is a single action reference or image found in a file. Images have an image name and
+// digest instead of an owner, repository and path. References ignored by a directive comment are
+// marked as suppressed, with the reason given next to the directive. Advisories and Allowed are
+// set by the caller, as matching needs an advisory database and the allow list.
+type Record struct {
+	File     string `json:"file"`
+	Kind     string `json:"kind"`
+	Job      string `json:"job,omitempty"`
+	Step     int    `json:"step,omitempty"`
+	StepName string `json:"step_name,omitempty"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Owner    string `json:"owner,omitempty"`
+	Repo     string `json:"repo,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Image    string `json:"image,omitempty"`
+	Ref      string `json:"ref"`
+	Digest   string `json:"digest,omitempty"`
+	Pinned   bool   `json:"pinned"`
+
+	Suppressed        bool   `json:"suppressed,omitempty"`
+	SuppressionReason string `json:"suppression_reason,omitempty"`
+
+	// Advisories lists the IDs of the known advisories affecting the reference
+	Advisories []string `json:"advisories,omitempty"`
+	// Allowed marks an unpinned reference that is accepted by the allow list, so it is only
+	// reported for its advisories
+	Allowed bool `json:"allowed,omitempty"`
+}
+
+// NewRecord creates a record for an action reference found in file
+func NewRecord(file string, action types.ActionRef) Record {
+	return Record{
+		File:     file,
+		Kind:     string(action.Kind),
+		Job:      action.Job,
+		Step:     action.Step,
+		StepName: action.StepName,
+		Line:     action.Line,
+		Column:   action.Column,
+		Owner:    action.Owner,
+		Repo:     action.Repo,
+		Path:     action.Path,
+		Image:    action.Image,
+		Ref:      action.Ref,
+		Digest:   action.Digest,
+		Pinned:   checker.IsPinned(action),
+
+		Suppressed:        action.Ignored,
+		SuppressionReason: action.IgnoreReason,
+	}
+}
+
+// Action returns the reference the record was created for, without its position
+func (r Record) Action() types.ActionRef {
+	return types.ActionRef{
+		Kind:   types.RefKind(r.Kind),
+		Owner:  r.Owner,
+		Repo:   r.Repo,
+		Path:   r.Path,
+		Image:  r.Image,
+		Ref:    r.Ref,
+		Digest: r.Digest,
+	}
+}
+
+// Uses returns the reference in the form used in workflow files, e.g. "owner/repo[/path]@ref"
+func (r Record) Uses() string {
+	return r.Action().String()
+}
+
+// WriteJSON writes the records as an indented JSON array
+func WriteJSON(w io.Writer, records []Record) error {
+	if records == nil {
+		records = []Record{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(records); err != nil {
+		return fmt.Errorf("failed to write JSON output: %w", err)
+	}
+	return nil
+}
+
+// csvHeader lists the CSV columns in the order they are written
+var csvHeader = []string{"file", "kind", "job", "step", "step_name", "line", "column", "owner", "repo", "path", "ref", "pinned", "image", "digest", "suppressed", "suppression_reason", "advisories"}
+
+// WriteCSV writes the records as CSV with a header row
+func WriteCSV(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, r := range records {
+		row := []string{
+			r.File,
+			r.Kind,
+			r.Job,
+			strconv.Itoa(r.Step),
+			r.StepName,
+			strconv.Itoa(r.Line),
+			strconv.Itoa(r.Column),
+			r.Owner,
+			r.Repo,
+			r.Path,
+			r.Ref,
+			strconv.FormatBool(r.Pinned),
+			r.Image,
+			r.Digest,
+			strconv.FormatBool(r.Suppressed),
+			r.SuppressionReason,
+			strings.Join(r.Advisories, ";"),
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+var testRecords = []Record{
+	NewRecord(".github/workflows/ci.yml", types.ActionRef{
+		Owner: "actions", Repo: "checkout", Ref: "v4",
+		Kind: types.KindAction, Job: "test", Step: 1, StepName: "Checkout, with history", Line: 7, Column: 15,
+	}),
+	NewRecord(".github/workflows/ci.yml", types.ActionRef{
+		Owner: "myorg", Repo: "shared", Path: ".github/workflows/build.yml", Ref: "a81bbbf8298c0fa03ea29cdc473d45769f953675",
+		Kind: types.KindReusableWorkflow, Job: "build", Line: 3, Column: 11,
+	}),
+	NewRecord(".github/workflows/ci.yml", types.ActionRef{
+		Image: "redis", Ref: "7", Digest: "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1",
+		Kind: types.KindContainerImage, Job: "test", Line: 4, Column: 16,
+	}),
+	NewRecord(".github/workflows/ci.yml", types.ActionRef{
+		Owner: "myorg", Repo: "internal", Ref: "main",
+		Kind: types.KindAction, Job: "test", Step: 2, Line: 9, Column: 15, Ignored: true, IgnoreReason: "under development",
+	}),
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"text", "json", "sarif", "csv"} {
+		if format, err := ParseFormat(name); err != nil || string(format) != name {
+			t.Errorf("ParseFormat(%q) = %q, %v", name, format, err)
+		}
+	}
+
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected error for unsupported format, got nil")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testRecords); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `[
+  {
+    "file": ".github/workflows/ci.yml",
+    "kind": "action",
+    "job": "test",
+    "step": 1,
+    "step_name": "Checkout, with history",
+    "line": 7,
+    "column": 15,
+    "owner": "actions",
+    "repo": "checkout",
+    "ref": "v4",
+    "pinned": false
+  },
+  {
+    "file": ".github/workflows/ci.yml",
+    "kind": "workflow",
+    "job": "build",
+    "line": 3,
+    "column": 11,
+    "owner": "myorg",
+    "repo": "shared",
+    "path": ".github/workflows/build.yml",
+    "ref": "a81bbbf8298c0fa03ea29cdc473d45769f953675",
+    "pinned": true
+  },
+  {
+    "file": ".github/workflows/ci.yml",
+    "kind": "container",
+    "job": "test",
+    "line": 4,
+    "column": 16,
+    "image": "redis",
+    "ref": "7",
+    "digest": "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1",
+    "pinned": true
+  },
+  {
+    "file": ".github/workflows/ci.yml",
+    "kind": "action",
+    "job": "test",
+    "step": 2,
+    "line": 9,
+    "column": 15,
+    "owner": "myorg",
+    "repo": "internal",
+    "ref": "main",
+    "pinned": false,
+    "suppressed": true,
+    "suppression_reason": "under development"
+  }
+]
+`
+	if buf.String() != expected {
+		t.Errorf("JSON mismatch:\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("expected empty JSON array, got %q", buf.String())
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testRecords); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `file,kind,job,step,step_name,line,column,owner,repo,path,ref,pinned,image,digest,suppressed,suppression_reason,advisories
+.github/workflows/ci.yml,action,test,1,"Checkout, with history",7,15,actions,checkout,,v4,false,,,false,,
+.github/workflows/ci.yml,workflow,build,0,,3,11,myorg,shared,.github/workflows/build.yml,a81bbbf8298c0fa03ea29cdc473d45769f953675,true,,,false,,
+.github/workflows/ci.yml,container,test,0,,4,16,,,,7,true,redis,sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1,false,,
+.github/workflows/ci.yml,action,test,2,,9,15,myorg,internal,,main,false,,,true,under development,
+`
+	if buf.String() != expected {
+		t.Errorf("CSV mismatch:\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/checker"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolName     = "github-actions-digest-pinner"
+	toolURI      = "https://github.com/zisuu/github-actions-digest-pinner"
+
+	// unpinnedRuleID identifies results for references not pinned to a commit SHA
+	unpinnedRuleID = "unpinned-action"
+	// advisoryRuleID identifies results for references affected by a known advisory
+	advisoryRuleID = "known-vulnerable-action"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Version        string      `json:"version,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	FullDescription  sarifMessage `json:"fullDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID       string             `json:"ruleId"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// WriteSARIF writes a SARIF 2.1.0 log with one result of the given level ("error", "warning"
+// or "note") for every record that is neither pinned to a commit SHA nor allowed, so that the findings show up
+// as code scanning annotations on the offending lines. Suppressed records are reported with an
+// in-source suppression, which code scanning shows as dismissed. Records affected by advisories
+// get an additional result, which ignore directives do not suppress.
+func WriteSARIF(w io.Writer, records []Record, toolVersion, level string) error {
+	results := []sarifResult{}
+	for _, r := range records {
+		locations := []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: r.File},
+				Region:           sarifRegion{StartLine: r.Line, StartColumn: r.Column},
+			},
+		}}
+
+		if !r.Pinned && !r.Allowed {
+			result := sarifResult{
+				RuleID:    unpinnedRuleID,
+				Level:     level,
+				Message:   sarifMessage{Text: fmt.Sprintf("%s is not pinned to %s", r.Uses(), checker.PinTarget(r.Action()))},
+				Locations: locations,
+			}
+			if r.Suppressed {
+				result.Suppressions = []sarifSuppression{{Kind: "inSource", Justification: r.SuppressionReason}}
+			}
+			results = append(results, result)
+		}
+
+		if len(r.Advisories) > 0 {
+			results = append(results, sarifResult{
+				RuleID:    advisoryRuleID,
+				Level:     level,
+				Message:   sarifMessage{Text: fmt.Sprintf("%s is affected by %s", r.Uses(), strings.Join(r.Advisories, ", "))},
+				Locations: locations,
+			})
+		}
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           toolName,
+				InformationURI: toolURI,
+				Version:        toolVersion,
+				Rules: []sarifRule{{
+					ID:               unpinnedRuleID,
+					ShortDescription: sarifMessage{Text: "Action reference not pinned to a commit SHA"},
+					FullDescription: sarifMessage{Text: "Tags and branches can be moved to point at different code. " +
+						"Pin actions and reusable workflows to a full-length commit SHA and container images to a digest."},
+					HelpURI: toolURI,
+				}, {
+					ID:               advisoryRuleID,
+					ShortDescription: sarifMessage{Text: "Action reference affected by a known advisory"},
+					FullDescription: sarifMessage{Text: "The referenced version or commit of the action is affected by a published security advisory. " +
+						"Update to a fixed version and pin it to its commit SHA."},
+					HelpURI: toolURI,
+				}},
+			}},
+			Results: results,
+		}},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(log); err != nil {
+		return fmt.Errorf("failed to write SARIF output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, testRecords, "1.2.3", "error"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+
+	if log.Version != "2.1.0" || log.Schema == "" {
+		t.Errorf("unexpected SARIF header: version %q, schema %q", log.Version, log.Schema)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("expected 1 run, got %d", len(log.Runs))
+	}
+
+	run := log.Runs[0]
+	if run.Tool.Driver.Version != "1.2.3" || len(run.Tool.Driver.Rules) != 2 {
+		t.Errorf("unexpected tool driver: %+v", run.Tool.Driver)
+	}
+
+	// Only the unpinned records produce a result
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(run.Results))
+	}
+
+	result := run.Results[0]
+	if result.RuleID != unpinnedRuleID || result.Level != "error" {
+		t.Errorf("unexpected rule or level: %s, %s", result.RuleID, result.Level)
+	}
+	if result.Message.Text != "actions/checkout@v4 is not pinned to a commit SHA" {
+		t.Errorf("unexpected message: %q", result.Message.Text)
+	}
+
+	location := result.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != ".github/workflows/ci.yml" || location.Region.StartLine != 7 || location.Region.StartColumn != 15 {
+		t.Errorf("unexpected location: %+v", location)
+	}
+	if len(result.Suppressions) != 0 {
+		t.Errorf("unexpected suppressions: %+v", result.Suppressions)
+	}
+
+	suppressed := run.Results[1]
+	expectedSuppression := sarifSuppression{Kind: "inSource", Justification: "under development"}
+	if len(suppressed.Suppressions) != 1 || suppressed.Suppressions[0] != expectedSuppression {
+		t.Errorf("expected suppression %+v, got %+v", expectedSuppression, suppressed.Suppressions)
+	}
+}
+
+func TestWriteSARIF_Image(t *testing.T) {
+	records := []Record{NewRecord(".github/workflows/ci.yml", types.ActionRef{
+		Image: "alpine", Ref: "3.19", Kind: types.KindDockerImage, Job: "test", Step: 2, Line: 8, Column: 15,
+	})}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, records, "1.2.3", "warning"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("expected 1 result, got %+v", log.Runs)
+	}
+	if text := log.Runs[0].Results[0].Message.Text; text != "docker://alpine:3.19 is not pinned to a digest" {
+		t.Errorf("unexpected message: %q", text)
+	}
+}
+
+func TestWriteSARIF_Advisories(t *testing.T) {
+	record := NewRecord(".github/workflows/ci.yml", types.ActionRef{
+		Owner: "tj-actions", Repo: "changed-files", Ref: "0e58ed8671d6b60d0890c21b07f8835ace038e67",
+		Kind: types.KindAction, Line: 12, Column: 15, Ignored: true, IgnoreReason: "reviewed",
+	})
+	record.Advisories = []string{"GHSA-mrrh-fwg8-r2c3", "GHSA-mcph-m25j-8j63"}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, []Record{record}, "1.2.3", "error"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("expected 1 result, got %+v", log.Runs)
+	}
+
+	// Pinned references only produce an advisory result, which ignore directives do not suppress
+	result := log.Runs[0].Results[0]
+	if result.RuleID != advisoryRuleID || len(result.Suppressions) != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	expected := "tj-actions/changed-files@0e58ed8671d6b60d0890c21b07f8835ace038e67 is affected by GHSA-mrrh-fwg8-r2c3, GHSA-mcph-m25j-8j63"
+	if result.Message.Text != expected {
+		t.Errorf("unexpected message: %q", result.Message.Text)
+	}
+}
+
+func TestWriteSARIF_AllowedAdvisories(t *testing.T) {
+	record := NewRecord(".github/workflows/ci.yml", types.ActionRef{
+		Owner: "tj-actions", Repo: "changed-files", Ref: "v45", Kind: types.KindAction, Line: 12, Column: 15,
+	})
+	record.Advisories = []string{"GHSA-mrrh-fwg8-r2c3"}
+	record.Allowed = true
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, []Record{record}, "1.2.3", "error"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("expected 1 result, got %+v", log.Runs)
+	}
+
+	// Allowed references are not reported as unpinned, only for their advisories
+	if result := log.Runs[0].Results[0]; result.RuleID != advisoryRuleID {
+		t.Errorf("expected only an advisory result, got %+v", result)
+	}
+}
@@ -0,0 +1,106 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Upgrade levels, each allowing the changes of the previous one.
+const (
+	LevelPatch = "patch"
+	LevelMinor = "minor"
+	LevelMajor = "major"
+)
+
+// Constraint restricts the versions a pinned release may be upgraded to, either relative to the
+// current version (an upgrade level) or as a fixed range.
+type Constraint struct {
+	level       string
+	comparators []comparator
+}
+
+// comparator is a single condition of a range, such as >=v4.2.0.
+type comparator struct {
+	op      string
+	version Version
+}
+
+// ParseLevel returns the constraint for an upgrade level: patch, minor or major.
+func ParseLevel(level string) (Constraint, error) {
+	switch level {
+	case LevelPatch, LevelMinor, LevelMajor:
+		return Constraint{level: level}, nil
+	default:
+		return Constraint{}, fmt.Errorf("unsupported upgrade level %q, expected patch, minor or major", level)
+	}
+}
+
+// ParseRange parses a range of comma or space separated conditions that must all hold. A condition is a
+// version prefixed by >=, >, <=, <, = (exact), ^ (same major version and not lower) or ~ (same minor
+// version and not lower). A bare version matches its release line, e.g. v4 matches v4.2.1 and v4.2
+// matches v4.2.1 but not v4.3.0.
+func ParseRange(expr string) (Constraint, error) {
+	var c Constraint
+	for _, field := range strings.FieldsFunc(expr, func(r rune) bool { return r == ',' || r == ' ' }) {
+		op := ""
+		for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(field, prefix) {
+				op = prefix
+				break
+			}
+		}
+
+		v, ok := Parse(strings.TrimPrefix(field, op))
+		if !ok {
+			return Constraint{}, fmt.Errorf("invalid version range %q: %q is not a version", expr, field)
+		}
+		c.comparators = append(c.comparators, comparator{op: op, version: v})
+	}
+
+	if len(c.comparators) == 0 {
+		return Constraint{}, fmt.Errorf("invalid version range %q: no conditions", expr)
+	}
+	return c, nil
+}
+
+// Allows reports whether candidate is an allowed version for a release currently at current.
+// Whether candidate is newer than current is not checked.
+func (c Constraint) Allows(current, candidate Version) bool {
+	switch c.level {
+	case LevelPatch:
+		return candidate.Major == current.Major && candidate.Minor == current.Minor
+	case LevelMinor:
+		return candidate.Major == current.Major
+	case LevelMajor:
+		return true
+	}
+
+	for _, cmp := range c.comparators {
+		if !cmp.matches(candidate) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether v satisfies the comparator.
+func (c comparator) matches(v Version) bool {
+	switch c.op {
+	case ">=":
+		return Compare(v, c.version) >= 0
+	case ">":
+		return Compare(v, c.version) > 0
+	case "<=":
+		return Compare(v, c.version) <= 0
+	case "<":
+		return Compare(v, c.version) < 0
+	case "=":
+		return Compare(v, c.version) == 0
+	case "^":
+		return v.Major == c.version.Major && Compare(v, c.version) >= 0
+	case "~":
+		return v.Major == c.version.Major && v.Minor == c.version.Minor && Compare(v, c.version) >= 0
+	default:
+		return c.version.Contains(v)
+	}
+}
@@ -0,0 +1,89 @@
+package semver
+
+import "testing"
+
+func TestParseLevel(t *testing.T) {
+	current, _ := Parse("v4.2.1")
+
+	tests := []struct {
+		level    string
+		allowed  []string
+		rejected []string
+		wantErr  bool
+	}{
+		{level: "patch", allowed: []string{"v4.2.5"}, rejected: []string{"v4.3.0", "v5.0.0"}},
+		{level: "minor", allowed: []string{"v4.2.5", "v4.3.0"}, rejected: []string{"v5.0.0"}},
+		{level: "major", allowed: []string{"v4.2.5", "v4.3.0", "v5.0.0"}},
+		{level: "latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			c, err := ParseLevel(tt.level)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkConstraint(t, c, current, tt.allowed, tt.rejected)
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	current, _ := Parse("v4.2.1")
+
+	tests := []struct {
+		expr     string
+		allowed  []string
+		rejected []string
+		wantErr  bool
+	}{
+		{expr: ">=4.2.3 <5", allowed: []string{"v4.2.3", "v4.9.0"}, rejected: []string{"v4.2.2", "v5.0.0"}},
+		{expr: ">v4.2.1,<=v4.3.0", allowed: []string{"v4.2.2", "v4.3.0"}, rejected: []string{"v4.2.1", "v4.3.1"}},
+		{expr: "^4.3", allowed: []string{"v4.3.0", "v4.9.9"}, rejected: []string{"v4.2.9", "v5.0.0"}},
+		{expr: "~4.2.3", allowed: []string{"v4.2.3", "v4.2.9"}, rejected: []string{"v4.2.2", "v4.3.0"}},
+		{expr: "=v4.2.4", allowed: []string{"v4.2.4"}, rejected: []string{"v4.2.5"}},
+		{expr: "v4.2", allowed: []string{"v4.2.0", "v4.2.9"}, rejected: []string{"v4.3.0"}},
+		{expr: "v5", allowed: []string{"v5.0.0", "v5.3.0"}, rejected: []string{"v4.9.0", "v6.0.0"}},
+		{expr: ">=main", wantErr: true},
+		{expr: " , ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseRange(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkConstraint(t, c, current, tt.allowed, tt.rejected)
+		})
+	}
+}
+
+// checkConstraint asserts which candidate versions a constraint allows for the current version
+func checkConstraint(t *testing.T, c Constraint, current Version, allowed, rejected []string) {
+	t.Helper()
+	for _, tag := range allowed {
+		v, _ := Parse(tag)
+		if !c.Allows(current, v) {
+			t.Errorf("expected %s to be allowed", tag)
+		}
+	}
+	for _, tag := range rejected {
+		v, _ := Parse(tag)
+		if c.Allows(current, v) {
+			t.Errorf("expected %s to be rejected", tag)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package semver
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed version tag such as v4, v4.1 or v4.1.0-rc.1.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	// Components is the number of numeric components present in the tag (1 to 3).
+	Components int
+	Original   string
+}
+
+// Parse parses a version tag with an optional "v" prefix. Build metadata is ignored.
+// It returns false if the tag is not a version.
+func Parse(tag string) (Version, bool) {
+	v := Version{Original: tag}
+
+	s := strings.TrimPrefix(strings.TrimPrefix(tag, "v"), "V")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.Prerelease = s[i+1:]
+		if v.Prerelease == "" {
+			return Version{}, false
+		}
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, false
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || strings.HasPrefix(part, "+") {
+			return Version{}, false
+		}
+		numbers[i] = n
+	}
+
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+	v.Components = len(parts)
+	return v, true
+}
+
+// IsVersion reports whether the tag parses as a version.
+func IsVersion(tag string) bool {
+	_, ok := Parse(tag)
+	return ok
+}
+
+// Compare returns -1, 0 or 1 depending on whether a has lower, equal or higher precedence than b.
+// Missing components count as zero and a prerelease has lower precedence than its release.
+func Compare(a, b Version) int {
+	for _, d := range []int{a.Major - b.Major, a.Minor - b.Minor, a.Patch - b.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	default:
+		return comparePrerelease(a.Prerelease, b.Prerelease)
+	}
+}
+
+// comparePrerelease compares two prereleases identifier by identifier as described in
+// semver 2.0 §11: numeric identifiers compare numerically and have lower precedence than
+// alphanumeric ones, which compare in ASCII order, and a shorter prerelease has lower
+// precedence if all its identifiers equal those of the longer one.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return cmp.Compare(an, bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case as[i] != bs[i]:
+			return cmp.Compare(as[i], bs[i])
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// Contains reports whether v lies within the release line denoted by the floating tag
+// prefix (e.g. v4.2.1 is within v4 and v4.2, but not within v4.3).
+func (prefix Version) Contains(v Version) bool {
+	if v.Major != prefix.Major {
+		return false
+	}
+	if prefix.Components >= 2 && v.Minor != prefix.Minor {
+		return false
+	}
+	if prefix.Components >= 3 && v.Patch != prefix.Patch {
+		return false
+	}
+	return true
+}
+
+// FromComment parses the version at the start of a trailing comment, as written next to a
+// pinned SHA (e.g. "v4.2.1" or "v4.2.1 # x-release-please-version").
+func FromComment(comment string) (Version, bool) {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(comment), "#"))
+	if len(fields) == 0 {
+		return Version{}, false
+	}
+	return Parse(fields[0])
+}
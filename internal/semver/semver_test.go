@@ -0,0 +1,109 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected Version
+		ok       bool
+	}{
+		{tag: "v4", expected: Version{Major: 4, Components: 1, Original: "v4"}, ok: true},
+		{tag: "v4.7", expected: Version{Major: 4, Minor: 7, Components: 2, Original: "v4.7"}, ok: true},
+		{tag: "4.3.0", expected: Version{Major: 4, Minor: 3, Components: 3, Original: "4.3.0"}, ok: true},
+		{tag: "v1.0.0-rc.1", expected: Version{Major: 1, Prerelease: "rc.1", Components: 3, Original: "v1.0.0-rc.1"}, ok: true},
+		{tag: "v2.1.0+build.5", expected: Version{Major: 2, Minor: 1, Components: 3, Original: "v2.1.0+build.5"}, ok: true},
+		{tag: "main", ok: false},
+		{tag: "v", ok: false},
+		{tag: "v1.2.3.4", ok: false},
+		{tag: "v1.x", ok: false},
+		{tag: "v1.0.0-", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			v, ok := Parse(tt.tag)
+			if ok != tt.ok {
+				t.Fatalf("expected ok=%v, got %v", tt.ok, ok)
+			}
+			if ok && v != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, v)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "v1.0.0", b: "v1.0.0", expected: 0},
+		{a: "v1", b: "v1.0.0", expected: 0},
+		{a: "v1.2.0", b: "v1.10.0", expected: -1},
+		{a: "v2.0.0", b: "v1.9.9", expected: 1},
+		{a: "v1.0.0-rc.1", b: "v1.0.0", expected: -1},
+		{a: "v1.0.0-rc.2", b: "v1.0.0-rc.1", expected: 1},
+		{a: "v1.0.0-rc.2", b: "v1.0.0-rc.10", expected: -1},
+		{a: "v1.0.0-alpha", b: "v1.0.0-alpha.1", expected: -1},
+		{a: "v1.0.0-alpha.1", b: "v1.0.0-alpha.beta", expected: -1},
+		{a: "v1.0.0-beta", b: "v1.0.0-alpha.1", expected: 1},
+		{a: "v1.0.0-rc.1", b: "v1.0.0-rc.1", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			a, _ := Parse(tt.a)
+			b, _ := Parse(tt.b)
+			if got := Compare(a, b); got != tt.expected {
+				t.Errorf("Compare(%s, %s) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		prefix, version string
+		expected        bool
+	}{
+		{prefix: "v4", version: "v4.2.1", expected: true},
+		{prefix: "v4", version: "v5.0.0", expected: false},
+		{prefix: "v4.2", version: "v4.2.1", expected: true},
+		{prefix: "v4.2", version: "v4.3.0", expected: false},
+		{prefix: "v4.2.1", version: "v4.2.1", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix+"_"+tt.version, func(t *testing.T) {
+			prefix, _ := Parse(tt.prefix)
+			v, _ := Parse(tt.version)
+			if got := prefix.Contains(v); got != tt.expected {
+				t.Errorf("%s.Contains(%s) = %v, expected %v", tt.prefix, tt.version, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFromComment(t *testing.T) {
+	tests := []struct {
+		comment  string
+		expected string
+		ok       bool
+	}{
+		{comment: "v4.2.1", expected: "v4.2.1", ok: true},
+		{comment: "# v4", expected: "v4", ok: true},
+		{comment: "v6.7.0 # x-release-please-version", expected: "v6.7.0", ok: true},
+		{comment: "x-release-please-version", ok: false},
+		{comment: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			v, ok := FromComment(tt.comment)
+			if ok != tt.ok || v.Original != tt.expected {
+				t.Errorf("FromComment(%q) = %q, %v, expected %q, %v", tt.comment, v.Original, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
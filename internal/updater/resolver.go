@@ -0,0 +1,230 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/checker"
+	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/internal/lockfile"
+	"github.com/zisuu/github-actions-digest-pinner/internal/semver"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// resolution is the commit SHA and version comment a reference is pinned to. For images, sha
+// holds the manifest digest and version is empty.
+type resolution struct {
+	sha     string
+	version string
+	// previous is the version an upgraded reference was pinned to
+	previous string
+}
+
+// resolveKey identifies the repository and ref a reference resolves against. Actions in
+// subdirectories of the same repository share a key, so they are resolved only once, as do
+// docker and container references to the same image.
+func resolveKey(action types.ActionRef) string {
+	if action.IsImage() {
+		return "docker://" + action.Image + ":" + action.Ref
+	}
+	return action.Owner + "/" + action.Repo + "@" + action.Ref
+}
+
+// skipsImage reports whether a reference is an image that cannot be resolved, as no registry client is set
+func (u *Updater) skipsImage(action types.ActionRef) bool {
+	return action.IsImage() && u.images == nil
+}
+
+// resolveAll resolves every unique unpinned reference of the parsed files using a bounded
+// number of workers. An image that cannot be resolved, e.g. in a private registry without
+// credentials, is logged and left out; the first failure to resolve an action cancels the
+// remaining lookups.
+func (u *Updater) resolveAll(ctx context.Context, files []parsedFile) (map[string]resolution, error) {
+	seen := make(map[string]bool)
+	var pending []types.ActionRef
+	for _, pf := range files {
+		for _, action := range pf.actions {
+			key := resolveKey(action)
+			if checker.IsPinned(action) || u.skipsImage(action) || seen[key] {
+				continue
+			}
+			seen[key] = true
+			pending = append(pending, action)
+		}
+	}
+
+	resolved := make(map[string]resolution, len(pending))
+	if len(pending) == 0 {
+		return resolved, nil
+	}
+
+	workers := min(max(u.concurrency, 1), len(pending))
+	log.Printf("Resolving %d unique references with %d workers", len(pending), workers)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		skipped  int
+		wg       sync.WaitGroup
+	)
+	jobs := make(chan types.ActionRef)
+	for range workers {
+		wg.Go(func() {
+			for action := range jobs {
+				res, err := u.resolve(ctx, action)
+				mu.Lock()
+				if err != nil && action.IsImage() && ctx.Err() == nil {
+					log.Printf("Warning: leaving %s unchanged: %v", action, err)
+					skipped++
+				} else if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else {
+					resolved[resolveKey(action)] = res
+				}
+				mu.Unlock()
+			}
+		})
+	}
+
+feed:
+	for _, action := range pending {
+		select {
+		case jobs <- action:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if len(resolved)+skipped < len(pending) {
+		return nil, ctx.Err()
+	}
+	return resolved, nil
+}
+
+// resolveFromLockfile resolves every unpinned reference of the parsed files from the lockfile.
+// References are looked up by their full owner/repo[/path]@ref form; all missing ones are reported.
+func (u *Updater) resolveFromLockfile(files []parsedFile) (map[string]resolution, error) {
+	if u.lock == nil {
+		return nil, errors.New("frozen mode requires a lockfile")
+	}
+
+	resolved := make(map[string]resolution)
+	var missing []string
+	for _, pf := range files {
+		for _, action := range pf.actions {
+			if checker.IsPinned(action) {
+				continue
+			}
+			entry, ok := u.lock.Lookup(action.String())
+			if !ok {
+				if !slices.Contains(missing, action.String()) {
+					missing = append(missing, action.String())
+				}
+				continue
+			}
+			resolved[resolveKey(action)] = resolution{sha: entry.SHA, version: entry.Version}
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("references missing from the lockfile: %s", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// recordResolutions records the resolution of every unpinned reference of the parsed files in the lockfile
+func (u *Updater) recordResolutions(files []parsedFile, resolved map[string]resolution) {
+	if u.lock == nil {
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, pf := range files {
+		for _, action := range pf.actions {
+			res, ok := resolved[resolveKey(action)]
+			if checker.IsPinned(action) || !ok {
+				continue
+			}
+			u.lock.Record(action.String(), lockfile.Entry{SHA: res.sha, Version: res.version, ResolvedAt: now})
+		}
+	}
+}
+
+// resolve looks up the commit SHA and version comment of a single reference, or the digest of an image
+func (u *Updater) resolve(ctx context.Context, action types.ActionRef) (resolution, error) {
+	if action.IsImage() {
+		digest, err := u.images.ResolveDigest(ctx, action.Image, action.Ref)
+		if err != nil {
+			return resolution{}, fmt.Errorf("failed to resolve digest for %s %s: %w", action.Kind, action, err)
+		}
+		return resolution{sha: digest}, nil
+	}
+
+	sha, err := u.Client.ResolveActionSHA(ctx, action)
+	if err != nil {
+		return resolution{}, fmt.Errorf("failed to resolve SHA for %s %s: %w", action.Kind, action, err)
+	}
+	return resolution{sha: sha, version: u.resolveVersion(ctx, action, sha)}, nil
+}
+
+// resolveVersion returns the version recorded next to a pinned SHA. For floating version
+// tags such as v4 it looks for the most specific release tag pointing at the same commit
+// (e.g. v4.2.1); otherwise, or if the client cannot list tags, the original ref is used.
+func (u *Updater) resolveVersion(ctx context.Context, action types.ActionRef, sha string) string {
+	floating, ok := semver.Parse(action.Ref)
+	if !ok || floating.Components == 3 {
+		return action.Ref
+	}
+
+	lister, ok := ghclient.AsTagLister(u.Client)
+	if !ok {
+		return action.Ref
+	}
+
+	tags, err := lister.ListTags(ctx, action.Owner, action.Repo)
+	if errors.Is(err, ghclient.ErrNotSupported) {
+		return action.Ref
+	}
+	if err != nil {
+		log.Printf("Warning: failed to list tags for %s, keeping %s as version: %v", action, action.Ref, err)
+		return action.Ref
+	}
+
+	return mostSpecificTag(tags, floating, sha).Original
+}
+
+// mostSpecificTag returns the most specific release tag within the release line of prefix that
+// points to sha, e.g. v4.2.1 for v4, or prefix itself if there is none
+func mostSpecificTag(tags []ghclient.Tag, prefix semver.Version, sha string) semver.Version {
+	best := prefix
+	for _, tag := range tags {
+		if !strings.EqualFold(tag.SHA, sha) {
+			continue
+		}
+		v, ok := semver.Parse(tag.Name)
+		if !ok || v.Prerelease != "" || !prefix.Contains(v) {
+			continue
+		}
+		if v.Components > best.Components || (v.Components == best.Components && semver.Compare(v, best) > 0) {
+			best = v
+		}
+	}
+	return best
+}
@@ -0,0 +1,244 @@
+package updater_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/lockfile"
+	"github.com/zisuu/github-actions-digest-pinner/internal/updater"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// trackingGitHubClient records how often each reference is resolved and how many
+// lookups are in flight at the same time
+type trackingGitHubClient struct {
+	mu          sync.Mutex
+	calls       map[string]int
+	inFlight    int
+	maxInFlight int
+	fail        string
+}
+
+func (c *trackingGitHubClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	key := action.Owner + "/" + action.Repo + "@" + action.Ref
+
+	c.mu.Lock()
+	c.calls[key]++
+	c.inFlight++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight)
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(10 * time.Millisecond):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	if key == c.fail {
+		return "", errors.New("not found")
+	}
+	return strings.Repeat("a", 40), nil
+}
+
+func TestUpdater_ResolveConcurrently(t *testing.T) {
+	workflow := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@v5
+      - uses: actions/cache@v4
+      - uses: actions/cache/save@v4
+      - uses: actions/upload-artifact@v4
+      - uses: actions/download-artifact@v4
+`
+
+	tests := []struct {
+		name        string
+		concurrency int
+		fail        string
+		wantUpdates int
+		wantErr     string
+	}{
+		{
+			name:        "sequential",
+			concurrency: 1,
+			wantUpdates: 18,
+		},
+		{
+			name:        "bounded",
+			concurrency: 3,
+			wantUpdates: 18,
+		},
+		{
+			name:        "more workers than references",
+			concurrency: 50,
+			wantUpdates: 18,
+		},
+		{
+			name:        "failure",
+			concurrency: 2,
+			fail:        "actions/setup-go@v5",
+			wantErr:     "failed to resolve SHA for action actions/setup-go@v5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := &writableMapFS{MapFS: fstest.MapFS{
+				".github/workflows/a.yml": &fstest.MapFile{Data: []byte(workflow)},
+				".github/workflows/b.yml": &fstest.MapFile{Data: []byte(workflow)},
+				".github/workflows/c.yml": &fstest.MapFile{Data: []byte(workflow)},
+			}}
+			client := &trackingGitHubClient{calls: make(map[string]int), fail: tt.fail}
+
+			u := updater.NewUpdater(client)
+			u.SetConcurrency(tt.concurrency)
+
+			updates, err := u.UpdateWorkflows(context.Background(), fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if string(fsys.MapFS[".github/workflows/a.yml"].Data) != workflow {
+					t.Errorf("expected no files to be written after a failed lookup")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if updates != tt.wantUpdates {
+				t.Errorf("expected %d updates, got %d", tt.wantUpdates, updates)
+			}
+			// actions/cache and actions/cache/save share a repository and ref
+			if len(client.calls) != 5 {
+				t.Errorf("expected 5 unique lookups, got %d: %v", len(client.calls), client.calls)
+			}
+			for key, n := range client.calls {
+				if n != 1 {
+					t.Errorf("expected %s to be resolved once, got %d", key, n)
+				}
+			}
+			if client.maxInFlight > tt.concurrency {
+				t.Errorf("expected at most %d concurrent lookups, got %d", tt.concurrency, client.maxInFlight)
+			}
+		})
+	}
+}
+
+func TestUpdater_Lockfile(t *testing.T) {
+	workflow := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/cache/save@v4
+      - uses: actions/setup-go@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v5.0.2
+`
+	lockedSHA := strings.Repeat("b", 40)
+	resolvedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		frozen      bool
+		locked      map[string]lockfile.Entry
+		wantSHA     string
+		wantVersion string
+		wantLookups int
+		wantErr     string
+	}{
+		{
+			name:        "resolved references are recorded",
+			wantSHA:     strings.Repeat("a", 40),
+			wantVersion: "v4",
+			wantLookups: 2,
+		},
+		{
+			name:   "frozen resolves from the lockfile",
+			frozen: true,
+			locked: map[string]lockfile.Entry{
+				"actions/checkout@v4":     {SHA: lockedSHA, Version: "v4.2.1", ResolvedAt: resolvedAt},
+				"actions/cache/save@v4":   {SHA: lockedSHA, Version: "v4.2.0", ResolvedAt: resolvedAt},
+				"actions/setup-python@v5": {SHA: lockedSHA, Version: "v5.3.0", ResolvedAt: resolvedAt},
+			},
+			wantSHA:     lockedSHA,
+			wantVersion: "v4.2.1",
+		},
+		{
+			name:   "frozen fails on missing entries",
+			frozen: true,
+			locked: map[string]lockfile.Entry{
+				"actions/checkout@v4": {SHA: lockedSHA, Version: "v4.2.1", ResolvedAt: resolvedAt},
+			},
+			wantErr: "references missing from the lockfile: actions/cache/save@v4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := &writableMapFS{MapFS: fstest.MapFS{
+				".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(workflow)},
+			}}
+			client := &trackingGitHubClient{calls: make(map[string]int)}
+			lock := lockfile.New()
+			for ref, entry := range tt.locked {
+				lock.Actions[ref] = entry
+			}
+
+			u := updater.NewUpdater(client)
+			u.SetLockfile(lock)
+			u.SetFrozen(tt.frozen)
+
+			_, err := u.UpdateWorkflows(context.Background(), fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if len(client.calls) != 0 {
+					t.Errorf("expected no lookups in frozen mode, got %v", client.calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(client.calls) != tt.wantLookups {
+				t.Errorf("expected %d lookups, got %d: %v", tt.wantLookups, len(client.calls), client.calls)
+			}
+			want := "actions/checkout@" + tt.wantSHA + " # " + tt.wantVersion
+			if content := string(fsys.MapFS[".github/workflows/ci.yml"].Data); !strings.Contains(content, want) {
+				t.Errorf("expected %q in updated workflow:\n%s", want, content)
+			}
+
+			// Every reference in the workflow is in the lockfile; already pinned ones are not recorded
+			for _, ref := range []string{"actions/checkout@v4", "actions/cache/save@v4"} {
+				entry, ok := lock.Lookup(ref)
+				if !ok || entry.SHA != tt.wantSHA || entry.ResolvedAt.IsZero() {
+					t.Errorf("unexpected lockfile entry for %s: %+v", ref, entry)
+				}
+			}
+			if _, ok := lock.Lookup("actions/setup-go@a81bbbf8298c0fa03ea29cdc473d45769f953675"); ok {
+				t.Error("expected pinned references not to be recorded")
+			}
+			if lock.Changed() == tt.frozen {
+				t.Errorf("expected lockfile changed to be %v", !tt.frozen)
+			}
+		})
+	}
+}
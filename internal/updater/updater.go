@@ -8,26 +8,72 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/zisuu/github-actions-digest-pinner/internal/checker"
+	"github.com/zisuu/github-actions-digest-pinner/internal/diff"
 	"github.com/zisuu/github-actions-digest-pinner/internal/finder"
 	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/internal/lockfile"
 	"github.com/zisuu/github-actions-digest-pinner/internal/parser"
+	"github.com/zisuu/github-actions-digest-pinner/internal/registry"
+	"github.com/zisuu/github-actions-digest-pinner/internal/semver"
 	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
 )
 
 var shaRegex = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
 
+// defaultConcurrency is the number of references resolved in parallel unless configured otherwise
+const defaultConcurrency = 8
+
 // Updater is responsible for updating GitHub Actions workflow files
 type Updater struct {
 	Client  ghclient.GitHubClient
 	baseDir string
+	dryRun  bool
+	results []FileResult
+	// concurrency is the number of references resolved in parallel
+	concurrency int
+	// lock records resolved references; in frozen mode references are resolved only from it
+	lock   *lockfile.Lockfile
+	frozen bool
+	// images resolves image tags to digests; without it images are left unchanged
+	images registry.Resolver
+	// filter selects the workflow and action files to update
+	filter finder.Filter
+}
+
+// Change describes a single pinned action reference
+type Change struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Version string `json:"version"`
+	// PreviousVersion is the version comment of an upgraded reference
+	PreviousVersion string `json:"previous_version,omitempty"`
+}
+
+// FileResult holds the original and updated content of a file changed by the updater
+type FileResult struct {
+	File     string
+	Original string
+	Updated  string
+	Changes  []Change
+}
+
+// Diff returns a unified diff between the original and updated content of the file
+func (r FileResult) Diff() string {
+	return diff.Unified("a/"+r.File, "b/"+r.File, r.Original, r.Updated)
 }
 
 // NewUpdater creates a new Updater instance with the provided GitHub client
 func NewUpdater(client ghclient.GitHubClient) *Updater {
 	return &Updater{
-		Client: client,
+		Client:      client,
+		concurrency: defaultConcurrency,
 	}
 }
 
@@ -36,16 +82,113 @@ func (u *Updater) SetBaseDir(dir string) {
 	u.baseDir = dir
 }
 
-// UpdateWorkflows scans for workflow files, parses them, and updates action references
+// SetConcurrency sets the number of references resolved in parallel
+func (u *Updater) SetConcurrency(n int) {
+	u.concurrency = max(n, 1)
+}
+
+// SetDryRun enables or disables dry-run mode, in which references are resolved but no files are written
+func (u *Updater) SetDryRun(dryRun bool) {
+	u.dryRun = dryRun
+}
+
+// SetImageResolver sets the registry client used to pin docker and container images to digests
+func (u *Updater) SetImageResolver(images registry.Resolver) {
+	u.images = images
+}
+
+// SetFileFilter sets the include and exclude globs selecting the files to update
+func (u *Updater) SetFileFilter(filter finder.Filter) {
+	u.filter = filter
+}
+
+// SetLockfile sets the lockfile in which resolved references are recorded
+func (u *Updater) SetLockfile(lock *lockfile.Lockfile) {
+	u.lock = lock
+}
+
+// SetFrozen enables or disables frozen mode, in which references are resolved only from the
+// lockfile without contacting GitHub, and a reference missing from it is an error
+func (u *Updater) SetFrozen(frozen bool) {
+	u.frozen = frozen
+}
+
+// Results returns the files changed (or, in dry-run mode, that would be changed) by the last update
+func (u *Updater) Results() []FileResult {
+	return u.results
+}
+
+// UpdateWorkflows scans for workflow and composite action files, parses them, and updates action references.
+// All files are parsed first so that every unique reference is resolved only once, concurrently.
 func (u *Updater) UpdateWorkflows(ctx context.Context, fsys fs.FS) (int, error) {
+	parsed, err := u.parseAll(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	var resolved map[string]resolution
+	if u.frozen {
+		resolved, err = u.resolveFromLockfile(parsed)
+	} else {
+		resolved, err = u.resolveAll(ctx, parsed)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !u.frozen {
+		u.recordResolutions(parsed, resolved)
+	}
+
+	return u.rewriteAll(fsys, parsed, func(action types.ActionRef) (resolution, bool) {
+		if checker.IsPinned(action) {
+			log.Printf("Skipping %s (already pinned)", action)
+			return resolution{}, false
+		}
+		if !u.frozen && u.skipsImage(action) {
+			log.Printf("Skipping %s (no image registry configured)", action)
+			return resolution{}, false
+		}
+		res, ok := resolved[resolveKey(action)]
+		if !ok {
+			log.Printf("Warning: reference %s was not resolved", action)
+		}
+		return res, ok
+	})
+}
+
+// parseAll finds and parses all workflow and composite action files selected by the file filter
+func (u *Updater) parseAll(fsys fs.FS) ([]parsedFile, error) {
 	files, err := finder.FindWorkflowFiles(fsys)
 	if err != nil {
-		return 0, fmt.Errorf("failed to find workflow files: %w", err)
+		return nil, fmt.Errorf("failed to find workflow files: %w", err)
 	}
 
-	totalUpdates := 0
+	actionFiles, err := finder.FindActionFiles(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find action files: %w", err)
+	}
+	files = u.filter.Apply(append(files, actionFiles...))
+
+	parsed := make([]parsedFile, 0, len(files))
 	for _, file := range files {
-		updates, err := u.processWorkflowFile(ctx, fsys, file)
+		pf, err := parseWorkflowFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, pf)
+	}
+	return parsed, nil
+}
+
+// target returns the resolution a reference is rewritten to, or false to leave it unchanged
+type target func(action types.ActionRef) (resolution, bool)
+
+// rewriteAll rewrites the references of the parsed files to their targets and returns the number of changes
+func (u *Updater) rewriteAll(fsys fs.FS, parsed []parsedFile, targetOf target) (int, error) {
+	u.results = nil
+	totalUpdates := 0
+	for _, pf := range parsed {
+		updates, err := u.processWorkflowFile(fsys, pf, targetOf)
 		if err != nil {
 			return totalUpdates, err
 		}
@@ -54,92 +197,238 @@ func (u *Updater) UpdateWorkflows(ctx context.Context, fsys fs.FS) (int, error)
 	return totalUpdates, nil
 }
 
-// processWorkflowFile reads a workflow file, parses it for action references
-func (u *Updater) processWorkflowFile(ctx context.Context, fsys fs.FS, file string) (int, error) {
+// parsedFile holds the content of a workflow or action file and the references found in it
+type parsedFile struct {
+	file    string
+	content string
+	actions []types.ActionRef
+}
+
+// parseWorkflowFile reads a workflow or action file and parses it for action references, leaving out ignored ones
+func parseWorkflowFile(fsys fs.FS, file string) (parsedFile, error) {
 	log.Printf("Processing file: %s", file)
 
 	content, err := fs.ReadFile(fsys, file)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read file %s: %w", file, err)
+		return parsedFile{}, fmt.Errorf("failed to read file %s: %w", file, err)
 	}
 
-	actions, err := parser.ParseWorkflowActions(content)
+	actions, err := parseFile(file, content)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse actions in file %s: %w", file, err)
+		return parsedFile{}, fmt.Errorf("failed to parse actions in file %s: %w", file, err)
 	}
 
 	debugActions(actions)
 	log.Printf("Found %d actions in file %s", len(actions), file)
 
-	updatedContent, fileUpdates, err := u.updateActionReferences(ctx, string(content), actions)
-	if err != nil {
-		return 0, err
+	// References suppressed by an ignore directive are neither resolved nor rewritten
+	actions = slices.DeleteFunc(actions, func(action types.ActionRef) bool {
+		if action.Ignored {
+			log.Printf("Skipping %s (ignored: %s)", action, action.IgnoreReason)
+		}
+		return action.Ignored
+	})
+
+	return parsedFile{file: file, content: string(content), actions: actions}, nil
+}
+
+// processWorkflowFile rewrites the references of a parsed file to their targets and writes the result
+func (u *Updater) processWorkflowFile(fsys fs.FS, pf parsedFile, targetOf target) (int, error) {
+	file := pf.file
+	updatedContent, changes := u.updateActionReferences(pf.content, pf.actions, targetOf)
+
+	if len(changes) == 0 {
+		log.Printf("No changes made to file: %s", file)
+		return 0, nil
 	}
 
-	if fileUpdates > 0 {
-		return fileUpdates, u.writeUpdatedFile(fsys, file, updatedContent)
+	for i := range changes {
+		changes[i].File = file
 	}
+	u.results = append(u.results, FileResult{
+		File:     file,
+		Original: pf.content,
+		Updated:  updatedContent,
+		Changes:  changes,
+	})
 
-	log.Printf("No changes made to file: %s", file)
-	return 0, nil
+	if u.dryRun {
+		log.Printf("Dry run: not writing %d changes to file: %s", len(changes), file)
+		return len(changes), nil
+	}
+
+	return len(changes), u.writeUpdatedFile(fsys, file, updatedContent)
+}
+
+// edit replaces the bytes between start and end of a file with text
+type edit struct {
+	start  int
+	end    int
+	text   string
+	change Change
 }
 
-// updateActionReferences updates action references in the content
-func (u *Updater) updateActionReferences(ctx context.Context, content string, actions []types.ActionRef) (string, int, error) {
-	updatedContent := content
-	fileUpdates := 0
+// updateActionReferences updates action references in the content. Each reference is rewritten
+// at the position recorded by the parser, so repeated references are all pinned and matching
+// text elsewhere (comments, run scripts) is left untouched.
+func (u *Updater) updateActionReferences(content string, actions []types.ActionRef, targetOf target) (string, []Change) {
+	lineStarts := lineOffsets(content)
 
+	var edits []edit
+	var changes []Change
 	for _, action := range actions {
-		newContent, updated, err := u.updateSingleActionReference(ctx, updatedContent, action)
-		if err != nil {
-			return "", 0, err
-		}
+		e, updated := u.updateSingleActionReference(content, lineStarts, action, targetOf)
 		if updated {
-			updatedContent = newContent
-			fileUpdates++
+			edits = append(edits, e)
+			changes = append(changes, e.change)
 		}
 	}
 
-	return updatedContent, fileUpdates, nil
+	return applyEdits(content, edits), changes
 }
 
-// updateSingleActionReference updates a single action reference in the content
-func (u *Updater) updateSingleActionReference(ctx context.Context, content string, action types.ActionRef) (string, bool, error) {
-	if isSHA(action.Ref) {
-		log.Printf("Skipping %s/%s@%s (already a SHA)", action.Owner, action.Repo, action.Ref)
-		return content, false, nil
+// updateSingleActionReference returns the edit pinning a single action reference to the SHA of its target
+func (u *Updater) updateSingleActionReference(content string, lineStarts []int, action types.ActionRef, targetOf target) (edit, bool) {
+	res, ok := targetOf(action)
+	if !ok {
+		return edit{}, false
 	}
 
-	log.Printf("Processing action: %s/%s@%s", action.Owner, action.Repo, action.Ref)
-	sha, err := u.Client.ResolveActionSHA(ctx, action)
-	if err != nil {
-		return "", false, fmt.Errorf("failed to resolve SHA for action %s/%s@%s: %w",
-			action.Owner, action.Repo, action.Ref, err)
+	start, end, ok := locateReference(content, lineStarts, action)
+	if !ok {
+		log.Printf("Warning: reference %s not found at line %d, column %d", action, action.Line, action.Column)
+		return edit{}, false
 	}
-	log.Printf("Resolved SHA for %s/%s@%s: %s", action.Owner, action.Repo, action.Ref, sha)
 
-	// Build the exact reference string that appears in the workflow file
-	var oldRef string
-	if action.Path != "" {
-		oldRef = fmt.Sprintf("%s/%s/%s@%s", action.Owner, action.Repo, action.Path, action.Ref)
+	pinned := action
+	if action.IsImage() {
+		pinned.Digest = res.sha
 	} else {
-		oldRef = fmt.Sprintf("%s/%s@%s", action.Owner, action.Repo, action.Ref)
+		pinned.Ref = res.sha
+	}
+	newRef := pinned.String()
+
+	change := Change{
+		Line:            action.Line,
+		Column:          action.Column,
+		Old:             action.String(),
+		New:             newRef,
+		Version:         res.version,
+		PreviousVersion: res.previous,
 	}
 
-	newRef := strings.Replace(oldRef, action.Ref, sha, 1)
+	// Images keep their tag next to the digest, so their comment is left alone. Otherwise only
+	// rewrite the comment when the reference is the last value on its line, so that flow
+	// mappings such as "{uses: ..., with: ...}" are left intact.
+	lineEnd := lineEndOffset(content, end)
+	rest := content[end:lineEnd]
+	if action.IsImage() || !isTrailing(rest) {
+		return edit{start: start, end: end, text: newRef, change: change}, true
+	}
+
+	text := newRef + withVersionComment(rest, action.Ref, res.version)
+	return edit{start: start, end: lineEnd, text: text, change: change}, true
+}
+
+// applyEdits applies non-overlapping edits to content
+func applyEdits(content string, edits []edit) string {
+	slices.SortFunc(edits, func(a, b edit) int { return a.start - b.start })
 
-	if !strings.Contains(content, oldRef) {
-		log.Printf("Warning: reference %s not found in content", oldRef)
-		return content, false, nil
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		b.WriteString(content[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
 	}
+	b.WriteString(content[last:])
+	return b.String()
+}
 
-	updated := strings.Replace(content, oldRef, newRef, 1)
-	if updated == content {
-		log.Printf("Warning: no changes made for %s (reference not found or already updated)", oldRef)
-		return content, false, nil
+// lineOffsets returns the byte offset at which each line of content starts
+func lineOffsets(content string) []int {
+	offsets := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			offsets = append(offsets, i+1)
+		}
 	}
+	return offsets
+}
 
-	return updated, true, nil
+// lineEndOffset returns the offset of the end of the line containing offset, excluding the line break
+func lineEndOffset(content string, offset int) int {
+	end := len(content)
+	if i := strings.IndexByte(content[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	if end > offset && content[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// locateReference returns the byte range of the reference text at the line and column recorded
+// by the parser. The column points at the start of the YAML value, which may be preceded by
+// an anchor, a tag or an opening quote, so the reference is searched from there to the end of line.
+func locateReference(content string, lineStarts []int, action types.ActionRef) (int, int, bool) {
+	if action.Line < 1 || action.Line > len(lineStarts) || action.Column < 1 {
+		return 0, 0, false
+	}
+
+	lineStart := lineStarts[action.Line-1]
+	line := content[lineStart:lineEndOffset(content, lineStart)]
+
+	// YAML columns count characters, not bytes
+	offset, column := len(line), 1
+	for i := range line {
+		if column == action.Column {
+			offset = i
+			break
+		}
+		column++
+	}
+
+	ref := action.String()
+	i := strings.Index(line[offset:], ref)
+	if i < 0 {
+		return 0, 0, false
+	}
+
+	start := lineStart + offset + i
+	return start, start + len(ref), true
+}
+
+// isTrailing reports whether the text following a reference on its line consists only of
+// an optional closing quote, whitespace and an optional comment
+func isTrailing(rest string) bool {
+	rest = strings.TrimLeft(rest, `"'`)
+	rest = strings.TrimLeft(rest, " \t")
+	return rest == "" || rest[0] == '#'
+}
+
+// withVersionComment rewrites the remainder of a uses line (closing quote and trailing comment)
+// so that its comment starts with the pinned version. If the existing comment starts with a
+// version or with the original ref, that word is replaced; any other comment is preserved
+// after the version, e.g. "# v4.2.1 # x-release-please-version".
+func withVersionComment(rest, ref, version string) string {
+	suffix, comment := rest, ""
+	for i := 1; i < len(rest); i++ {
+		if rest[i] == '#' && (rest[i-1] == ' ' || rest[i-1] == '\t') {
+			suffix, comment = strings.TrimRight(rest[:i], " \t"), strings.TrimSpace(rest[i+1:])
+			break
+		}
+	}
+
+	if comment == "" {
+		return suffix + " # " + version
+	}
+
+	if first := strings.Fields(comment)[0]; first == ref || semver.IsVersion(first) {
+		return suffix + " # " + version + strings.TrimPrefix(comment, first)
+	}
+
+	return suffix + " # " + version + " # " + comment
 }
 
 // writeUpdatedFile writes the updated content back to the file system
@@ -159,6 +448,14 @@ func (u *Updater) writeUpdatedFile(fsys fs.FS, file string, content string) erro
 	return os.WriteFile(fullPath, []byte(content), 0644)
 }
 
+// parseFile extracts action references using the parser matching the file type
+func parseFile(file string, content []byte) ([]types.ActionRef, error) {
+	if finder.IsActionFile(file) {
+		return parser.ParseCompositeActions(content)
+	}
+	return parser.ParseWorkflowActions(content)
+}
+
 // isSHA checks if a string is a valid Git SHA-1 hash (40 hex characters)
 func isSHA(ref string) bool {
 	return shaRegex.MatchString(ref)
@@ -167,6 +464,6 @@ func isSHA(ref string) bool {
 // debugActions logs the action references for debugging purposes
 func debugActions(actions []types.ActionRef) {
 	for i, action := range actions {
-		log.Printf("Action %d: %s/%s@%s", i+1, action.Owner, action.Repo, action.Ref)
+		log.Printf("Action %d (%s): %s", i+1, action.Kind, action)
 	}
 }
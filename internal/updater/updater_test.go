@@ -2,17 +2,22 @@ package updater_test
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
 
+	"github.com/zisuu/github-actions-digest-pinner/internal/finder"
+	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
 	"github.com/zisuu/github-actions-digest-pinner/internal/updater"
 	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
 )
 
 type mockGitHubClient struct {
 	shaMap map[string]string
+	tags   map[string][]ghclient.Tag
 }
 
 func (m *mockGitHubClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
@@ -20,6 +25,10 @@ func (m *mockGitHubClient) ResolveActionSHA(ctx context.Context, action types.Ac
 	return m.shaMap[key], nil
 }
 
+func (m *mockGitHubClient) ListTags(ctx context.Context, owner, repo string) ([]ghclient.Tag, error) {
+	return m.tags[owner+"/"+repo], nil
+}
+
 type writableMapFS struct {
 	fstest.MapFS
 }
@@ -38,6 +47,7 @@ func TestUpdater_UpdateWorkflows(t *testing.T) {
 		files       map[string]string
 		expected    map[string]string
 		shaMap      map[string]string
+		tags        map[string][]ghclient.Tag
 		wantUpdates int
 		wantErr     bool
 		errContains string
@@ -63,9 +73,9 @@ jobs:
   test:
     runs-on: ubuntu-latest
     steps:
-      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675
-      - uses: actions/setup-java@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8g9h0i
-      - uses: actions/setup-node@c9d0e1f2a3b4c5d6e7f8g9h0i1j2k3l4m5n6o7p8
+      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4
+      - uses: actions/setup-java@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8g9h0i # v4
+      - uses: actions/setup-node@c9d0e1f2a3b4c5d6e7f8g9h0i1j2k3l4m5n6o7p8 # v4
 `,
 			},
 			shaMap: map[string]string{
@@ -134,15 +144,15 @@ jobs:
       pull-requests: write # to be able to comment on released pull requests
       id-token: write # to enable use of OIDC for npm provenance
     steps:
-      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675
+      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4
       - name: Set up JDK 21
-        uses: actions/setup-java@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8g9h0i
+        uses: actions/setup-java@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8g9h0i # v4.0.1
         with:
           java-version: '21'
           distribution: 'adopt'
           cache: maven
       - name: Setup Node.js
-        uses: actions/setup-node@c9d0e1f2a3b4c5d6e7f8g9h0i1j2k3l4m5n6o7p8
+        uses: actions/setup-node@c9d0e1f2a3b4c5d6e7f8g9h0i1j2k3l4m5n6o7p8 # v4
         with:
           node-version: 'lts/*'
       - name: Build with Maven
@@ -177,7 +187,7 @@ jobs:
     runs-on: ubuntu-latest
     steps:
       - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675
-      - uses: actions/setup-java@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8g9h0i
+      - uses: actions/setup-java@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8g9h0i # v4
 `,
 			},
 			shaMap: map[string]string{
@@ -214,7 +224,7 @@ jobs:
   test:
     runs-on: ubuntu-latest
     steps:
-      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675
+      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4
       - name: Check if version was updated
         id: version-tag
         uses: ./.github/actions/setup-versions
@@ -224,7 +234,7 @@ jobs:
           deployment-tag: ${{ github.event.pull_request.merged }}
       - uses: ../parent/local/action
       - uses: docker://alpine:3.14
-      - uses: actions/setup-java@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8g9h0i
+      - uses: actions/setup-java@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8g9h0i # v4.0.1
 `,
 			},
 			shaMap: map[string]string{
@@ -234,6 +244,192 @@ jobs:
 			wantUpdates: 2,
 			wantErr:     false,
 		},
+		{
+			name: "update reusable workflow calls",
+			files: map[string]string{
+				".github/workflows/test-reusable.yml": `---
+on: push
+jobs:
+  build:
+    uses: myorg/shared/.github/workflows/build.yml@v2
+    secrets: inherit
+  local:
+    uses: ./.github/workflows/local.yml
+  test:
+    needs: build
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+`,
+			},
+			expected: map[string]string{
+				".github/workflows/test-reusable.yml": `---
+on: push
+jobs:
+  build:
+    uses: myorg/shared/.github/workflows/build.yml@d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3 # v2
+    secrets: inherit
+  local:
+    uses: ./.github/workflows/local.yml
+  test:
+    needs: build
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4
+`,
+			},
+			shaMap: map[string]string{
+				"myorg/shared@v2":     "d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3",
+				"actions/checkout@v4": "a81bbbf8298c0fa03ea29cdc473d45769f953675",
+			},
+			wantUpdates: 2,
+		},
+		{
+			name: "update composite action metadata",
+			files: map[string]string{
+				".github/workflows/ci.yml": `---
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: ./.github/actions/setup
+`,
+				".github/actions/setup/action.yml": `---
+name: Setup
+runs:
+  using: composite
+  steps:
+    - uses: actions/setup-go@v5
+    - run: go version
+      shell: bash
+`,
+			},
+			expected: map[string]string{
+				".github/workflows/ci.yml": `---
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: ./.github/actions/setup
+`,
+				".github/actions/setup/action.yml": `---
+name: Setup
+runs:
+  using: composite
+  steps:
+    - uses: actions/setup-go@e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4 # v5
+    - run: go version
+      shell: bash
+`,
+			},
+			shaMap: map[string]string{
+				"actions/setup-go@v5": "e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4",
+			},
+			wantUpdates: 1,
+		},
+		{
+			name: "record most specific version and keep other comments",
+			files: map[string]string{
+				".github/workflows/test-comments.yml": `---
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: "actions/setup-go@v5"  # v5 is required for go 1.25
+      - uses: super-linter/super-linter@v6.7.0  # x-release-please-version
+      - uses: actions/checkout@v4.1
+      - uses: actions/setup-node@main
+      - run: echo "actions/checkout@v4 is pinned"
+`,
+			},
+			expected: map[string]string{
+				".github/workflows/test-comments.yml": `---
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4.2.1
+      - uses: "actions/setup-go@e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4" # v5.0.2 is required for go 1.25
+      - uses: super-linter/super-linter@f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5 # v6.7.0 # x-release-please-version
+      - uses: actions/checkout@b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7 # v4.1.7
+      - uses: actions/setup-node@c9d0e1f2a3b4c5d6e7f8g9h0i1j2k3l4m5n6o7p8 # main
+      - run: echo "actions/checkout@v4 is pinned"
+`,
+			},
+			shaMap: map[string]string{
+				"actions/checkout@v4":              "a81bbbf8298c0fa03ea29cdc473d45769f953675",
+				"actions/checkout@v4.1":            "b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7",
+				"actions/setup-go@v5":              "e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4",
+				"actions/setup-node@main":          "c9d0e1f2a3b4c5d6e7f8g9h0i1j2k3l4m5n6o7p8",
+				"super-linter/super-linter@v6.7.0": "f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5",
+			},
+			tags: map[string][]ghclient.Tag{
+				"actions/checkout": {
+					{Name: "v5.0.0", SHA: "0000000000000000000000000000000000000000"},
+					{Name: "v4.2.1", SHA: "a81bbbf8298c0fa03ea29cdc473d45769f953675"},
+					{Name: "v4.2.0", SHA: "1111111111111111111111111111111111111111"},
+					{Name: "v4.2", SHA: "a81bbbf8298c0fa03ea29cdc473d45769f953675"},
+					{Name: "v4.1.7", SHA: "b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7"},
+					{Name: "v4", SHA: "a81bbbf8298c0fa03ea29cdc473d45769f953675"},
+				},
+				"actions/setup-go": {
+					{Name: "v5.0.2", SHA: "e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4"},
+					{Name: "v6.0.0-beta.1", SHA: "e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4"},
+				},
+			},
+			wantUpdates: 5,
+		},
+		{
+			name: "rewrite only uses values at their positions",
+			files: map[string]string{
+				".github/workflows/test-positions.yml": `---
+# Upgrade actions/checkout@v4 with care
+on: push
+jobs:
+  lint:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "using actions/checkout@v4"
+      - uses: &checkout actions/checkout@v4
+      - {uses: 'actions/setup-go@v5', with: {go-version: '1.25'}}
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: *checkout
+      - uses: 'actions/setup-go@v5' # pinned by bot
+      - uses: actions/setup-go@v5
+`,
+			},
+			expected: map[string]string{
+				".github/workflows/test-positions.yml": `---
+# Upgrade actions/checkout@v4 with care
+on: push
+jobs:
+  lint:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "using actions/checkout@v4"
+      - uses: &checkout actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4
+      - {uses: 'actions/setup-go@e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4', with: {go-version: '1.25'}}
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: *checkout
+      - uses: 'actions/setup-go@e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4' # v5 # pinned by bot
+      - uses: actions/setup-go@e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4 # v5
+`,
+			},
+			shaMap: map[string]string{
+				"actions/checkout@v4": "a81bbbf8298c0fa03ea29cdc473d45769f953675",
+				"actions/setup-go@v5": "e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4",
+			},
+			wantUpdates: 4,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -245,7 +441,7 @@ jobs:
 			}
 
 			// Run updater
-			client := &mockGitHubClient{shaMap: tc.shaMap}
+			client := &mockGitHubClient{shaMap: tc.shaMap, tags: tc.tags}
 			u := updater.NewUpdater(client)
 			updates, err := u.UpdateWorkflows(context.Background(), memFS)
 
@@ -281,3 +477,261 @@ jobs:
 		})
 	}
 }
+
+func TestUpdater_DryRun(t *testing.T) {
+	original := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v5.0.2
+`
+	memFS := &writableMapFS{MapFS: fstest.MapFS{
+		".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(original)},
+	}}
+
+	client := &mockGitHubClient{shaMap: map[string]string{
+		"actions/checkout@v4": "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c",
+	}}
+	u := updater.NewUpdater(client)
+	u.SetDryRun(true)
+
+	updates, err := u.UpdateWorkflows(context.Background(), memFS)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if updates != 1 {
+		t.Errorf("Expected 1 update, got %d", updates)
+	}
+
+	content, err := fs.ReadFile(memFS, ".github/workflows/ci.yml")
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != original {
+		t.Errorf("Dry run modified the file:\n%s", content)
+	}
+
+	results := u.Results()
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 file result, got %d", len(results))
+	}
+
+	expectedChange := updater.Change{
+		File:    ".github/workflows/ci.yml",
+		Line:    6,
+		Column:  15,
+		Old:     "actions/checkout@v4",
+		New:     "actions/checkout@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c",
+		Version: "v4",
+	}
+	if len(results[0].Changes) != 1 || results[0].Changes[0] != expectedChange {
+		t.Errorf("Expected changes [%+v], got %+v", expectedChange, results[0].Changes)
+	}
+
+	expectedDiff := `--- a/.github/workflows/ci.yml
++++ b/.github/workflows/ci.yml
+@@ -3,5 +3,5 @@
+   test:
+     runs-on: ubuntu-latest
+     steps:
+-      - uses: actions/checkout@v4
++      - uses: actions/checkout@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c # v4
+       - uses: actions/setup-go@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v5.0.2
+`
+	if diff := results[0].Diff(); diff != expectedDiff {
+		t.Errorf("Diff mismatch:\nExpected:\n%s\nGot:\n%s", expectedDiff, diff)
+	}
+}
+
+func TestUpdater_FileFilter(t *testing.T) {
+	workflow := []byte("on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n")
+	memFS := &writableMapFS{MapFS: fstest.MapFS{
+		".github/workflows/ci.yml":            &fstest.MapFile{Data: workflow},
+		".github/workflows/legacy-deploy.yml": &fstest.MapFile{Data: workflow},
+	}}
+
+	client := &mockGitHubClient{shaMap: map[string]string{
+		"actions/checkout@v4": "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c",
+	}}
+	u := updater.NewUpdater(client)
+	u.SetFileFilter(finder.Filter{Exclude: []string{".github/workflows/legacy-*.yml"}})
+
+	updates, err := u.UpdateWorkflows(context.Background(), memFS)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if updates != 1 {
+		t.Errorf("Expected 1 update, got %d", updates)
+	}
+
+	content, err := fs.ReadFile(memFS, ".github/workflows/legacy-deploy.yml")
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != string(workflow) {
+		t.Errorf("Excluded file was modified:\n%s", content)
+	}
+}
+
+func TestUpdater_IgnoreDirectives(t *testing.T) {
+	original := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      # digest-pinner: ignore
+      - uses: myorg/internal@main
+      - uses: myorg/internal@main # digest-pinner: ignore
+`
+	ignoredFile := "# digest-pinner: ignore-file\non: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n"
+	memFS := &writableMapFS{MapFS: fstest.MapFS{
+		".github/workflows/ci.yml":      &fstest.MapFile{Data: []byte(original)},
+		".github/workflows/ignored.yml": &fstest.MapFile{Data: []byte(ignoredFile)},
+	}}
+
+	client := &mockGitHubClient{shaMap: map[string]string{
+		"actions/checkout@v4": "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c",
+	}}
+	u := updater.NewUpdater(client)
+
+	updates, err := u.UpdateWorkflows(context.Background(), memFS)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if updates != 1 {
+		t.Errorf("Expected 1 update, got %d", updates)
+	}
+
+	expected := strings.Replace(original, "actions/checkout@v4", "actions/checkout@b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c # v4", 1)
+	content, err := fs.ReadFile(memFS, ".github/workflows/ci.yml")
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, content)
+	}
+
+	content, err = fs.ReadFile(memFS, ".github/workflows/ignored.yml")
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != ignoredFile {
+		t.Errorf("Ignored file was modified:\n%s", content)
+	}
+}
+
+// mockImageResolver resolves image:tag references from a fixed map and counts the lookups
+type mockImageResolver struct {
+	digests map[string]string
+	lookups atomic.Int32
+}
+
+func (m *mockImageResolver) ResolveDigest(ctx context.Context, image, tag string) (string, error) {
+	m.lookups.Add(1)
+	digest, ok := m.digests[image+":"+tag]
+	if !ok {
+		return "", fmt.Errorf("manifest unknown: %s:%s", image, tag)
+	}
+	return digest, nil
+}
+
+func TestUpdater_Images(t *testing.T) {
+	const (
+		alpineDigest = "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1"
+		nodeDigest   = "sha256:9c7e3b4a5d6f7e8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a"
+		redisDigest  = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	)
+
+	workflow := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    container: node:20 # runtime
+    services:
+      redis:
+        image: redis:7@` + redisDigest + `
+      cache:
+        image: "alpine:3.19"
+    steps:
+      - uses: docker://alpine:3.19
+      - uses: actions/checkout@v4
+`
+
+	tests := []struct {
+		name        string
+		images      *mockImageResolver
+		wantUpdates int
+		wantLookups int32
+		wantLines   []string
+	}{
+		{
+			name:        "images are pinned to digests",
+			images:      &mockImageResolver{digests: map[string]string{"node:20": nodeDigest, "alpine:3.19": alpineDigest}},
+			wantUpdates: 4,
+			wantLookups: 2,
+			wantLines: []string{
+				"    container: node:20@" + nodeDigest + " # runtime\n",
+				"        image: redis:7@" + redisDigest + "\n",
+				`        image: "alpine:3.19@` + alpineDigest + `"` + "\n",
+				"      - uses: docker://alpine:3.19@" + alpineDigest + "\n",
+				"      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4\n",
+			},
+		},
+		{
+			name:        "images are left alone without a registry client",
+			wantUpdates: 1,
+			wantLines: []string{
+				"    container: node:20 # runtime\n",
+				"      - uses: docker://alpine:3.19\n",
+			},
+		},
+		{
+			name:        "images that cannot be resolved are left alone",
+			images:      &mockImageResolver{digests: map[string]string{"node:20": nodeDigest}},
+			wantUpdates: 2,
+			wantLookups: 2,
+			wantLines: []string{
+				"    container: node:20@" + nodeDigest + " # runtime\n",
+				`        image: "alpine:3.19"` + "\n",
+				"      - uses: docker://alpine:3.19\n",
+				"      - uses: actions/checkout@a81bbbf8298c0fa03ea29cdc473d45769f953675 # v4\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := &writableMapFS{MapFS: fstest.MapFS{
+				".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(workflow)},
+			}}
+
+			u := updater.NewUpdater(&mockGitHubClient{shaMap: map[string]string{
+				"actions/checkout@v4": "a81bbbf8298c0fa03ea29cdc473d45769f953675",
+			}})
+			if tt.images != nil {
+				u.SetImageResolver(tt.images)
+			}
+
+			updates, err := u.UpdateWorkflows(context.Background(), fsys)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updates != tt.wantUpdates {
+				t.Errorf("expected %d updates, got %d", tt.wantUpdates, updates)
+			}
+			if tt.images != nil && tt.images.lookups.Load() != tt.wantLookups {
+				t.Errorf("expected %d image lookups, got %d", tt.wantLookups, tt.images.lookups.Load())
+			}
+
+			content := string(fsys.MapFS[".github/workflows/ci.yml"].Data)
+			for _, line := range tt.wantLines {
+				if !strings.Contains(content, line) {
+					t.Errorf("expected %q in updated workflow:\n%s", line, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/internal/semver"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+// UpgradeWorkflows moves every SHA-pinned reference with a version comment to the newest release
+// allowed by constraint, rewriting both the SHA and the comment. Prereleases are never chosen, and
+// references without a version comment are left alone, as their current release is unknown.
+func (u *Updater) UpgradeWorkflows(ctx context.Context, fsys fs.FS, constraint semver.Constraint) (int, error) {
+	lister, ok := ghclient.AsTagLister(u.Client)
+	if !ok {
+		return 0, errors.New("upgrading requires a client that can list tags")
+	}
+
+	parsed, err := u.parseAll(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	tags := make(map[string][]ghclient.Tag)
+	upgrades := make(map[string]resolution)
+	for _, pf := range parsed {
+		for _, action := range pf.actions {
+			current, ok := pinnedVersion(action)
+			if !ok {
+				continue
+			}
+			key := upgradeKey(action, current)
+			if _, seen := upgrades[key]; seen {
+				continue
+			}
+
+			repo := action.Owner + "/" + action.Repo
+			if _, listed := tags[repo]; !listed {
+				repoTags, err := lister.ListTags(ctx, action.Owner, action.Repo)
+				if err != nil {
+					return 0, fmt.Errorf("failed to list tags of %s: %w", repo, err)
+				}
+				tags[repo] = repoTags
+			}
+
+			res, ok := upgradeTarget(tags[repo], action.Ref, current, constraint)
+			if ok {
+				log.Printf("Upgrading %s from %s to %s", repo, res.previous, res.version)
+			} else {
+				log.Printf("No upgrade for %s %s", repo, current.Original)
+			}
+			// Unavailable upgrades are recorded too, so that each reference is only looked at once
+			upgrades[key] = res
+		}
+	}
+
+	return u.rewriteAll(fsys, parsed, func(action types.ActionRef) (resolution, bool) {
+		current, ok := pinnedVersion(action)
+		if !ok {
+			return resolution{}, false
+		}
+		res := upgrades[upgradeKey(action, current)]
+		return res, res.sha != ""
+	})
+}
+
+// pinnedVersion returns the version in the comment of a SHA-pinned reference
+func pinnedVersion(action types.ActionRef) (semver.Version, bool) {
+	if action.IsImage() || !isSHA(action.Ref) {
+		return semver.Version{}, false
+	}
+	return semver.FromComment(action.Comment)
+}
+
+// upgradeKey identifies a pinned release of a repository
+func upgradeKey(action types.ActionRef, current semver.Version) string {
+	return action.Owner + "/" + action.Repo + "@" + strings.ToLower(action.Ref) + "#" + current.Original
+}
+
+// upgradeTarget returns the newest release tag allowed by constraint that is newer than the release
+// pinned at sha, as determined by currentRelease.
+func upgradeTarget(tags []ghclient.Tag, sha string, current semver.Version, constraint semver.Constraint) (resolution, bool) {
+	current = currentRelease(tags, current, sha)
+
+	var best ghclient.Tag
+	var bestVersion semver.Version
+	for _, tag := range tags {
+		v, ok := semver.Parse(tag.Name)
+		if !ok || v.Prerelease != "" || semver.Compare(v, current) <= 0 || !constraint.Allows(current, v) {
+			continue
+		}
+		if best.Name == "" || semver.Compare(v, bestVersion) > 0 ||
+			(semver.Compare(v, bestVersion) == 0 && v.Components > bestVersion.Components) {
+			best, bestVersion = tag, v
+		}
+	}
+
+	if best.Name == "" {
+		return resolution{}, false
+	}
+	return resolution{sha: best.SHA, version: best.Name, previous: current.Original}, true
+}
+
+// currentRelease returns the release pinned at sha, given its version comment. It is the most
+// specific release tag of the comment's line pointing at sha. Failing that, a floating comment
+// such as v4 whose tag still points at sha means the pin is as new as its line, so the newest
+// release of the line is returned. Only if neither tag points at sha is the comment used as is.
+func currentRelease(tags []ghclient.Tag, comment semver.Version, sha string) semver.Version {
+	if current := mostSpecificTag(tags, comment, sha); current.Original != comment.Original || comment.Components == 3 {
+		return current
+	}
+
+	for _, tag := range tags {
+		if tag.Name != comment.Original || !strings.EqualFold(tag.SHA, sha) {
+			continue
+		}
+		newest := comment
+		for _, release := range tags {
+			v, ok := semver.Parse(release.Name)
+			if ok && v.Prerelease == "" && v.Components == 3 && comment.Contains(v) && semver.Compare(v, newest) > 0 {
+				newest = v
+			}
+		}
+		return newest
+	}
+	return comment
+}
@@ -0,0 +1,250 @@
+package updater_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/zisuu/github-actions-digest-pinner/internal/ghclient"
+	"github.com/zisuu/github-actions-digest-pinner/internal/semver"
+	"github.com/zisuu/github-actions-digest-pinner/internal/updater"
+	"github.com/zisuu/github-actions-digest-pinner/pgk/types"
+)
+
+func TestUpdater_UpgradeWorkflows(t *testing.T) {
+	const (
+		sha410 = "4100000000000000000000000000000000000000"
+		sha412 = "4120000000000000000000000000000000000000"
+		sha430 = "4300000000000000000000000000000000000000"
+		sha500 = "5000000000000000000000000000000000000000"
+		sha600 = "6000000000000000000000000000000000000000"
+	)
+
+	workflow := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@` + sha410 + ` # v4.1.0
+      - uses: actions/checkout@` + sha410 + ` # v4 # keep me
+      - uses: actions/checkout@` + sha410 + `
+      - uses: actions/checkout@v4
+`
+
+	tags := map[string][]ghclient.Tag{
+		"actions/checkout": {
+			{Name: "v4", SHA: sha430},
+			{Name: "v4.1.0", SHA: sha410},
+			{Name: "v4.1.2", SHA: sha412},
+			{Name: "v4.3.0", SHA: sha430},
+			{Name: "v5.0.0", SHA: sha500},
+			{Name: "v6.0.0-rc.1", SHA: sha600},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		constraint  func() (semver.Constraint, error)
+		wantUpdates int
+		wantLines   []string
+	}{
+		{
+			name:        "patch",
+			constraint:  func() (semver.Constraint, error) { return semver.ParseLevel("patch") },
+			wantUpdates: 2,
+			wantLines: []string{
+				"actions/checkout@" + sha412 + " # v4.1.2\n",
+				"actions/checkout@" + sha412 + " # v4.1.2 # keep me\n",
+			},
+		},
+		{
+			name:        "minor",
+			constraint:  func() (semver.Constraint, error) { return semver.ParseLevel("minor") },
+			wantUpdates: 2,
+			wantLines:   []string{"actions/checkout@" + sha430 + " # v4.3.0\n"},
+		},
+		{
+			name:        "major skips prereleases",
+			constraint:  func() (semver.Constraint, error) { return semver.ParseLevel("major") },
+			wantUpdates: 2,
+			wantLines:   []string{"actions/checkout@" + sha500 + " # v5.0.0\n"},
+		},
+		{
+			name:        "range",
+			constraint:  func() (semver.Constraint, error) { return semver.ParseRange(">=4.1.1 <4.3") },
+			wantUpdates: 2,
+			wantLines:   []string{"actions/checkout@" + sha412 + " # v4.1.2\n"},
+		},
+		{
+			name:        "no release in range",
+			constraint:  func() (semver.Constraint, error) { return semver.ParseRange("v3") },
+			wantUpdates: 0,
+			wantLines:   []string{"actions/checkout@" + sha410 + " # v4.1.0\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraint, err := tt.constraint()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			fsys := &writableMapFS{MapFS: fstest.MapFS{
+				".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(workflow)},
+			}}
+
+			u := updater.NewUpdater(&mockGitHubClient{tags: tags})
+			updates, err := u.UpgradeWorkflows(context.Background(), fsys, constraint)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updates != tt.wantUpdates {
+				t.Errorf("expected %d updates, got %d", tt.wantUpdates, updates)
+			}
+
+			content := string(fsys.MapFS[".github/workflows/ci.yml"].Data)
+			for _, line := range tt.wantLines {
+				if !strings.Contains(content, line) {
+					t.Errorf("expected %q in upgraded workflow:\n%s", line, content)
+				}
+			}
+			// References without a version comment or SHA are never touched
+			if !strings.Contains(content, "actions/checkout@"+sha410+"\n") || !strings.Contains(content, "actions/checkout@v4\n") {
+				t.Errorf("expected references without a version comment to be unchanged:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpgradeWorkflowsFloatingComment(t *testing.T) {
+	const (
+		shaHead = "4ead000000000000000000000000000000000000"
+		shaOld  = "0000000000000000000000000000000000000001"
+		sha400  = "4000000000000000000000000000000000000000"
+		sha410  = "4100000000000000000000000000000000000000"
+		sha500  = "5000000000000000000000000000000000000000"
+	)
+
+	// The floating v4 tag points at a commit no release tag points at
+	workflow := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/setup-go@` + shaHead + ` # v4
+      - uses: actions/setup-go@` + shaOld + ` # v4
+`
+
+	tags := map[string][]ghclient.Tag{
+		"actions/setup-go": {
+			{Name: "v4", SHA: shaHead},
+			{Name: "v4.0.0", SHA: sha400},
+			{Name: "v4.1.0", SHA: sha410},
+			{Name: "v5.0.0", SHA: sha500},
+		},
+	}
+
+	tests := []struct {
+		level       string
+		wantUpdates int
+		wantLines   []string
+	}{
+		{
+			level:       "minor",
+			wantUpdates: 1,
+			wantLines: []string{
+				"actions/setup-go@" + shaHead + " # v4\n",
+				"actions/setup-go@" + sha410 + " # v4.1.0\n",
+			},
+		},
+		{
+			level:       "major",
+			wantUpdates: 2,
+			wantLines: []string{
+				"actions/setup-go@" + sha500 + " # v5.0.0\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			constraint, err := semver.ParseLevel(tt.level)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			fsys := &writableMapFS{MapFS: fstest.MapFS{
+				".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(workflow)},
+			}}
+
+			u := updater.NewUpdater(&mockGitHubClient{tags: tags})
+			updates, err := u.UpgradeWorkflows(context.Background(), fsys, constraint)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updates != tt.wantUpdates {
+				t.Errorf("expected %d updates, got %d", tt.wantUpdates, updates)
+			}
+
+			content := string(fsys.MapFS[".github/workflows/ci.yml"].Data)
+			for _, line := range tt.wantLines {
+				if !strings.Contains(content, line) {
+					t.Errorf("expected %q in upgraded workflow:\n%s", line, content)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdater_UpgradeWorkflowsDryRun(t *testing.T) {
+	const (
+		oldSHA = "a81bbbf8298c0fa03ea29cdc473d45769f953675"
+		newSHA = "b72c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c"
+	)
+	original := "jobs:\n  test:\n    steps:\n      - uses: actions/setup-go@" + oldSHA + " # v5.0.2\n"
+	fsys := &writableMapFS{MapFS: fstest.MapFS{
+		".github/workflows/ci.yml": &fstest.MapFile{Data: []byte(original)},
+	}}
+
+	u := updater.NewUpdater(&mockGitHubClient{tags: map[string][]ghclient.Tag{
+		"actions/setup-go": {{Name: "v5.0.2", SHA: oldSHA}, {Name: "v5.4.0", SHA: newSHA}},
+	}})
+	u.SetDryRun(true)
+
+	constraint, _ := semver.ParseLevel("minor")
+	if _, err := u.UpgradeWorkflows(context.Background(), fsys, constraint); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(fsys.MapFS[".github/workflows/ci.yml"].Data) != original {
+		t.Error("dry run modified the file")
+	}
+
+	expected := updater.Change{
+		File:            ".github/workflows/ci.yml",
+		Line:            4,
+		Column:          15,
+		Old:             "actions/setup-go@" + oldSHA,
+		New:             "actions/setup-go@" + newSHA,
+		Version:         "v5.4.0",
+		PreviousVersion: "v5.0.2",
+	}
+	results := u.Results()
+	if len(results) != 1 || len(results[0].Changes) != 1 || results[0].Changes[0] != expected {
+		t.Errorf("expected change %+v, got %+v", expected, results)
+	}
+}
+
+// resolveOnlyClient cannot list tags
+type resolveOnlyClient struct{}
+
+func (resolveOnlyClient) ResolveActionSHA(ctx context.Context, action types.ActionRef) (string, error) {
+	return action.Ref, nil
+}
+
+func TestUpdater_UpgradeWorkflowsRequiresTags(t *testing.T) {
+	constraint, _ := semver.ParseLevel("major")
+	u := updater.NewUpdater(resolveOnlyClient{})
+	if _, err := u.UpgradeWorkflows(context.Background(), fstest.MapFS{}, constraint); err == nil {
+		t.Error("expected an error for a client that cannot list tags")
+	}
+}